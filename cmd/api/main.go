@@ -8,11 +8,19 @@ import (
 
 	"github.com/joho/godotenv"
 	"github.com/vickon16/go-gin-rest-api/cmd/api/routes"
+	"github.com/vickon16/go-gin-rest-api/cmd/api/services"
 	"github.com/vickon16/go-gin-rest-api/internal/app"
+	"github.com/vickon16/go-gin-rest-api/internal/authz"
 	"github.com/vickon16/go-gin-rest-api/internal/database"
 	"github.com/vickon16/go-gin-rest-api/internal/database/models"
 	"github.com/vickon16/go-gin-rest-api/internal/env"
+	"github.com/vickon16/go-gin-rest-api/internal/mailer"
+	"github.com/vickon16/go-gin-rest-api/internal/oidc"
+	"github.com/vickon16/go-gin-rest-api/internal/passwords"
 	"github.com/vickon16/go-gin-rest-api/internal/redisDb"
+	"github.com/vickon16/go-gin-rest-api/internal/search"
+	"github.com/vickon16/go-gin-rest-api/internal/storage"
+	"github.com/vickon16/go-gin-rest-api/internal/utils"
 
 	_ "github.com/lib/pq"
 	_ "github.com/vickon16/go-gin-rest-api/docs"
@@ -37,6 +45,24 @@ import (
 func main() {
 	_ = godotenv.Load()
 
+	if err := utils.LoadJWTKeys(); err != nil {
+		log.Fatalf("Could not load JWT keys: %v", err)
+	}
+	if err := utils.LoadEncryptionKey(); err != nil {
+		log.Fatalf("Could not load encryption key: %v", err)
+	}
+	if err := utils.LoadCursorSecret(); err != nil {
+		log.Fatalf("Could not load cursor secret: %v", err)
+	}
+	if err := utils.LoadPasswordHasher(); err != nil {
+		log.Fatalf("Could not load password hasher: %v", err)
+	}
+
+	passwordPolicy, err := passwords.NewPolicyFromEnv()
+	if err != nil {
+		log.Fatalf("Could not load password policy: %v", err)
+	}
+
 	db := database.SetupDatabase()
 	defer db.Close()
 
@@ -44,11 +70,26 @@ func main() {
 	redisClient := redisDb.NewRedisClient()
 
 	app := &app.Application{
-		Port:   env.GetEnvInt("PORT", 8080),
-		Models: models,
-		Redis:  redisClient,
+		Port:      env.GetEnvInt("PORT", 8080),
+		Models:    models,
+		Redis:     redisClient,
+		Authz:     authz.NewAuthorizer(models),
+		Mailer:    mailer.NewMailer(),
+		OIDC:      oidc.NewProviders(),
+		Passwords: passwordPolicy,
+		Storage:   storage.NewStorage(),
+		Search:    search.NewBackend(db),
 	}
 
+	// The in-memory index starts out empty
+	if _, ok := app.Search.(*search.MemoryBackend); ok {
+		if err := services.IndexAllEvents(app); err != nil {
+			log.Fatalf("Could not index events: %v", err)
+		}
+	}
+
+	services.StartAccountDeletionWorker(app, time.Hour)
+
 	server := &http.Server{
 		Addr:         fmt.Sprintf(":%d", app.Port),
 		Handler:      routes.SetupRoutes(app),
@@ -1,8 +1,10 @@
 package middlewares
 
 import (
+	"crypto/subtle"
 	"encoding/json"
 	"errors"
+	"log"
 	"net/http"
 	"strings"
 	"time"
@@ -10,11 +12,18 @@ import (
 	"github.com/gin-gonic/gin"
 	"github.com/vickon16/go-gin-rest-api/internal/app"
 	"github.com/vickon16/go-gin-rest-api/internal/database/models"
+	"github.com/vickon16/go-gin-rest-api/internal/env"
 	"github.com/vickon16/go-gin-rest-api/internal/utils"
 )
 
 func AuthMiddleware(app *app.Application) gin.HandlerFunc {
 	return func(ctx *gin.Context) {
+		// Machine clients may send a personal API key instead of a JWT
+		if apiKey := extractAPIKey(ctx); apiKey != "" {
+			authenticateAPIKey(app, ctx, apiKey)
+			return
+		}
+
 		authHeader := ctx.GetHeader("Authorization")
 		if authHeader == "" {
 			utils.ErrorResponse(ctx, "Missing authorized header", http.StatusUnauthorized)
@@ -38,6 +47,12 @@ func AuthMiddleware(app *app.Application) gin.HandlerFunc {
 			return
 		}
 
+		if err := checkTokenRevocation(app, claims); err != nil {
+			utils.ErrorResponse(ctx, err.Error(), http.StatusUnauthorized)
+			ctx.Abort()
+			return
+		}
+
 		userId := claims.UserID
 		user, err := storeOrRetrieveFromRedis(app, userId)
 		if err != nil {
@@ -46,11 +61,166 @@ func AuthMiddleware(app *app.Application) gin.HandlerFunc {
 			return
 		}
 
+		if claims.SessionID != 0 {
+			refreshExpirationDays := env.GetEnvInt("REFRESH_TOKEN_EXPIRATION_DAYS", 30)
+			if err := app.Redis.TouchSession(claims.SessionID, time.Duration(refreshExpirationDays)*24*time.Hour); err != nil {
+				log.Printf("Failed to update session activity: %v", err)
+			}
+		}
+
 		ctx.Set("user", user)
+		ctx.Set("claims", claims)
+
+		if claims.Actor != nil {
+			actor, err := authenticateActor(app, claims.Actor)
+			if err != nil {
+				utils.ErrorResponse(ctx, err.Error(), http.StatusUnauthorized)
+				ctx.Abort()
+				return
+			}
+
+			ctx.Set("actor", actor)
+			ctx.Next()
+			recordImpersonatedRequest(app, ctx, actor, user)
+			return
+		}
+
 		ctx.Next()
 	}
 }
 
+// authenticateActor checks that the admin behind an impersonation token is
+// still an admin and has not logged out of all sessions since issuing it.
+func authenticateActor(app *app.Application, claim *utils.ActorClaim) (*models.UserSerializer, error) {
+	actor, err := storeOrRetrieveFromRedis(app, claim.UserID)
+	if err != nil {
+		return nil, err
+	}
+	if actor.Role != models.RoleAdmin {
+		return nil, errors.New("impersonation is no longer allowed")
+	}
+
+	tokenVersion, err := app.Redis.GetTokenVersion(claim.UserID)
+	if err != nil {
+		return nil, errors.New("something went wrong: 8")
+	}
+	if claim.TokenVersion != tokenVersion {
+		return nil, errors.New("token has been revoked")
+	}
+
+	return actor, nil
+}
+
+func recordImpersonatedRequest(app *app.Application, ctx *gin.Context, actor, user *models.UserSerializer) {
+	outcome := models.AuditOutcomeAllowed
+	if ctx.Writer.Status() == http.StatusForbidden {
+		outcome = models.AuditOutcomeDenied
+	}
+
+	entry := models.AuditLog{
+		ActorID:      &actor.ID,
+		Action:       models.AuditActionImpersonatedRequest,
+		ResourceType: "user",
+		ResourceID:   &user.ID,
+		Outcome:      outcome,
+		Method:       ctx.Request.Method,
+		Path:         ctx.Request.URL.Path,
+		IPAddress:    ctx.ClientIP(),
+	}
+	if err := app.Models.AuditLogs.Insert(&entry); err != nil {
+		log.Printf("Failed to write audit log: %v", err)
+	}
+}
+
+// extractAPIKey reads a key from the X-API-Key header or from a Bearer value that looks like a key.
+func extractAPIKey(ctx *gin.Context) string {
+	if apiKey := ctx.GetHeader("X-API-Key"); apiKey != "" {
+		return apiKey
+	}
+
+	bearer := strings.TrimPrefix(ctx.GetHeader("Authorization"), "Bearer ")
+	if utils.IsAPIKey(bearer) {
+		return bearer
+	}
+
+	return ""
+}
+
+func authenticateAPIKey(app *app.Application, ctx *gin.Context, key string) {
+	prefix, ok := utils.ParseAPIKeyPrefix(key)
+	if !ok {
+		utils.ErrorResponse(ctx, "Invalid API key", http.StatusUnauthorized)
+		ctx.Abort()
+		return
+	}
+
+	apiKey, err := app.Models.APIKeys.GetByPrefix(prefix)
+	if err != nil {
+		utils.ErrorResponse(ctx, "something went wrong: 6", http.StatusUnauthorized)
+		ctx.Abort()
+		return
+	}
+	if apiKey == nil || subtle.ConstantTimeCompare([]byte(apiKey.KeyHash), []byte(utils.HashToken(key))) != 1 {
+		utils.ErrorResponse(ctx, "Invalid API key", http.StatusUnauthorized)
+		ctx.Abort()
+		return
+	}
+	if apiKey.RevokedAt != nil || (apiKey.ExpiresAt != nil && apiKey.ExpiresAt.Before(time.Now())) {
+		utils.ErrorResponse(ctx, "API key has been revoked or has expired", http.StatusUnauthorized)
+		ctx.Abort()
+		return
+	}
+
+	user, err := storeOrRetrieveFromRedis(app, apiKey.UserID)
+	if err != nil {
+		utils.ErrorResponse(ctx, err.Error(), http.StatusUnauthorized)
+		ctx.Abort()
+		return
+	}
+
+	if err := app.Models.APIKeys.TouchLastUsed(apiKey.ID); err != nil {
+		log.Printf("Failed to update API key last use: %v", err)
+	}
+
+	ctx.Set("user", user)
+	ctx.Set("apiKeyScopes", apiKey.Scopes)
+	ctx.Next()
+}
+
+func checkTokenRevocation(app *app.Application, claims *utils.CustomClaims) error {
+	if claims.ID == "" {
+		return errors.New("invalid or expired token")
+	}
+
+	revoked, err := app.Redis.IsTokenRevoked(claims.ID)
+	if err != nil {
+		return errors.New("something went wrong: 4")
+	}
+	if revoked {
+		return errors.New("token has been revoked")
+	}
+
+	tokenVersion, err := app.Redis.GetTokenVersion(claims.UserID)
+	if err != nil {
+		return errors.New("something went wrong: 5")
+	}
+	if claims.TokenVersion != tokenVersion {
+		return errors.New("token has been revoked")
+	}
+
+	if claims.SessionID != 0 {
+		revoked, err := app.Redis.IsSessionRevoked(claims.SessionID)
+		if err != nil {
+			return errors.New("something went wrong: 7")
+		}
+		if revoked {
+			return errors.New("session has been revoked")
+		}
+	}
+
+	return nil
+}
+
 func storeOrRetrieveFromRedis(app *app.Application, userId int64) (*models.UserSerializer, error) {
 	// find user
 	cacheKey := utils.ConstructRedisUserKey(userId)
@@ -3,8 +3,11 @@ package middlewares
 import (
 	"github.com/gin-gonic/gin"
 	"github.com/vickon16/go-gin-rest-api/internal/database/models"
+	"github.com/vickon16/go-gin-rest-api/internal/utils"
 )
 
+// GetUserFromContext returns the effective user of the request. While an admin
+// impersonates someone this is the impersonated user, see GetActorFromContext.
 func GetUserFromContext(ctx *gin.Context) *models.UserSerializer {
 	contextUser, exists := ctx.Get("user")
 	if !exists {
@@ -18,3 +21,41 @@ func GetUserFromContext(ctx *gin.Context) *models.UserSerializer {
 
 	return user
 }
+
+// GetActorFromContext returns the admin behind an impersonation token.
+// The second value is false when the user is acting as themselves.
+func GetActorFromContext(ctx *gin.Context) (*models.UserSerializer, bool) {
+	contextActor, exists := ctx.Get("actor")
+	if !exists {
+		return nil, false
+	}
+
+	actor, ok := contextActor.(*models.UserSerializer)
+	return actor, ok
+}
+
+func GetClaimsFromContext(ctx *gin.Context) *utils.CustomClaims {
+	contextClaims, exists := ctx.Get("claims")
+	if !exists {
+		return &utils.CustomClaims{}
+	}
+
+	claims, ok := contextClaims.(*utils.CustomClaims)
+	if !ok {
+		return &utils.CustomClaims{}
+	}
+
+	return claims
+}
+
+// GetAPIKeyScopesFromContext returns the scopes of the API key used for the request.
+// The second value is false when the request was authenticated with a JWT.
+func GetAPIKeyScopesFromContext(ctx *gin.Context) ([]string, bool) {
+	contextScopes, exists := ctx.Get("apiKeyScopes")
+	if !exists {
+		return nil, false
+	}
+
+	scopes, ok := contextScopes.([]string)
+	return scopes, ok
+}
@@ -0,0 +1,73 @@
+package middlewares
+
+import (
+	"net/http"
+	"slices"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/vickon16/go-gin-rest-api/internal/app"
+	"github.com/vickon16/go-gin-rest-api/internal/authz"
+	"github.com/vickon16/go-gin-rest-api/internal/utils"
+)
+
+// RequireRole only lets users with one of the given roles through.
+// It must run after AuthMiddleware.
+func RequireRole(app *app.Application, roles ...string) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		user := GetUserFromContext(ctx)
+		if !slices.Contains(roles, user.Role) {
+			app.Authz.Deny(ctx, authz.Action("role:"+strings.Join(roles, ",")), authz.Resource{})
+			return
+		}
+
+		ctx.Next()
+	}
+}
+
+// RequirePermission only lets users whose role grants the permission through.
+// It must run after AuthMiddleware.
+func RequirePermission(app *app.Application, permission authz.Permission) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		user := GetUserFromContext(ctx)
+		if !authz.HasPermission(user.Role, permission) {
+			app.Authz.Deny(ctx, authz.Action(permission), authz.Resource{})
+			return
+		}
+
+		// API keys are further limited to the scopes they were created with
+		if scopes, isAPIKey := GetAPIKeyScopesFromContext(ctx); isAPIKey && !slices.Contains(scopes, string(permission)) {
+			app.Authz.Deny(ctx, authz.Action(permission), authz.Resource{})
+			return
+		}
+
+		ctx.Next()
+	}
+}
+
+// RejectAPIKeys only lets requests authenticated with a JWT through,
+// so a leaked API key cannot be used to manage credentials.
+func RejectAPIKeys() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		if _, isAPIKey := GetAPIKeyScopesFromContext(ctx); isAPIKey {
+			utils.ErrorResponse(ctx, "This endpoint cannot be used with an API key", http.StatusForbidden)
+			ctx.Abort()
+			return
+		}
+
+		ctx.Next()
+	}
+}
+
+// RejectImpersonation blocks actions an admin must not take on a user's behalf.
+func RejectImpersonation() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		if _, impersonating := GetActorFromContext(ctx); impersonating {
+			utils.ErrorResponse(ctx, "This action is not allowed while impersonating", http.StatusForbidden)
+			ctx.Abort()
+			return
+		}
+
+		ctx.Next()
+	}
+}
@@ -0,0 +1,31 @@
+package middlewares
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/vickon16/go-gin-rest-api/internal/env"
+	"github.com/vickon16/go-gin-rest-api/internal/utils"
+)
+
+// RequireVerifiedEmail blocks users who have not verified their email address yet.
+// The policy is turned off with REQUIRE_EMAIL_VERIFICATION=false. It must run after AuthMiddleware.
+func RequireVerifiedEmail() gin.HandlerFunc {
+	enabled := env.GetEnvBool("REQUIRE_EMAIL_VERIFICATION", true)
+
+	return func(ctx *gin.Context) {
+		if !enabled {
+			ctx.Next()
+			return
+		}
+
+		user := GetUserFromContext(ctx)
+		if user.EmailVerifiedAt == nil {
+			utils.ErrorResponse(ctx, "Please verify your email address first", http.StatusForbidden)
+			ctx.Abort()
+			return
+		}
+
+		ctx.Next()
+	}
+}
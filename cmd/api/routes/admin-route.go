@@ -0,0 +1,16 @@
+package routes
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/vickon16/go-gin-rest-api/cmd/api/middlewares"
+	"github.com/vickon16/go-gin-rest-api/cmd/api/services"
+	"github.com/vickon16/go-gin-rest-api/internal/app"
+	"github.com/vickon16/go-gin-rest-api/internal/database/models"
+)
+
+func setupAdminControllers(router *gin.RouterGroup, app *app.Application) {
+	priv := router.Group("/admin", middlewares.AuthMiddleware(app), middlewares.RejectAPIKeys(), middlewares.RequireRole(app, models.RoleAdmin))
+
+	priv.POST("/users/:id/unlock", services.UnlockUser(app))
+	priv.POST("/users/:id/impersonate", services.ImpersonateUser(app))
+}
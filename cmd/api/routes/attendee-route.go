@@ -5,18 +5,20 @@ import (
 	"github.com/vickon16/go-gin-rest-api/cmd/api/middlewares"
 	"github.com/vickon16/go-gin-rest-api/cmd/api/services"
 	"github.com/vickon16/go-gin-rest-api/internal/app"
+	"github.com/vickon16/go-gin-rest-api/internal/authz"
 )
 
 func setupAttendeesControllers(router *gin.RouterGroup, app *app.Application) {
 	priv := router.Group("/attendees", middlewares.AuthMiddleware(app))
 
-	priv.POST("/", services.CreateAttendee(app))
+	priv.POST("/", middlewares.RequirePermission(app, authz.PermissionCreateAttendees), middlewares.RequireVerifiedEmail(), services.CreateAttendee(app))
 
-	priv.GET("/", services.GetAllAttendees(app))
-	priv.GET("/:id", services.GetAttendee(app))
-	priv.GET("/:id/events", services.GetEventsByAttendee(app))
+	priv.GET("/", middlewares.RequirePermission(app, authz.PermissionReadAttendees), services.GetAllAttendees(app))
+	priv.GET("/:id", middlewares.RequirePermission(app, authz.PermissionReadAttendees), services.GetAttendee(app))
+	priv.GET("/:id/status-changes", middlewares.RequirePermission(app, authz.PermissionReadAttendees), services.GetAttendeeStatusChanges(app))
+	priv.GET("/:id/events", middlewares.RequirePermission(app, authz.PermissionReadEvents), services.GetEventsByAttendee(app))
 
-	priv.PUT("/:id", services.UpdateAttendee(app))
+	priv.PUT("/:id/status", middlewares.RequirePermission(app, authz.PermissionUpdateAttendees), services.UpdateAttendeeStatus(app))
 
-	priv.DELETE("/:id", services.DeleteAttendee(app))
+	priv.DELETE("/:id", middlewares.RequirePermission(app, authz.PermissionDeleteAttendees), services.DeleteAttendee(app))
 }
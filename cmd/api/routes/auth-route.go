@@ -2,6 +2,7 @@ package routes
 
 import (
 	"github.com/gin-gonic/gin"
+	"github.com/vickon16/go-gin-rest-api/cmd/api/middlewares"
 	"github.com/vickon16/go-gin-rest-api/cmd/api/services"
 	"github.com/vickon16/go-gin-rest-api/internal/app"
 )
@@ -11,4 +12,21 @@ func setupAuthControllers(router *gin.RouterGroup, app *app.Application) {
 
 	r.POST("/register", services.RegisterUser(app))
 	r.POST("/login", services.LoginUser(app))
+	r.POST("/refresh", services.RefreshToken(app))
+	r.POST("/forgot-password", services.ForgotPassword(app))
+	r.POST("/reset-password", services.ResetPassword(app))
+	r.POST("/verify-email", services.VerifyEmail(app))
+	r.POST("/2fa/verify", services.VerifyTwoFactorLogin(app))
+	r.POST("/magic-link", services.RequestMagicLink(app))
+	r.POST("/magic-link/verify", services.VerifyMagicLink(app))
+	r.GET("/oidc/:provider", services.StartOIDCLogin(app))
+	r.GET("/oidc/:provider/callback", services.OIDCCallback(app))
+
+	priv := r.Group("", middlewares.AuthMiddleware(app), middlewares.RejectAPIKeys())
+	priv.POST("/logout", services.LogoutUser(app))
+	priv.POST("/logout-all", middlewares.RejectImpersonation(), services.LogoutAllSessions(app))
+	priv.POST("/resend-verification", services.ResendVerification(app))
+	priv.POST("/2fa/enroll", middlewares.RejectImpersonation(), services.EnrollTwoFactor(app))
+	priv.POST("/2fa/confirm", middlewares.RejectImpersonation(), services.ConfirmTwoFactor(app))
+	priv.POST("/2fa/disable", middlewares.RejectImpersonation(), services.DisableTwoFactor(app))
 }
@@ -5,20 +5,24 @@ import (
 	"github.com/vickon16/go-gin-rest-api/cmd/api/middlewares"
 	"github.com/vickon16/go-gin-rest-api/cmd/api/services"
 	"github.com/vickon16/go-gin-rest-api/internal/app"
+	"github.com/vickon16/go-gin-rest-api/internal/authz"
 )
 
 func setupEventsControllers(router *gin.RouterGroup, app *app.Application) {
 	priv := router.Group("/events", middlewares.AuthMiddleware(app))
 
-	priv.POST("/", services.CreateEvent(app))
-	priv.POST("/:id/attendees/:userId", services.AddAttendeeToEvent(app))
+	priv.POST("/", middlewares.RequirePermission(app, authz.PermissionCreateEvents), middlewares.RequireVerifiedEmail(), services.CreateEvent(app))
+	priv.POST("/:id/attendees/:userId", middlewares.RequirePermission(app, authz.PermissionUpdateEvents), services.AddAttendeeToEvent(app))
+	priv.POST("/:id/organizers/:userId", middlewares.RequirePermission(app, authz.PermissionUpdateEvents), services.AddCoOrganizerToEvent(app))
 
-	priv.GET("/", services.GetAllEvent(app))
-	priv.GET("/:id", services.GetEvent(app))
-	priv.GET("/:id/attendees", services.GetAttendeesForEvent(app))
+	priv.GET("/", middlewares.RequirePermission(app, authz.PermissionReadEvents), services.GetAllEvent(app))
+	priv.GET("/search", middlewares.RequirePermission(app, authz.PermissionReadEvents), services.SearchEvents(app))
+	priv.GET("/:id", middlewares.RequirePermission(app, authz.PermissionReadEvents), services.GetEvent(app))
+	priv.GET("/:id/attendees", middlewares.RequirePermission(app, authz.PermissionReadAttendees), services.GetAttendeesForEvent(app))
 
-	priv.PUT("/:id", services.UpdateEvent(app))
+	priv.PUT("/:id", middlewares.RequirePermission(app, authz.PermissionUpdateEvents), services.UpdateEvent(app))
 
-	priv.DELETE("/:id/attendees/:userId", services.DeleteAttendeeFromEvent(app))
-	priv.DELETE("/:id", services.DeleteEvent(app))
+	priv.DELETE("/:id/attendees/:userId", middlewares.RequirePermission(app, authz.PermissionUpdateEvents), services.DeleteAttendeeFromEvent(app))
+	priv.DELETE("/:id/organizers/:userId", middlewares.RequirePermission(app, authz.PermissionUpdateEvents), services.DeleteCoOrganizerFromEvent(app))
+	priv.DELETE("/:id", middlewares.RequirePermission(app, authz.PermissionDeleteEvents), services.DeleteEvent(app))
 }
@@ -7,8 +7,10 @@ import (
 	"github.com/gin-gonic/gin"
 	swaggerFiles "github.com/swaggo/files"
 	ginSwagger "github.com/swaggo/gin-swagger"
+	"github.com/vickon16/go-gin-rest-api/cmd/api/services"
 	"github.com/vickon16/go-gin-rest-api/internal/app"
 	"github.com/vickon16/go-gin-rest-api/internal/env"
+	"github.com/vickon16/go-gin-rest-api/internal/storage"
 	"github.com/vickon16/go-gin-rest-api/internal/utils"
 )
 
@@ -72,6 +74,17 @@ func SetupRoutes(app *app.Application) http.Handler {
 	// Attendees
 	setupAttendeesControllers(v1, app)
 
+	// Admin
+	setupAdminControllers(v1, app)
+
+	// Public signing keys
+	g.GET("/.well-known/jwks.json", services.GetJWKS())
+
+	// Uploaded files, when they are kept on this server
+	if local, ok := app.Storage.(*storage.LocalStorage); ok {
+		g.Static(storage.LocalURLPath, local.Dir)
+	}
+
 	// for swagger docs
 	g.GET("/swagger/*any", func(c *gin.Context) {
 		if c.Request.RequestURI == "/swagger/" {
@@ -5,6 +5,8 @@ import (
 	"github.com/vickon16/go-gin-rest-api/cmd/api/middlewares"
 	"github.com/vickon16/go-gin-rest-api/cmd/api/services"
 	"github.com/vickon16/go-gin-rest-api/internal/app"
+	"github.com/vickon16/go-gin-rest-api/internal/authz"
+	"github.com/vickon16/go-gin-rest-api/internal/database/models"
 )
 
 func setupUserControllers(router *gin.RouterGroup, app *app.Application) {
@@ -12,9 +14,38 @@ func setupUserControllers(router *gin.RouterGroup, app *app.Application) {
 	// Private routes
 	priv := router.Group("/users", middlewares.AuthMiddleware(app))
 
-	priv.GET("/", services.GetAllUsers(app))
-	priv.GET("/:id", services.GetUser(app))
-	priv.GET("/me", services.GetMe(app))
-	priv.PUT("/:id", services.UpdateUser(app))
-	priv.DELETE("/:id", services.DeleteUser(app))
+	priv.GET("/", middlewares.RequirePermission(app, authz.PermissionReadUsers), services.GetAllUsers(app))
+	priv.GET("/:id", middlewares.RequirePermission(app, authz.PermissionReadUsers), services.GetUser(app))
+	priv.GET("/me", middlewares.RequirePermission(app, authz.PermissionReadUsers), services.GetMe(app))
+	priv.PUT("/:id", middlewares.RequirePermission(app, authz.PermissionUpdateUsers), services.UpdateUser(app))
+	priv.PUT("/:id/role", middlewares.RequireRole(app, models.RoleAdmin), middlewares.RejectAPIKeys(), services.UpdateUserRole(app))
+	priv.DELETE("/:id", middlewares.RequirePermission(app, authz.PermissionDeleteUsers), middlewares.RejectImpersonation(), services.DeleteUser(app))
+
+	priv.GET("/me/feed", middlewares.RequirePermission(app, authz.PermissionReadEvents), services.GetMyFeed(app))
+	priv.GET("/:id/followers", middlewares.RequirePermission(app, authz.PermissionReadUsers), services.GetFollowers(app))
+	priv.GET("/:id/following", middlewares.RequirePermission(app, authz.PermissionReadUsers), services.GetFollowing(app))
+	// Following changes the caller's own state, so read-only API keys cannot do it
+	priv.POST("/:id/follow", middlewares.RequirePermission(app, authz.PermissionUpdateUsers), services.FollowUser(app))
+	priv.DELETE("/:id/follow", middlewares.RequirePermission(app, authz.PermissionUpdateUsers), services.UnfollowUser(app))
+
+	account := priv.Group("/me", middlewares.RejectAPIKeys(), middlewares.RejectImpersonation())
+	account.GET("/export", services.ExportMyData(app))
+	account.POST("/deletion", services.RequestAccountDeletion(app))
+	account.POST("/deletion/confirm", services.ConfirmAccountDeletion(app))
+	account.DELETE("/deletion", services.CancelAccountDeletion(app))
+
+	avatar := priv.Group("/me/avatar", middlewares.RequirePermission(app, authz.PermissionUpdateUsers))
+	avatar.PUT("", services.UploadAvatar(app))
+	avatar.DELETE("", services.DeleteAvatar(app))
+
+	// Personal API keys can only be managed with a logged in session
+	apiKeys := priv.Group("/me/api-keys", middlewares.RejectAPIKeys(), middlewares.RejectImpersonation())
+	apiKeys.GET("/", services.GetMyAPIKeys(app))
+	apiKeys.POST("/", services.CreateAPIKey(app))
+	apiKeys.PUT("/:keyId", services.UpdateAPIKey(app))
+	apiKeys.DELETE("/:keyId", services.RevokeAPIKey(app))
+
+	sessions := priv.Group("/me/sessions", middlewares.RejectAPIKeys(), middlewares.RejectImpersonation())
+	sessions.GET("/", services.GetMySessions(app))
+	sessions.DELETE("/:sessionId", services.RevokeMySession(app))
 }
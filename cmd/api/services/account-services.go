@@ -0,0 +1,351 @@
+package services
+
+import (
+	"archive/zip"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/vickon16/go-gin-rest-api/cmd/api/middlewares"
+	"github.com/vickon16/go-gin-rest-api/internal/app"
+	"github.com/vickon16/go-gin-rest-api/internal/database/models"
+	"github.com/vickon16/go-gin-rest-api/internal/env"
+	"github.com/vickon16/go-gin-rest-api/internal/mailer"
+	"github.com/vickon16/go-gin-rest-api/internal/utils"
+)
+
+// ExportMyData returns everything stored about the user, as JSON or with
+// ?format=zip as an archive holding one JSON file per section.
+func ExportMyData(app *app.Application) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		contextUser := middlewares.GetUserFromContext(c)
+
+		export, err := buildUserExport(app, contextUser.ID)
+		if err != nil {
+			log.Printf("Failed to export user data: %v", err)
+			utils.ErrorResponse(c, "Failed to export user data", http.StatusInternalServerError)
+			return
+		}
+
+		filename := fmt.Sprintf("user-%d-export-%s", contextUser.ID, export.ExportedAt.Format("20060102"))
+
+		switch format := c.DefaultQuery("format", "json"); format {
+		case "json":
+			c.Header("Content-Disposition", `attachment; filename="`+filename+`.json"`)
+			utils.SuccessResponse(c, "Successfully exported user data", export)
+		case "zip":
+			c.Header("Content-Type", "application/zip")
+			c.Header("Content-Disposition", `attachment; filename="`+filename+`.zip"`)
+			c.Status(http.StatusOK)
+
+			if err := writeUserExportZip(c.Writer, export); err != nil {
+				log.Printf("Failed to write user data archive: %v", err)
+			}
+		default:
+			utils.ErrorResponse(c, "Unsupported export format: "+format, http.StatusBadRequest)
+		}
+	}
+}
+
+// RequestAccountDeletion schedules the account to be anonymized once the grace period is over.
+// The request is confirmed with the password, or when none is given, with a link emailed to the
+// user, since accounts created through a provider or login links never set a password.
+func RequestAccountDeletion(app *app.Application) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var dto models.DeleteAccountDto
+
+		// The body is optional, it only carries the password
+		if c.Request.ContentLength != 0 {
+			if err := c.ShouldBindJSON(&dto); err != nil {
+				utils.ErrorResponse(c, err.Error(), http.StatusBadRequest)
+				return
+			}
+		}
+
+		contextUser := middlewares.GetUserFromContext(c)
+
+		user, err := app.Models.Users.GetUserByEmail(contextUser.Email, true)
+		if err != nil || user == nil {
+			utils.ErrorResponse(c, "Failed to get user", http.StatusInternalServerError)
+			return
+		}
+
+		if dto.Password == "" {
+			if err := sendAccountDeletionConfirmation(app, user); err != nil {
+				log.Printf("Failed to send account deletion confirmation: %v", err)
+				utils.ErrorResponse(c, "Failed to send account deletion confirmation", http.StatusInternalServerError)
+				return
+			}
+
+			utils.SuccessResponse(c, "Confirm the deletion with the link sent to your email", nil, http.StatusAccepted)
+			return
+		}
+
+		if !checkCurrentPassword(app, c, user, dto.Password) {
+			return
+		}
+
+		scheduleAccountDeletion(app, c, user)
+	}
+}
+
+// ConfirmAccountDeletion schedules the deletion requested without a password, using the emailed token.
+func ConfirmAccountDeletion(app *app.Application) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var dto models.ConfirmAccountDeletionDto
+
+		if err := c.ShouldBindJSON(&dto); err != nil {
+			utils.ErrorResponse(c, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		token, err := app.Models.OneTimeTokens.Consume(utils.HashToken(dto.Token), models.TokenPurposeAccountDeletion)
+		if err != nil {
+			utils.ErrorResponse(c, "Failed to confirm account deletion", http.StatusInternalServerError)
+			return
+		}
+
+		// The link only works in the account it was sent for
+		contextUser := middlewares.GetUserFromContext(c)
+		if token == nil || token.UserID != contextUser.ID {
+			utils.ErrorResponse(c, "Invalid or expired confirmation token", http.StatusBadRequest)
+			return
+		}
+
+		user, err := app.Models.Users.Get(contextUser.ID)
+		if err != nil || user == nil {
+			utils.ErrorResponse(c, "Failed to get user", http.StatusInternalServerError)
+			return
+		}
+
+		scheduleAccountDeletion(app, c, user)
+	}
+}
+
+// scheduleAccountDeletion marks the account for deletion, unless it already is, and tells the user when it happens.
+func scheduleAccountDeletion(app *app.Application, c *gin.Context, user *models.User) {
+	requestedAt := time.Now()
+	if user.DeletionRequestedAt != nil {
+		requestedAt = *user.DeletionRequestedAt
+	} else if err := app.Models.Users.SetDeletionRequested(user.ID, &requestedAt); err != nil {
+		utils.ErrorResponse(c, "Failed to request account deletion", http.StatusInternalServerError)
+		return
+	}
+
+	app.Redis.Delete(utils.ConstructRedisUserKey(user.ID))
+
+	deleteAfter := requestedAt.Add(accountDeletionGracePeriod())
+	err := app.Mailer.Send(mailer.Message{
+		To:      user.Email,
+		Subject: "Your account will be deleted",
+		Body: fmt.Sprintf(
+			"Hi %s,\n\nYour account is scheduled for deletion on %s. Log in and cancel the request before then if you change your mind.\n",
+			user.Name, deleteAfter.Format("January 2, 2006"),
+		),
+	})
+	if err != nil {
+		log.Printf("Failed to send account deletion email: %v", err)
+	}
+
+	utils.SuccessResponse(c, "Account deletion scheduled", models.DeletionRequestSerializer{
+		RequestedAt: requestedAt,
+		DeleteAfter: deleteAfter,
+	}, http.StatusAccepted)
+}
+
+func sendAccountDeletionConfirmation(app *app.Application, user *models.User) error {
+	expirationMinutes := env.GetEnvInt("ACCOUNT_DELETION_CONFIRMATION_MINUTES", 60)
+	token, err := IssueOneTimeToken(app, user.ID, models.TokenPurposeAccountDeletion, time.Duration(expirationMinutes)*time.Minute)
+	if err != nil {
+		return err
+	}
+
+	confirmUrl := env.GetEnvString("APP_URL", "http://localhost:3000") + "/confirm-account-deletion?token=" + url.QueryEscape(token)
+	return app.Mailer.Send(mailer.Message{
+		To:      user.Email,
+		Subject: "Confirm deleting your account",
+		Body: fmt.Sprintf(
+			"Hi %s,\n\nUse the link below to confirm that your account should be deleted. It expires in %d minutes.\n\n%s\n\nIf you did not request this, you can ignore this email.\n",
+			user.Name, expirationMinutes, confirmUrl,
+		),
+	})
+}
+
+func CancelAccountDeletion(app *app.Application) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		contextUser := middlewares.GetUserFromContext(c)
+
+		if err := app.Models.Users.SetDeletionRequested(contextUser.ID, nil); err != nil {
+			utils.ErrorResponse(c, "Failed to cancel account deletion", http.StatusInternalServerError)
+			return
+		}
+
+		app.Redis.Delete(utils.ConstructRedisUserKey(contextUser.ID))
+
+		utils.SuccessResponse(c, "Account deletion cancelled", nil)
+	}
+}
+
+// StartAccountDeletionWorker anonymizes accounts whose grace period is over, checking every interval.
+func StartAccountDeletionWorker(app *app.Application, interval time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			purgeDueAccounts(app)
+			<-ticker.C
+		}
+	}()
+}
+
+// purgeDueAccounts runs on every instance, the advisory lock keeps them from anonymizing the same accounts at once.
+func purgeDueAccounts(app *app.Application) {
+	release, ok, err := app.Models.Users.TryLockAccountDeletion()
+	if err != nil {
+		log.Printf("Failed to lock account deletion: %v", err)
+		return
+	}
+	if !ok {
+		return
+	}
+	defer release()
+
+	ids, err := app.Models.Users.GetDueForDeletion(time.Now().Add(-accountDeletionGracePeriod()))
+	if err != nil {
+		log.Printf("Failed to get accounts due for deletion: %v", err)
+		return
+	}
+
+	for _, id := range ids {
+		if err := anonymizeAccount(app, id); err != nil {
+			log.Printf("Failed to anonymize user %d: %v", id, err)
+			continue
+		}
+		log.Printf("Anonymized user %d after deletion request", id)
+	}
+}
+
+// anonymizeAccount turns the user into a "Deleted user" placeholder and ends every session.
+func anonymizeAccount(app *app.Application, userId int64) error {
+	user, err := app.Models.Users.Get(userId)
+	if err != nil {
+		return err
+	}
+
+	if err := app.Models.Users.Anonymize(userId); err != nil {
+		return err
+	}
+
+	if user != nil {
+		deleteAvatarFiles(app, user.Avatar)
+	}
+
+	app.Redis.Delete(utils.ConstructRedisUserKey(userId))
+
+	// Sessions and refresh tokens are gone, this rejects the access tokens still in flight
+	return app.Redis.IncrementTokenVersion(userId)
+}
+
+func accountDeletionGracePeriod() time.Duration {
+	return time.Duration(env.GetEnvInt("ACCOUNT_DELETION_GRACE_DAYS", 14)) * 24 * time.Hour
+}
+
+func buildUserExport(app *app.Application, userId int64) (*models.UserExportSerializer, error) {
+	user, err := app.Models.Users.Get(userId)
+	if err != nil {
+		return nil, err
+	}
+	if user == nil {
+		return nil, fmt.Errorf("user %d not found", userId)
+	}
+
+	viewer := models.NewViewer(user.ID, user.Role)
+
+	export := &models.UserExportSerializer{
+		ExportedAt:      time.Now(),
+		Profile:         models.CreateResponseUser(user),
+		OrganizedEvents: []models.EventSerializer{},
+		AttendedEvents:  []models.EventSerializer{},
+		Sessions:        []models.SessionSerializer{},
+		APIKeys:         []models.APIKeySerializer{},
+		LinkedAccounts:  []models.UserIdentity{},
+	}
+
+	organized, err := app.Models.Events.GetByOrganizer(userId)
+	if err != nil {
+		return nil, err
+	}
+	for _, event := range organized {
+		export.OrganizedEvents = append(export.OrganizedEvents, models.CreateResponseEvent(event, viewer))
+	}
+
+	attended, err := app.Models.Events.GetAttendedByUser(userId)
+	if err != nil {
+		return nil, err
+	}
+	for _, event := range attended {
+		export.AttendedEvents = append(export.AttendedEvents, models.CreateResponseEvent(event, viewer))
+	}
+
+	sessions, err := app.Models.Sessions.GetActiveByUser(userId)
+	if err != nil {
+		return nil, err
+	}
+	for _, session := range sessions {
+		export.Sessions = append(export.Sessions, models.CreateResponseSession(session))
+	}
+
+	apiKeys, err := app.Models.APIKeys.GetActiveByUser(userId)
+	if err != nil {
+		return nil, err
+	}
+	for _, apiKey := range apiKeys {
+		export.APIKeys = append(export.APIKeys, models.CreateResponseAPIKey(apiKey))
+	}
+
+	identities, err := app.Models.Identities.GetByUser(userId)
+	if err != nil {
+		return nil, err
+	}
+	for _, identity := range identities {
+		export.LinkedAccounts = append(export.LinkedAccounts, *identity)
+	}
+
+	return export, nil
+}
+
+func writeUserExportZip(w http.ResponseWriter, export *models.UserExportSerializer) error {
+	archive := zip.NewWriter(w)
+
+	files := []struct {
+		name string
+		data any
+	}{
+		{"profile.json", export.Profile},
+		{"organized-events.json", export.OrganizedEvents},
+		{"attended-events.json", export.AttendedEvents},
+		{"sessions.json", export.Sessions},
+		{"api-keys.json", export.APIKeys},
+		{"linked-accounts.json", export.LinkedAccounts},
+	}
+
+	for _, file := range files {
+		writer, err := archive.CreateHeader(&zip.FileHeader{Name: file.name, Method: zip.Deflate, Modified: export.ExportedAt})
+		if err != nil {
+			return err
+		}
+
+		encoder := json.NewEncoder(writer)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(file.data); err != nil {
+			return err
+		}
+	}
+
+	return archive.Close()
+}
@@ -0,0 +1,118 @@
+package services
+
+import (
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/vickon16/go-gin-rest-api/cmd/api/middlewares"
+	"github.com/vickon16/go-gin-rest-api/internal/app"
+	"github.com/vickon16/go-gin-rest-api/internal/database/models"
+	"github.com/vickon16/go-gin-rest-api/internal/utils"
+)
+
+func UnlockUser(app *app.Application) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+		if err != nil {
+			utils.ErrorResponse(c, "Invalid user Id", http.StatusBadRequest)
+			return
+		}
+
+		user, err := app.Models.Users.Get(id)
+		if err != nil {
+			utils.ErrorResponse(c, "Failed to get user", http.StatusInternalServerError)
+			return
+		}
+		if user == nil {
+			utils.ErrorResponse(c, "User not found", http.StatusNotFound)
+			return
+		}
+
+		if err := resetLoginFailures(app, user.Email); err != nil {
+			log.Printf("Failed to unlock user %d: %v", id, err)
+			utils.ErrorResponse(c, "Failed to unlock user", http.StatusInternalServerError)
+			return
+		}
+
+		utils.SuccessResponse(c, "Successfully unlocked user", nil)
+	}
+}
+
+// ImpersonateUser issues a short-lived token that acts as the user. Every
+// request made with it is recorded in the audit log against the admin.
+func ImpersonateUser(app *app.Application) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+		if err != nil {
+			utils.ErrorResponse(c, "Invalid user Id", http.StatusBadRequest)
+			return
+		}
+
+		admin := middlewares.GetUserFromContext(c)
+		if admin.ID == id {
+			utils.ErrorResponse(c, "You cannot impersonate yourself", http.StatusBadRequest)
+			return
+		}
+
+		user, err := app.Models.Users.Get(id)
+		if err != nil {
+			utils.ErrorResponse(c, "Failed to get user", http.StatusInternalServerError)
+			return
+		}
+		if user == nil {
+			utils.ErrorResponse(c, "User not found", http.StatusNotFound)
+			return
+		}
+		if user.Role == models.RoleAdmin {
+			utils.ErrorResponse(c, "Admins cannot be impersonated", http.StatusForbidden)
+			return
+		}
+
+		tokenVersion, err := app.Redis.GetTokenVersion(user.ID)
+		if err != nil {
+			utils.ErrorResponse(c, "Something went wrong", http.StatusInternalServerError)
+			return
+		}
+		adminTokenVersion, err := app.Redis.GetTokenVersion(admin.ID)
+		if err != nil {
+			utils.ErrorResponse(c, "Something went wrong", http.StatusInternalServerError)
+			return
+		}
+
+		token, expiresAt, err := utils.GenerateImpersonationToken(user.ID, user.Email, user.Role, tokenVersion, utils.ActorClaim{
+			UserID:       admin.ID,
+			Email:        admin.Email,
+			TokenVersion: adminTokenVersion,
+		})
+		if err != nil {
+			log.Printf("Failed to create impersonation token: %v", err)
+			utils.ErrorResponse(c, "Something went wrong", http.StatusInternalServerError)
+			return
+		}
+
+		entry := models.AuditLog{
+			ActorID:      &admin.ID,
+			Action:       models.AuditActionImpersonate,
+			ResourceType: "user",
+			ResourceID:   &user.ID,
+			Outcome:      models.AuditOutcomeAllowed,
+			Method:       c.Request.Method,
+			Path:         c.FullPath(),
+			IPAddress:    c.ClientIP(),
+		}
+		if err := app.Models.AuditLogs.Insert(&entry); err != nil {
+			// Impersonation without a trail is not allowed
+			log.Printf("Failed to write audit log: %v", err)
+			utils.ErrorResponse(c, "Something went wrong", http.StatusInternalServerError)
+			return
+		}
+
+		utils.SuccessResponse(c, "Impersonation token issued", models.ImpersonationSerializer{
+			Token:     token,
+			ExpiresAt: expiresAt,
+			User:      models.CreateResponseUser(user),
+		})
+	}
+}
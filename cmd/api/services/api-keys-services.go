@@ -0,0 +1,158 @@
+package services
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"slices"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/vickon16/go-gin-rest-api/cmd/api/middlewares"
+	"github.com/vickon16/go-gin-rest-api/internal/app"
+	"github.com/vickon16/go-gin-rest-api/internal/authz"
+	"github.com/vickon16/go-gin-rest-api/internal/database/models"
+	"github.com/vickon16/go-gin-rest-api/internal/utils"
+)
+
+func GetMyAPIKeys(app *app.Application) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		contextUser := middlewares.GetUserFromContext(c)
+
+		apiKeys, err := app.Models.APIKeys.GetActiveByUser(contextUser.ID)
+		if err != nil {
+			log.Printf("Error getting API keys: %v", err)
+			utils.ErrorResponse(c, "Failed to get API keys", http.StatusInternalServerError)
+			return
+		}
+
+		serializedKeys := []models.APIKeySerializer{}
+		for _, apiKey := range apiKeys {
+			serializedKeys = append(serializedKeys, models.CreateResponseAPIKey(apiKey))
+		}
+
+		utils.SuccessResponse(c, "Successfully retrieved API keys", serializedKeys)
+	}
+}
+
+func CreateAPIKey(app *app.Application) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var dto models.CreateAPIKeyDto
+
+		if err := c.ShouldBindJSON(&dto); err != nil {
+			utils.ErrorResponse(c, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		contextUser := middlewares.GetUserFromContext(c)
+		if err := validateAPIKeyScopes(contextUser, dto.Scopes); err != nil {
+			utils.ErrorResponse(c, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		key, prefix, err := utils.GenerateAPIKey()
+		if err != nil {
+			utils.ErrorResponse(c, "Something went wrong", http.StatusInternalServerError)
+			return
+		}
+
+		apiKey := models.APIKey{
+			UserID:  contextUser.ID,
+			Name:    dto.Name,
+			Prefix:  prefix,
+			KeyHash: utils.HashToken(key),
+			Scopes:  slices.Compact(slices.Sorted(slices.Values(dto.Scopes))),
+		}
+		if dto.ExpiresInDays > 0 {
+			expiresAt := time.Now().Add(time.Duration(dto.ExpiresInDays) * 24 * time.Hour)
+			apiKey.ExpiresAt = &expiresAt
+		}
+
+		if err := app.Models.APIKeys.Insert(&apiKey); err != nil {
+			log.Printf("Error inserting API key: %v", err)
+			utils.ErrorResponse(c, "Failed to create API key", http.StatusInternalServerError)
+			return
+		}
+
+		response := models.CreateResponseAPIKey(&apiKey)
+		response.Key = key
+
+		utils.SuccessResponse(c, "API key created. Copy it now, it will not be shown again", response, http.StatusCreated)
+	}
+}
+
+func UpdateAPIKey(app *app.Application) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id, err := strconv.ParseInt(c.Param("keyId"), 10, 64)
+		if err != nil {
+			utils.ErrorResponse(c, "Invalid API key Id", http.StatusBadRequest)
+			return
+		}
+
+		var dto models.UpdateAPIKeyDto
+		if err := c.ShouldBindJSON(&dto); err != nil {
+			utils.ErrorResponse(c, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		contextUser := middlewares.GetUserFromContext(c)
+		if len(dto.Scopes) > 0 {
+			if err := validateAPIKeyScopes(contextUser, dto.Scopes); err != nil {
+				utils.ErrorResponse(c, err.Error(), http.StatusBadRequest)
+				return
+			}
+			dto.Scopes = slices.Compact(slices.Sorted(slices.Values(dto.Scopes)))
+		}
+
+		apiKey, err := app.Models.APIKeys.Update(id, contextUser.ID, &dto)
+		if err != nil {
+			utils.ErrorResponse(c, "Failed to update API key", http.StatusInternalServerError)
+			return
+		}
+		if apiKey == nil {
+			utils.ErrorResponse(c, "API key not found", http.StatusNotFound)
+			return
+		}
+
+		utils.SuccessResponse(c, "Successfully updated API key", models.CreateResponseAPIKey(apiKey))
+	}
+}
+
+func RevokeAPIKey(app *app.Application) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id, err := strconv.ParseInt(c.Param("keyId"), 10, 64)
+		if err != nil {
+			utils.ErrorResponse(c, "Invalid API key Id", http.StatusBadRequest)
+			return
+		}
+
+		contextUser := middlewares.GetUserFromContext(c)
+
+		revoked, err := app.Models.APIKeys.Revoke(id, contextUser.ID)
+		if err != nil {
+			utils.ErrorResponse(c, "Failed to revoke API key", http.StatusInternalServerError)
+			return
+		}
+		if !revoked {
+			utils.ErrorResponse(c, "API key not found", http.StatusNotFound)
+			return
+		}
+
+		utils.SuccessResponse(c, "Successfully revoked API key", nil)
+	}
+}
+
+// validateAPIKeyScopes only allows permissions the user's role already grants.
+func validateAPIKeyScopes(user *models.UserSerializer, scopes []string) error {
+	for _, scope := range scopes {
+		if !authz.IsPermission(scope) {
+			return fmt.Errorf("unknown scope %q", scope)
+		}
+		if !authz.HasPermission(user.Role, authz.Permission(scope)) {
+			return fmt.Errorf("your role does not grant the %q scope", scope)
+		}
+	}
+
+	return nil
+}
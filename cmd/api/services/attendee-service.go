@@ -1,13 +1,17 @@
 package services
 
 import (
+	"errors"
+	"fmt"
 	"log"
 	"net/http"
 	"strconv"
 
 	"github.com/gin-gonic/gin"
 	"github.com/vickon16/go-gin-rest-api/internal/app"
+	"github.com/vickon16/go-gin-rest-api/internal/authz"
 	"github.com/vickon16/go-gin-rest-api/internal/database/models"
+	"github.com/vickon16/go-gin-rest-api/internal/mailer"
 	"github.com/vickon16/go-gin-rest-api/internal/utils"
 )
 
@@ -29,40 +33,59 @@ func CreateAttendee(app *app.Application) gin.HandlerFunc {
 		newAttendee := models.Attendee{
 			UserID:  attendee.UserID,
 			EventID: attendee.EventID,
+			Status:  attendee.Status,
 			User:    user,
 			Event:   event,
 		}
 
-		if err := app.Models.Attendees.Insert(&newAttendee); err != nil {
-			log.Printf("Error inserting attendee: %v", err)
-			utils.ErrorResponse(c, "Failed to create attendee", http.StatusInternalServerError)
+		if !app.Authz.Authorize(c, authz.ActionCreateAttendee, authz.AttendeeResource(&newAttendee, event)) {
+			return
+		}
+		// Users cannot invite themselves
+		if attendee.Status == models.AttendeeStatusInvited && !app.Authz.Authorize(c, authz.ActionManageEventAttendees, authz.EventResource(event)) {
+			return
+		}
+
+		if err := app.Models.Attendees.Register(&newAttendee); err != nil {
+			registrationErrorResponse(c, err, "Failed to create attendee")
 			return
 		}
 
-		utils.SuccessResponse(c, "Attendee Created successfully", models.CreateResponseAttendee(&newAttendee), http.StatusCreated)
+		message := "Attendee Created successfully"
+		if newAttendee.Status == models.AttendeeStatusWaitlisted {
+			message = "Event is full, attendee added to the waitlist"
+		}
+
+		utils.SuccessResponse(c, message, models.CreateResponseAttendee(&newAttendee, attendeeViewerFromContext(app, c)), http.StatusCreated)
 	}
 }
 
 func GetAllAttendees(app *app.Application) gin.HandlerFunc {
 	return func(c *gin.Context) {
+		options, ok := parseListQuery(c, listQuerySpec{SortColumns: models.AttendeeSortColumns, DefaultSort: "createdAt"})
+		if !ok {
+			return
+		}
+		filters, ok := attendeeFiltersFromQuery(c)
+		if !ok {
+			return
+		}
 
-		allAttendees, err := app.Models.Attendees.GetAll()
+		allAttendees, total, err := app.Models.Attendees.GetAll(filters, options)
 		if err != nil {
 			log.Printf("Error getting attendees: %v", err)
 			utils.ErrorResponse(c, "Failed to get attendees", http.StatusInternalServerError)
 			return
 		}
-		if allAttendees == nil {
-			utils.ErrorResponse(c, "Attendees not found", http.StatusNotFound)
-			return
-		}
 
-		var serializedAttendees []models.AttendeeSerializer
+		viewer := attendeeViewerFromContext(app, c)
+
+		serializedAttendees := []models.AttendeeSerializer{}
 		for _, attendee := range allAttendees {
-			serializedAttendees = append(serializedAttendees, models.CreateResponseAttendee(attendee))
+			serializedAttendees = append(serializedAttendees, models.CreateResponseAttendee(attendee, viewer))
 		}
 
-		utils.SuccessResponse(c, "Successfully retrieved attendees", serializedAttendees)
+		utils.PaginatedResponse(c, "Successfully retrieved attendees", serializedAttendees, utils.NewPagination(options.Page, options.Limit, total))
 	}
 }
 
@@ -84,7 +107,7 @@ func GetAttendee(app *app.Application) gin.HandlerFunc {
 			return
 		}
 
-		utils.SuccessResponse(c, "Successfully retrieved attendee", models.CreateResponseAttendee(attendee))
+		utils.SuccessResponse(c, "Successfully retrieved attendee", models.CreateResponseAttendee(attendee, attendeeViewerFromContext(app, c)))
 	}
 }
 
@@ -96,27 +119,38 @@ func GetEventsByAttendee(app *app.Application) gin.HandlerFunc {
 			return
 		}
 
-		events, err := app.Models.Events.GetEventsByAttendeeId(attendeeId)
+		options, ok := parseListQuery(c, listQuerySpec{SortColumns: models.EventSortColumns, DefaultSort: "createdAt"})
+		if !ok {
+			return
+		}
+
+		events, total, err := app.Models.Events.GetEventsByAttendeeId(attendeeId, options)
 		if err != nil {
 			log.Printf("Error getting events for attendee: %v", err)
 			utils.ErrorResponse(c, "Failed to get events for attendee", http.StatusInternalServerError)
 			return
 		}
-		if events == nil {
-			utils.ErrorResponse(c, "No events found for attendee", http.StatusNotFound)
+
+		if err := loadAttendeeCounts(app, events...); err != nil {
+			log.Printf("Error counting attendees: %v", err)
+			utils.ErrorResponse(c, "Failed to get events for attendee", http.StatusInternalServerError)
 			return
 		}
 
-		var serializedEvents []models.EventSerializer
+		viewer := viewerFromContext(c)
+
+		serializedEvents := []models.EventSerializer{}
 		for _, event := range events {
-			serializedEvents = append(serializedEvents, models.CreateResponseEvent(event))
+			serializedEvents = append(serializedEvents, models.CreateResponseEvent(event, viewer))
 		}
 
-		utils.SuccessResponse(c, "Successfully retrieved events for attendee", serializedEvents)
+		utils.PaginatedResponse(c, "Successfully retrieved events for attendee", serializedEvents, utils.NewPagination(options.Page, options.Limit, total))
 	}
 }
 
-func UpdateAttendee(app *app.Application) gin.HandlerFunc {
+// UpdateAttendeeStatus moves the attendee to another RSVP status. Asking to go to a full
+// event puts the attendee on the waitlist, and a seat given up goes to the next one waiting.
+func UpdateAttendeeStatus(app *app.Application) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		id, err := strconv.ParseInt(c.Param("id"), 10, 64)
 		if err != nil {
@@ -124,7 +158,12 @@ func UpdateAttendee(app *app.Application) gin.HandlerFunc {
 			return
 		}
 
-		// Check for existing attendee
+		var dto models.UpdateAttendeeStatusDto
+		if err := c.ShouldBindJSON(&dto); err != nil {
+			utils.ErrorResponse(c, err.Error(), http.StatusBadRequest)
+			return
+		}
+
 		existingAttendee, err := app.Models.Attendees.Get(id)
 		if err != nil {
 			utils.ErrorResponse(c, "Failed to get attendee", http.StatusInternalServerError)
@@ -135,19 +174,69 @@ func UpdateAttendee(app *app.Application) gin.HandlerFunc {
 			return
 		}
 
-		var updatedAttendee models.UpdateAttendeeDto
-		if err := c.ShouldBindJSON(&updatedAttendee); err != nil {
-			utils.ErrorResponse(c, err.Error(), http.StatusBadRequest)
+		if !app.Authz.Authorize(c, authz.ActionUpdateAttendee, authz.AttendeeResource(existingAttendee, existingAttendee.Event)) {
+			return
+		}
+
+		attendee, promoted, err := app.Models.Attendees.SetStatus(id, dto.Status)
+		if err != nil {
+			// The status may have changed since the attendee was read, report the one that was refused
+			var transitionErr *models.StatusTransitionError
+			switch {
+			case errors.As(err, &transitionErr):
+				utils.ErrorResponse(c, "Cannot change status from "+transitionErr.From+" to "+transitionErr.To, http.StatusConflict)
+			case errors.Is(err, models.ErrAttendeeNotFound):
+				utils.ErrorResponse(c, "No attendee found", http.StatusNotFound)
+			default:
+				log.Printf("Error updating status of attendee %d: %v", id, err)
+				utils.ErrorResponse(c, "Failed to update attendee status", http.StatusInternalServerError)
+			}
+			return
+		}
+
+		notifyPromotedAttendees(app, existingAttendee.Event, promoted)
+
+		message := "Successfully updated attendee status"
+		if attendee.Status != dto.Status {
+			message = "Event is full, attendee added to the waitlist"
+		}
+
+		utils.SuccessResponse(c, message, models.CreateResponseAttendee(attendee, attendeeViewerFromContext(app, c)))
+	}
+}
+
+// GetAttendeeStatusChanges lists every status the attendee entered, oldest first.
+func GetAttendeeStatusChanges(app *app.Application) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+		if err != nil {
+			utils.ErrorResponse(c, "Invalid attendee Id", http.StatusBadRequest)
 			return
 		}
 
-		attendee, err := app.Models.Attendees.Update(id, &updatedAttendee)
+		attendee, err := app.Models.Attendees.Get(id)
 		if err != nil {
-			utils.ErrorResponse(c, "Failed to update attendee", http.StatusInternalServerError)
+			utils.ErrorResponse(c, "Failed to get attendee", http.StatusInternalServerError)
+			return
+		}
+		if attendee == nil {
+			utils.ErrorResponse(c, "No attendee found", http.StatusNotFound)
 			return
 		}
 
-		utils.SuccessResponse(c, "Successfully updated attendee", models.CreateResponseAttendee(attendee))
+		// The history is for those who can change the status
+		if !app.Authz.Authorize(c, authz.ActionUpdateAttendee, authz.AttendeeResource(attendee, attendee.Event)) {
+			return
+		}
+
+		changes, err := app.Models.Attendees.GetStatusChanges(id)
+		if err != nil {
+			log.Printf("Error getting status changes of attendee %d: %v", id, err)
+			utils.ErrorResponse(c, "Failed to get attendee status changes", http.StatusInternalServerError)
+			return
+		}
+
+		utils.SuccessResponse(c, "Successfully retrieved attendee status changes", changes)
 	}
 }
 
@@ -169,11 +258,56 @@ func DeleteAttendee(app *app.Application) gin.HandlerFunc {
 			return
 		}
 
-		if err := app.Models.Attendees.Delete(id); err != nil {
+		if !app.Authz.Authorize(c, authz.ActionDeleteAttendee, authz.AttendeeResource(attendee, attendee.Event)) {
+			return
+		}
+
+		promoted, err := app.Models.Attendees.Unregister(attendee.EventID, attendee.UserID)
+		if err != nil {
+			log.Printf("Error deleting attendee %d: %v", id, err)
 			utils.ErrorResponse(c, "Failed to delete attendee", http.StatusInternalServerError)
 			return
 		}
 
+		notifyPromotedAttendees(app, attendee.Event, promoted)
+
 		utils.SuccessResponse(c, "Successfully deleted attendee", nil)
 	}
 }
+
+// registrationErrorResponse writes the response for an error of AttendeesModel.Register.
+func registrationErrorResponse(c *gin.Context, err error, message string) {
+	switch {
+	case errors.Is(err, models.ErrAlreadyAttending):
+		utils.ErrorResponse(c, "Attendee already exist for this event", http.StatusConflict)
+	case errors.Is(err, models.ErrEventNotFound):
+		utils.ErrorResponse(c, "Event does not exist", http.StatusNotFound)
+	default:
+		log.Printf("Error registering attendee: %v", err)
+		utils.ErrorResponse(c, message, http.StatusInternalServerError)
+	}
+}
+
+// notifyPromotedAttendees tells the attendees who moved up from the waitlist that they have a seat.
+// Failures are only logged, the seat is theirs either way.
+func notifyPromotedAttendees(app *app.Application, event *models.Event, promoted []*models.Attendee) {
+	for _, attendee := range promoted {
+		user, err := app.Models.Users.Get(attendee.UserID)
+		if err != nil || user == nil {
+			log.Printf("Failed to get promoted attendee %d: %v", attendee.UserID, err)
+			continue
+		}
+
+		err = app.Mailer.Send(mailer.Message{
+			To:      user.Email,
+			Subject: "You have a seat at " + event.Name,
+			Body: fmt.Sprintf(
+				"Hi %s,\n\nA seat freed up at %s on %s and it is yours now. If you can no longer make it, cancel your registration so the next person on the waitlist gets it.\n",
+				user.Name, event.Name, event.Date.Format("January 2, 2006"),
+			),
+		})
+		if err != nil {
+			log.Printf("Failed to send waitlist promotion email: %v", err)
+		}
+	}
+}
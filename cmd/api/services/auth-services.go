@@ -1,12 +1,20 @@
 package services
 
 import (
+	"errors"
+	"fmt"
+	"io"
 	"log"
+	"math"
 	"net/http"
+	"strconv"
+	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/vickon16/go-gin-rest-api/cmd/api/middlewares"
 	"github.com/vickon16/go-gin-rest-api/internal/app"
 	"github.com/vickon16/go-gin-rest-api/internal/database/models"
+	"github.com/vickon16/go-gin-rest-api/internal/env"
 	"github.com/vickon16/go-gin-rest-api/internal/utils"
 )
 
@@ -30,6 +38,10 @@ func RegisterUser(app *app.Application) gin.HandlerFunc {
 			return
 		}
 
+		if !validateNewPassword(app, c, dto.Password, dto.Name, dto.Email) {
+			return
+		}
+
 		hashedPassword, err := utils.HashPassword(dto.Password)
 		if err != nil {
 			utils.ErrorResponse(c, "Something went wrong", http.StatusInternalServerError)
@@ -48,6 +60,10 @@ func RegisterUser(app *app.Application) gin.HandlerFunc {
 			return
 		}
 
+		if err := sendVerificationEmail(app, &user); err != nil {
+			log.Printf("Failed to send verification email: %v", err)
+		}
+
 		utils.SuccessResponse(c, "User Created successfully", models.CreateResponseUser(&user), http.StatusCreated)
 	}
 }
@@ -61,33 +77,297 @@ func LoginUser(app *app.Application) gin.HandlerFunc {
 			return
 		}
 
+		// No password can be set longer than the policy allows, so longer ones are refused before the costly hash
+		if app.Passwords.MaxLength > 0 && len(dto.Password) > app.Passwords.MaxLength {
+			utils.ErrorResponse(c, fmt.Sprintf("Password must be at most %d bytes long", app.Passwords.MaxLength), http.StatusBadRequest)
+			return
+		}
+
+		clientIP := c.ClientIP()
+
+		remaining, err := loginLockRemaining(app, dto.Email, clientIP)
+		if err != nil {
+			log.Printf("Failed to check login lock: %v", err)
+			utils.ErrorResponse(c, "Something went wrong", http.StatusInternalServerError)
+			return
+		}
+		if remaining > 0 {
+			c.Header("Retry-After", strconv.Itoa(int(math.Ceil(remaining.Seconds()))))
+			utils.ErrorResponse(c, "Too many login attempts, please try again later", http.StatusTooManyRequests)
+			return
+		}
+
 		// Check if user already exists
 		existingUser, err := app.Models.Users.GetUserByEmail(dto.Email, true)
 		if err != nil {
 			utils.ErrorResponse(c, "Failed to get user by email", http.StatusInternalServerError)
 			return
 		}
-		if existingUser == nil {
-			utils.ErrorResponse(c, "User does not exists", http.StatusBadRequest)
-			return
+
+		// Unknown emails and wrong passwords get the same response and take the same time
+		isValid := false
+		if existingUser != nil {
+			isValid = utils.CheckPasswordHash(existingUser.Password, dto.Password)
+		} else {
+			utils.SimulatePasswordCheck(dto.Password)
 		}
 
-		isValid := utils.CheckPasswordHash(existingUser.Password, dto.Password)
 		if !isValid {
+			if err := recordLoginFailure(app, dto.Email, clientIP); err != nil {
+				log.Printf("Failed to record login failure: %v", err)
+			}
 			utils.ErrorResponse(c, "Invalid email or password", http.StatusUnauthorized)
 			return
 		}
 
-		token, err := utils.GenerateLoginToken(existingUser.ID, existingUser.Email)
+		// Upgrade hashes made with an older algorithm or weaker parameters while we have the plain password
+		if utils.PasswordNeedsRehash(existingUser.Password) {
+			if err := rehashPassword(app, existingUser.ID, dto.Password); err != nil {
+				log.Printf("Failed to rehash password for user %d: %v", existingUser.ID, err)
+			}
+		}
+
+		// With two-factor enabled the password alone only earns a challenge
+		if existingUser.TOTPEnabledAt != nil {
+			challenge, err := createTwoFactorChallenge(app, existingUser.ID)
+			if err != nil {
+				log.Printf("Failed to create two-factor challenge: %v", err)
+				utils.ErrorResponse(c, "Something went wrong", http.StatusInternalServerError)
+				return
+			}
+
+			// The failures are only cleared once the second factor is verified too
+			utils.SuccessResponse(c, "Two-factor authentication required", challenge)
+			return
+		}
+
+		if err := resetLoginFailures(app, existingUser.Email); err != nil {
+			log.Printf("Failed to reset login failures: %v", err)
+		}
+
+		loginResponse, err := createLoginResponse(app, c, existingUser, "")
 		if err != nil {
+			log.Printf("Failed to create login response: %v", err)
 			utils.ErrorResponse(c, "Something went wrong", http.StatusInternalServerError)
 			return
 		}
 
-		loginResponse := models.LoginSerializer{
-			Token: token,
+		utils.SuccessResponse(c, "Login successful", loginResponse)
+	}
+}
+
+func RefreshToken(app *app.Application) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var dto models.RefreshTokenDto
+
+		if err := c.ShouldBindJSON(&dto); err != nil {
+			utils.ErrorResponse(c, err.Error(), http.StatusBadRequest)
+			return
 		}
 
-		utils.SuccessResponse(c, "Login successful", loginResponse)
+		existingToken, err := app.Models.RefreshTokens.GetByHash(utils.HashToken(dto.RefreshToken))
+		if err != nil {
+			utils.ErrorResponse(c, "Failed to get refresh token", http.StatusInternalServerError)
+			return
+		}
+		if existingToken == nil || existingToken.RevokedAt != nil {
+			utils.ErrorResponse(c, "Invalid refresh token", http.StatusUnauthorized)
+			return
+		}
+
+		// A token that was already rotated is being replayed, so the whole family is compromised
+		if existingToken.UsedAt != nil {
+			revokeRefreshTokenFamily(app, existingToken)
+			utils.ErrorResponse(c, "Invalid refresh token", http.StatusUnauthorized)
+			return
+		}
+
+		if existingToken.ExpiresAt.Before(time.Now()) {
+			utils.ErrorResponse(c, "Refresh token has expired", http.StatusUnauthorized)
+			return
+		}
+
+		marked, err := app.Models.RefreshTokens.MarkUsed(existingToken.ID)
+		if err != nil {
+			utils.ErrorResponse(c, "Failed to rotate refresh token", http.StatusInternalServerError)
+			return
+		}
+		if !marked {
+			// Another request rotated this token first
+			revokeRefreshTokenFamily(app, existingToken)
+			utils.ErrorResponse(c, "Invalid refresh token", http.StatusUnauthorized)
+			return
+		}
+
+		user, err := app.Models.Users.Get(existingToken.UserID)
+		if err != nil {
+			utils.ErrorResponse(c, "Failed to get user", http.StatusInternalServerError)
+			return
+		}
+		if user == nil {
+			utils.ErrorResponse(c, "Invalid refresh token", http.StatusUnauthorized)
+			return
+		}
+
+		loginResponse, err := createLoginResponse(app, c, user, existingToken.FamilyID)
+		if err != nil {
+			log.Printf("Failed to create login response: %v", err)
+			utils.ErrorResponse(c, "Something went wrong", http.StatusInternalServerError)
+			return
+		}
+
+		utils.SuccessResponse(c, "Token refreshed successfully", loginResponse)
+	}
+}
+
+func LogoutUser(app *app.Application) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var dto models.LogoutDto
+
+		// The refresh token is optional, so an empty body is fine
+		if err := c.ShouldBindJSON(&dto); err != nil && !errors.Is(err, io.EOF) {
+			utils.ErrorResponse(c, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		contextUser := middlewares.GetUserFromContext(c)
+		claims := middlewares.GetClaimsFromContext(c)
+
+		if err := app.Redis.RevokeToken(claims.ID, claims.ExpiresAt.Time); err != nil {
+			log.Printf("Failed to revoke access token: %v", err)
+			utils.ErrorResponse(c, "Failed to logout", http.StatusInternalServerError)
+			return
+		}
+
+		if claims.SessionID != 0 {
+			if _, err := revokeSession(app, contextUser.ID, claims.SessionID); err != nil {
+				log.Printf("Failed to revoke session: %v", err)
+				utils.ErrorResponse(c, "Failed to logout", http.StatusInternalServerError)
+				return
+			}
+		}
+
+		if dto.RefreshToken != "" {
+			refreshToken, err := app.Models.RefreshTokens.GetByHash(utils.HashToken(dto.RefreshToken))
+			if err != nil {
+				utils.ErrorResponse(c, "Failed to get refresh token", http.StatusInternalServerError)
+				return
+			}
+
+			if refreshToken != nil && refreshToken.UserID == contextUser.ID {
+				if err := app.Models.RefreshTokens.RevokeFamily(refreshToken.FamilyID); err != nil {
+					log.Printf("Failed to revoke refresh token family: %v", err)
+					utils.ErrorResponse(c, "Failed to logout", http.StatusInternalServerError)
+					return
+				}
+			}
+		}
+
+		utils.SuccessResponse(c, "Logout successful", nil)
+	}
+}
+
+func LogoutAllSessions(app *app.Application) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		contextUser := middlewares.GetUserFromContext(c)
+
+		if err := revokeAllSessions(app, contextUser.ID); err != nil {
+			log.Printf("Failed to revoke sessions: %v", err)
+			utils.ErrorResponse(c, "Failed to logout from all sessions", http.StatusInternalServerError)
+			return
+		}
+
+		utils.SuccessResponse(c, "Logged out from all sessions", nil)
+	}
+}
+
+// revokeAllSessions invalidates every session, access and refresh token issued to the user.
+func revokeAllSessions(app *app.Application, userId int64) error {
+	if err := app.Redis.IncrementTokenVersion(userId); err != nil {
+		return err
+	}
+
+	if err := app.Models.Sessions.RevokeAllForUser(userId); err != nil {
+		return err
+	}
+
+	return app.Models.RefreshTokens.RevokeAllForUser(userId)
+}
+
+// createLoginResponse issues an access token and a refresh token for the user.
+// An empty familyId starts a new refresh token family and a new session.
+func createLoginResponse(app *app.Application, c *gin.Context, user *models.User, familyId string) (*models.LoginSerializer, error) {
+	tokenVersion, err := app.Redis.GetTokenVersion(user.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	if familyId == "" {
+		familyId, err = utils.GenerateOpaqueToken(16)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	refreshExpirationDays := env.GetEnvInt("REFRESH_TOKEN_EXPIRATION_DAYS", 30)
+	expiresAt := time.Now().Add(time.Duration(refreshExpirationDays) * 24 * time.Hour)
+
+	session, err := startOrExtendSession(app, c, user.ID, familyId, expiresAt)
+	if err != nil {
+		return nil, err
+	}
+
+	token, err := utils.GenerateLoginToken(user.ID, user.Email, user.Role, tokenVersion, session.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	refreshToken, err := utils.GenerateOpaqueToken()
+	if err != nil {
+		return nil, err
+	}
+
+	err = app.Models.RefreshTokens.Insert(&models.RefreshToken{
+		UserID:    user.ID,
+		TokenHash: utils.HashToken(refreshToken),
+		FamilyID:  familyId,
+		ExpiresAt: expiresAt,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &models.LoginSerializer{
+		Token:        token,
+		RefreshToken: refreshToken,
+	}, nil
+}
+
+func rehashPassword(app *app.Application, userId int64, password string) error {
+	hashedPassword, err := utils.HashPassword(password)
+	if err != nil {
+		return err
+	}
+
+	return app.Models.Users.UpdatePassword(userId, hashedPassword)
+}
+
+func revokeRefreshTokenFamily(app *app.Application, token *models.RefreshToken) {
+	log.Printf("Refresh token reuse detected for user %d, revoking family %s", token.UserID, token.FamilyID)
+	if err := app.Models.RefreshTokens.RevokeFamily(token.FamilyID); err != nil {
+		log.Printf("Failed to revoke refresh token family: %v", err)
+	}
+	if err := app.Models.Sessions.RevokeFamily(token.FamilyID); err != nil {
+		log.Printf("Failed to revoke session: %v", err)
+	}
+
+	// Access tokens already issued for the session carry its sid, reject them too
+	session, err := app.Models.Sessions.GetByFamily(token.FamilyID)
+	if err != nil || session == nil {
+		log.Printf("Failed to get session of refresh token family %s: %v", token.FamilyID, err)
+		return
+	}
+	if err := app.Redis.RevokeSession(session.ID, accessTokenLifetime()); err != nil {
+		log.Printf("Failed to revoke session %d: %v", session.ID, err)
 	}
 }
@@ -0,0 +1,200 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"image"
+	"io"
+	"log"
+	"net/http"
+	"path"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/vickon16/go-gin-rest-api/cmd/api/middlewares"
+	"github.com/vickon16/go-gin-rest-api/internal/app"
+	"github.com/vickon16/go-gin-rest-api/internal/database/models"
+	"github.com/vickon16/go-gin-rest-api/internal/env"
+	"github.com/vickon16/go-gin-rest-api/internal/images"
+	"github.com/vickon16/go-gin-rest-api/internal/utils"
+)
+
+// Larger images are rejected before they are decoded
+const avatarMaxDimension = 4096
+
+// Edge lengths of the square thumbnails generated for every avatar
+var avatarThumbnailSizes = []int{256, 64}
+
+// UploadAvatar replaces the user's avatar with the image in the "avatar" multipart field.
+func UploadAvatar(app *app.Application) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		contextUser := middlewares.GetUserFromContext(c)
+		maxSize := int64(env.GetEnvInt("AVATAR_MAX_SIZE_MB", 5)) << 20
+
+		// Leave room for the multipart boundaries and headers
+		c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxSize+(1<<20))
+
+		fileHeader, err := c.FormFile("avatar")
+		if err != nil {
+			var tooLarge *http.MaxBytesError
+			if errors.As(err, &tooLarge) {
+				utils.ErrorResponse(c, fmt.Sprintf("Avatar must be at most %d MB", maxSize>>20), http.StatusRequestEntityTooLarge)
+				return
+			}
+			utils.ErrorResponse(c, "An image is required in the avatar field", http.StatusBadRequest)
+			return
+		}
+		if fileHeader.Size > maxSize {
+			utils.ErrorResponse(c, fmt.Sprintf("Avatar must be at most %d MB", maxSize>>20), http.StatusRequestEntityTooLarge)
+			return
+		}
+
+		file, err := fileHeader.Open()
+		if err != nil {
+			utils.ErrorResponse(c, "Failed to read avatar", http.StatusBadRequest)
+			return
+		}
+		data, err := io.ReadAll(io.LimitReader(file, maxSize))
+		file.Close()
+		if err != nil {
+			utils.ErrorResponse(c, "Failed to read avatar", http.StatusBadRequest)
+			return
+		}
+
+		// The content is checked rather than the declared content type
+		img, format, err := images.Decode(data, avatarMaxDimension)
+		if err != nil {
+			status := http.StatusBadRequest
+			if errors.Is(err, images.ErrUnsupportedFormat) {
+				status = http.StatusUnsupportedMediaType
+			}
+			utils.ErrorResponse(c, err.Error(), status)
+			return
+		}
+
+		avatar, err := storeAvatar(c.Request.Context(), app, contextUser.ID, img, format)
+		if err != nil {
+			log.Printf("Failed to store avatar: %v", err)
+			utils.ErrorResponse(c, "Failed to store avatar", http.StatusInternalServerError)
+			return
+		}
+
+		previous, err := app.Models.Users.SetAvatar(contextUser.ID, avatar)
+		if err != nil {
+			deleteAvatarFiles(app, avatar)
+			utils.ErrorResponse(c, "Failed to update avatar", http.StatusInternalServerError)
+			return
+		}
+
+		app.Redis.Delete(utils.ConstructRedisUserKey(contextUser.ID))
+
+		// Only removed once nothing points to them anymore. This is the avatar the swap
+		// replaced, which may be a concurrent upload's rather than the one read earlier.
+		deleteAvatarFiles(app, previous)
+
+		utils.SuccessResponse(c, "Successfully updated avatar", avatar)
+	}
+}
+
+// DeleteAvatar removes the user's avatar and its thumbnails.
+func DeleteAvatar(app *app.Application) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		contextUser := middlewares.GetUserFromContext(c)
+
+		existing, err := app.Models.Users.Get(contextUser.ID)
+		if err != nil || existing == nil {
+			utils.ErrorResponse(c, "Failed to get user", http.StatusInternalServerError)
+			return
+		}
+		if existing.Avatar == nil {
+			utils.ErrorResponse(c, "No avatar to delete", http.StatusNotFound)
+			return
+		}
+
+		previous, err := app.Models.Users.SetAvatar(contextUser.ID, nil)
+		if err != nil {
+			utils.ErrorResponse(c, "Failed to delete avatar", http.StatusInternalServerError)
+			return
+		}
+
+		app.Redis.Delete(utils.ConstructRedisUserKey(contextUser.ID))
+		deleteAvatarFiles(app, previous)
+
+		utils.SuccessResponse(c, "Successfully deleted avatar", nil)
+	}
+}
+
+// storeAvatar uploads the image and its thumbnails under a new random prefix,
+// so cached copies of a previous avatar are never served for the new one.
+// The original is re-encoded from the decoded pixels too, which drops metadata
+// such as the EXIF location of a photo.
+func storeAvatar(ctx context.Context, app *app.Application, userId int64, img image.Image, format string) (*models.Avatar, error) {
+	id, err := utils.GenerateOpaqueToken(12)
+	if err != nil {
+		return nil, err
+	}
+	prefix := path.Join("avatars", strconv.FormatInt(userId, 10), id)
+
+	avatar := &models.Avatar{Thumbnails: map[string]string{}}
+
+	put := func(name string, data []byte, format string) (string, error) {
+		key := prefix + "/" + name + "." + extensionForFormat(format)
+		if err := app.Storage.Put(ctx, key, data, images.ContentType(format)); err != nil {
+			return "", err
+		}
+		avatar.Keys = append(avatar.Keys, key)
+		return app.Storage.URL(key), nil
+	}
+
+	original, originalFormat, err := images.Encode(img, format)
+	if err != nil {
+		return nil, err
+	}
+	if avatar.URL, err = put("original", original, originalFormat); err != nil {
+		deleteAvatarFiles(app, avatar)
+		return nil, err
+	}
+
+	for _, size := range avatarThumbnailSizes {
+		encoded, encodedFormat, err := images.Encode(images.Thumbnail(img, size), format)
+		if err != nil {
+			deleteAvatarFiles(app, avatar)
+			return nil, err
+		}
+
+		url, err := put(strconv.Itoa(size), encoded, encodedFormat)
+		if err != nil {
+			deleteAvatarFiles(app, avatar)
+			return nil, err
+		}
+		avatar.Thumbnails[strconv.Itoa(size)] = url
+	}
+
+	return avatar, nil
+}
+
+// deleteAvatarFiles removes the stored files of an avatar. Failures are only logged,
+// an orphaned file is not worth failing the request for.
+func deleteAvatarFiles(app *app.Application, avatar *models.Avatar) {
+	if avatar == nil {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	for _, key := range avatar.Keys {
+		if err := app.Storage.Delete(ctx, key); err != nil {
+			log.Printf("Failed to delete avatar file %s: %v", key, err)
+		}
+	}
+}
+
+func extensionForFormat(format string) string {
+	if format == "jpeg" {
+		return "jpg"
+	}
+	return format
+}
@@ -4,9 +4,13 @@ import (
 	"errors"
 	"log"
 	"sync"
+	"time"
 
+	"github.com/gin-gonic/gin"
+	"github.com/vickon16/go-gin-rest-api/cmd/api/middlewares"
 	"github.com/vickon16/go-gin-rest-api/internal/app"
 	"github.com/vickon16/go-gin-rest-api/internal/database/models"
+	"github.com/vickon16/go-gin-rest-api/internal/utils"
 )
 
 func FindEventAndUser(app *app.Application, eventId, userId int64) (*models.Event, *models.User, error) {
@@ -55,3 +59,49 @@ func FindEventAndUser(app *app.Application, eventId, userId int64) (*models.Even
 
 	return existingEvent, existingUser, nil
 }
+
+// IssueOneTimeToken replaces any pending token of the same purpose with a new one.
+// Only the hash is stored, the plain token is returned to be sent to the user.
+func IssueOneTimeToken(app *app.Application, userId int64, purpose string, ttl time.Duration) (string, error) {
+	if err := app.Models.OneTimeTokens.InvalidateForUser(userId, purpose); err != nil {
+		return "", err
+	}
+
+	token, err := utils.GenerateOpaqueToken()
+	if err != nil {
+		return "", err
+	}
+
+	err = app.Models.OneTimeTokens.Insert(&models.OneTimeToken{
+		UserID:    userId,
+		Purpose:   purpose,
+		TokenHash: utils.HashToken(token),
+		ExpiresAt: time.Now().Add(ttl),
+	})
+	if err != nil {
+		return "", err
+	}
+
+	return token, nil
+}
+
+// viewerFromContext describes the authenticated user to the serializers.
+func viewerFromContext(c *gin.Context) *models.Viewer {
+	contextUser := middlewares.GetUserFromContext(c)
+	return models.NewViewer(contextUser.ID, contextUser.Role)
+}
+
+// attendeeViewerFromContext also loads the events the user organizes, whose attendees'
+// emails they may see. When that fails the emails are left out rather than failing the request.
+func attendeeViewerFromContext(app *app.Application, c *gin.Context) *models.Viewer {
+	viewer := viewerFromContext(c)
+
+	organizedEventIDs, err := app.Models.Events.GetOrganizedEventIDs(viewer.UserID)
+	if err != nil {
+		log.Printf("Error getting events organized by user %d: %v", viewer.UserID, err)
+		return viewer
+	}
+
+	viewer.OrganizedEventIDs = organizedEventIDs
+	return viewer
+}
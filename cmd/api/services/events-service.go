@@ -8,6 +8,7 @@ import (
 	"github.com/gin-gonic/gin"
 	"github.com/vickon16/go-gin-rest-api/cmd/api/middlewares"
 	"github.com/vickon16/go-gin-rest-api/internal/app"
+	"github.com/vickon16/go-gin-rest-api/internal/authz"
 	"github.com/vickon16/go-gin-rest-api/internal/database/models"
 	"github.com/vickon16/go-gin-rest-api/internal/utils"
 )
@@ -39,7 +40,10 @@ func CreateEvent(app *app.Application) gin.HandlerFunc {
 			return
 		}
 
-		utils.SuccessResponse(c, "Event Created successfully", models.CreateResponseEvent(newEvent), http.StatusCreated)
+		indexEvent(app, newEvent)
+		newEvent.AttendeeCounts = models.NewAttendeeCounts()
+
+		utils.SuccessResponse(c, "Event Created successfully", models.CreateResponseEvent(newEvent, viewerFromContext(c)), http.StatusCreated)
 	}
 }
 
@@ -53,24 +57,43 @@ func CreateEvent(app *app.Application) gin.HandlerFunc {
 // @Router /api/v1/events [get]
 func GetAllEvent(app *app.Application) gin.HandlerFunc {
 	return func(c *gin.Context) {
+		filters, ok := eventFiltersFromQuery(c)
+		if !ok {
+			return
+		}
+		options, ok := parseListQuery(c, listQuerySpec{SortColumns: models.EventKeysetColumns, DefaultSort: "date", AllowCursor: true, Filters: filters})
+		if !ok {
+			return
+		}
 
-		allEvents, err := app.Models.Events.GetAll()
+		allEvents, page, err := app.Models.Events.GetAll(filters, options)
 		if err != nil {
 			log.Printf("Error getting events: %v", err)
 			utils.ErrorResponse(c, "Failed to get events", http.StatusInternalServerError)
 			return
 		}
-		if allEvents == nil {
-			utils.ErrorResponse(c, "Events not found", http.StatusNotFound)
+
+		pagination, err := keysetPagination(c, options, filters, page)
+		if err != nil {
+			log.Printf("Error signing event cursors: %v", err)
+			utils.ErrorResponse(c, "Failed to get events", http.StatusInternalServerError)
 			return
 		}
 
-		var serializedEvents []models.EventSerializer
+		if err := loadAttendeeCounts(app, allEvents...); err != nil {
+			log.Printf("Error counting attendees: %v", err)
+			utils.ErrorResponse(c, "Failed to get events", http.StatusInternalServerError)
+			return
+		}
+
+		viewer := viewerFromContext(c)
+
+		serializedEvents := []models.EventSerializer{}
 		for _, event := range allEvents {
-			serializedEvents = append(serializedEvents, models.CreateResponseEvent(event))
+			serializedEvents = append(serializedEvents, models.CreateResponseEvent(event, viewer))
 		}
 
-		utils.SuccessResponse(c, "Successfully retrieved events", serializedEvents)
+		utils.PaginatedResponse(c, "Successfully retrieved events", serializedEvents, pagination)
 	}
 }
 
@@ -92,7 +115,13 @@ func GetEvent(app *app.Application) gin.HandlerFunc {
 			return
 		}
 
-		utils.SuccessResponse(c, "Successfully retrieved event", models.CreateResponseEvent(event))
+		if err := loadAttendeeCounts(app, event); err != nil {
+			log.Printf("Error counting attendees of event %d: %v", event.ID, err)
+			utils.ErrorResponse(c, "Failed to get event", http.StatusInternalServerError)
+			return
+		}
+
+		utils.SuccessResponse(c, "Successfully retrieved event", models.CreateResponseEvent(event, viewerFromContext(c)))
 	}
 }
 
@@ -115,36 +144,27 @@ func AddAttendeeToEvent(app *app.Application) gin.HandlerFunc {
 			return
 		}
 
-		contextUser := middlewares.GetUserFromContext(c)
-		if event.UserID != contextUser.ID {
-			utils.ErrorResponse(c, "You are not authorized to add an attendee to this event", http.StatusForbidden)
-			return
-		}
-
-		// Check if the user is not already an attendee
-		existingAttendee, err := app.Models.Attendees.GetByEventAndAttendee(event.ID, user.ID)
-		if err != nil {
-			utils.ErrorResponse(c, "Failed to get events by attendee", http.StatusConflict)
-			return
-		}
-		if existingAttendee != nil {
-			utils.ErrorResponse(c, "Attendee already exist for this event", http.StatusConflict)
+		if !app.Authz.Authorize(c, authz.ActionManageEventAttendees, authz.EventResource(event)) {
 			return
 		}
 
 		attendee := models.Attendee{
 			EventID: event.ID,
-			UserID:  event.UserID,
+			UserID:  user.ID,
 		}
 
-		err = app.Models.Attendees.Insert(&attendee)
-		if err != nil {
-			log.Printf("Error adding attendee: %v", err)
-			utils.ErrorResponse(c, "Failed to add attendee to event", http.StatusConflict)
+		// Checks for an existing registration and a free seat in the same transaction
+		if err := app.Models.Attendees.Register(&attendee); err != nil {
+			registrationErrorResponse(c, err, "Failed to add attendee to event")
 			return
 		}
 
-		utils.SuccessResponse(c, "Successfully added attendee to event", models.CreateResponseAttendee(&attendee), http.StatusCreated)
+		message := "Successfully added attendee to event"
+		if attendee.Status == models.AttendeeStatusWaitlisted {
+			message = "Event is full, attendee added to the waitlist"
+		}
+
+		utils.SuccessResponse(c, message, models.CreateResponseAttendee(&attendee, attendeeViewerFromContext(app, c)), http.StatusCreated)
 	}
 }
 
@@ -156,19 +176,39 @@ func GetAttendeesForEvent(app *app.Application) gin.HandlerFunc {
 			return
 		}
 
-		attendees, err := app.Models.Attendees.GetAttendeesByEventId(eventId)
+		statuses, ok := queryAttendeeStatuses(c)
+		if !ok {
+			return
+		}
+		filters := &models.AttendeeFilters{Statuses: statuses}
+
+		options, ok := parseListQuery(c, listQuerySpec{SortColumns: models.AttendeeKeysetColumns, DefaultSort: "createdAt", AllowCursor: true, Filters: filters})
+		if !ok {
+			return
+		}
+
+		attendees, page, err := app.Models.Attendees.GetAttendeesByEventId(eventId, filters, options)
 		if err != nil {
 			log.Printf("Error getting attendees for event: %v", err)
 			utils.ErrorResponse(c, "Failed to get attendees for event", http.StatusInternalServerError)
 			return
 		}
 
-		var serializedAttendees []models.AttendeeSerializer
+		pagination, err := keysetPagination(c, options, filters, page)
+		if err != nil {
+			log.Printf("Error signing attendee cursors: %v", err)
+			utils.ErrorResponse(c, "Failed to get attendees for event", http.StatusInternalServerError)
+			return
+		}
+
+		viewer := attendeeViewerFromContext(app, c)
+
+		serializedAttendees := []models.AttendeeSerializer{}
 		for _, attendee := range attendees {
-			serializedAttendees = append(serializedAttendees, models.CreateResponseAttendee(attendee))
+			serializedAttendees = append(serializedAttendees, models.CreateResponseAttendee(attendee, viewer))
 		}
 
-		utils.SuccessResponse(c, "Successfully retrieved attendees for event", serializedAttendees)
+		utils.PaginatedResponse(c, "Successfully retrieved attendees for event", serializedAttendees, pagination)
 	}
 }
 
@@ -191,10 +231,7 @@ func UpdateEvent(app *app.Application) gin.HandlerFunc {
 			return
 		}
 
-		contextUser := middlewares.GetUserFromContext(c)
-
-		if existingEvent.UserID != contextUser.ID {
-			utils.ErrorResponse(c, "You are not authorized to update this event", http.StatusForbidden)
+		if !app.Authz.Authorize(c, authz.ActionUpdateEvent, authz.EventResource(existingEvent)) {
 			return
 		}
 
@@ -210,7 +247,23 @@ func UpdateEvent(app *app.Application) gin.HandlerFunc {
 			return
 		}
 
-		utils.SuccessResponse(c, "Successfully updated event", models.CreateResponseEvent(event))
+		indexEvent(app, event)
+
+		// A raised or lifted limit frees seats for the waitlist
+		if updatedEvent.Capacity != nil {
+			promoted, err := app.Models.Attendees.PromoteWaitlisted(event.ID)
+			if err != nil {
+				log.Printf("Error promoting waitlist of event %d: %v", event.ID, err)
+			}
+			notifyPromotedAttendees(app, event, promoted)
+		}
+
+		// The event is saved, counts are left out rather than failing the request
+		if err := loadAttendeeCounts(app, event); err != nil {
+			log.Printf("Error counting attendees of event %d: %v", event.ID, err)
+		}
+
+		utils.SuccessResponse(c, "Successfully updated event", models.CreateResponseEvent(event, viewerFromContext(c)))
 	}
 }
 
@@ -232,9 +285,7 @@ func DeleteEvent(app *app.Application) gin.HandlerFunc {
 			return
 		}
 
-		contextUser := middlewares.GetUserFromContext(c)
-		if event.UserID != contextUser.ID {
-			utils.ErrorResponse(c, "You are not authorized to delete this event", http.StatusForbidden)
+		if !app.Authz.Authorize(c, authz.ActionDeleteEvent, authz.EventResource(event)) {
 			return
 		}
 
@@ -243,6 +294,8 @@ func DeleteEvent(app *app.Application) gin.HandlerFunc {
 			return
 		}
 
+		removeEventFromIndex(app, id)
+
 		utils.SuccessResponse(c, "Successfully deleted event", nil)
 	}
 }
@@ -267,17 +320,110 @@ func DeleteAttendeeFromEvent(app *app.Application) gin.HandlerFunc {
 			return
 		}
 
-		contextUser := middlewares.GetUserFromContext(c)
-		if event.UserID != contextUser.ID {
-			utils.ErrorResponse(c, "You are not authorized to delete an attendee from this event", http.StatusForbidden)
+		if !app.Authz.Authorize(c, authz.ActionManageEventAttendees, authz.EventResource(event)) {
 			return
 		}
 
-		if err := app.Models.Attendees.DeleteByIdAndEventId(event.ID, user.ID); err != nil {
+		promoted, err := app.Models.Attendees.Unregister(event.ID, user.ID)
+		if err != nil {
+			log.Printf("Error deleting attendee from event %d: %v", event.ID, err)
 			utils.ErrorResponse(c, "Failed to delete attendee from event", http.StatusInternalServerError)
 			return
 		}
 
+		notifyPromotedAttendees(app, event, promoted)
+
 		utils.SuccessResponse(c, "Successfully deleted attendee from event", nil)
 	}
 }
+
+func AddCoOrganizerToEvent(app *app.Application) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		eventId, err := strconv.ParseInt(c.Param("id"), 10, 64)
+		if err != nil {
+			utils.ErrorResponse(c, "Invalid event Id", http.StatusBadRequest)
+			return
+		}
+
+		userId, err := strconv.ParseInt(c.Param("userId"), 10, 64)
+		if err != nil {
+			utils.ErrorResponse(c, "Invalid user Id", http.StatusBadRequest)
+			return
+		}
+
+		event, user, err := FindEventAndUser(app, eventId, userId)
+		if err != nil {
+			utils.ErrorResponse(c, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		if !app.Authz.Authorize(c, authz.ActionManageEventOrganizers, authz.EventResource(event)) {
+			return
+		}
+
+		if user.ID == event.UserID {
+			utils.ErrorResponse(c, "User already owns this event", http.StatusConflict)
+			return
+		}
+
+		if err := app.Models.Events.AddCoOrganizer(event.ID, user.ID); err != nil {
+			log.Printf("Error adding co-organizer: %v", err)
+			utils.ErrorResponse(c, "Failed to add co-organizer to event", http.StatusInternalServerError)
+			return
+		}
+
+		utils.SuccessResponse(c, "Successfully added co-organizer to event", nil, http.StatusCreated)
+	}
+}
+
+func DeleteCoOrganizerFromEvent(app *app.Application) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		eventId, err := strconv.ParseInt(c.Param("id"), 10, 64)
+		if err != nil {
+			utils.ErrorResponse(c, "Invalid event Id", http.StatusBadRequest)
+			return
+		}
+
+		userId, err := strconv.ParseInt(c.Param("userId"), 10, 64)
+		if err != nil {
+			utils.ErrorResponse(c, "Invalid user Id", http.StatusBadRequest)
+			return
+		}
+
+		event, user, err := FindEventAndUser(app, eventId, userId)
+		if err != nil {
+			utils.ErrorResponse(c, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		if !app.Authz.Authorize(c, authz.ActionManageEventOrganizers, authz.EventResource(event)) {
+			return
+		}
+
+		if err := app.Models.Events.RemoveCoOrganizer(event.ID, user.ID); err != nil {
+			utils.ErrorResponse(c, "Failed to delete co-organizer from event", http.StatusInternalServerError)
+			return
+		}
+
+		utils.SuccessResponse(c, "Successfully deleted co-organizer from event", nil)
+	}
+}
+
+// loadAttendeeCounts sets the number of attendees per status on the events.
+func loadAttendeeCounts(app *app.Application, events ...*models.Event) error {
+	ids := make([]int64, len(events))
+	for i, event := range events {
+		ids[i] = event.ID
+	}
+
+	counts, err := app.Models.Attendees.CountByStatus(ids)
+	if err != nil {
+		return err
+	}
+
+	for _, event := range events {
+		event.AttendeeCounts = counts[event.ID]
+	}
+
+	return nil
+}
@@ -0,0 +1,191 @@
+package services
+
+import (
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/vickon16/go-gin-rest-api/cmd/api/middlewares"
+	"github.com/vickon16/go-gin-rest-api/internal/app"
+	"github.com/vickon16/go-gin-rest-api/internal/database/models"
+	"github.com/vickon16/go-gin-rest-api/internal/utils"
+)
+
+// FollowUser makes the authenticated user follow an organizer.
+func FollowUser(app *app.Application) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		followee, ok := getFollowee(app, c)
+		if !ok {
+			return
+		}
+
+		contextUser := middlewares.GetUserFromContext(c)
+		if followee.ID == contextUser.ID {
+			utils.ErrorResponse(c, "You cannot follow yourself", http.StatusBadRequest)
+			return
+		}
+		if followee.Role != models.RoleOrganizer && followee.Role != models.RoleAdmin {
+			utils.ErrorResponse(c, "Only organizers can be followed", http.StatusBadRequest)
+			return
+		}
+
+		created, err := app.Models.Follows.Insert(contextUser.ID, followee.ID)
+		if err != nil {
+			log.Printf("Error following user %d: %v", followee.ID, err)
+			utils.ErrorResponse(c, "Failed to follow user", http.StatusInternalServerError)
+			return
+		}
+
+		if !created {
+			utils.SuccessResponse(c, "Already following user", nil)
+			return
+		}
+
+		utils.SuccessResponse(c, "Successfully followed user", nil, http.StatusCreated)
+	}
+}
+
+// UnfollowUser stops following an organizer.
+func UnfollowUser(app *app.Application) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		followeeId, err := strconv.ParseInt(c.Param("id"), 10, 64)
+		if err != nil {
+			utils.ErrorResponse(c, "Invalid user Id", http.StatusBadRequest)
+			return
+		}
+
+		contextUser := middlewares.GetUserFromContext(c)
+
+		deleted, err := app.Models.Follows.Delete(contextUser.ID, followeeId)
+		if err != nil {
+			log.Printf("Error unfollowing user %d: %v", followeeId, err)
+			utils.ErrorResponse(c, "Failed to unfollow user", http.StatusInternalServerError)
+			return
+		}
+		if !deleted {
+			utils.ErrorResponse(c, "You are not following this user", http.StatusNotFound)
+			return
+		}
+
+		utils.SuccessResponse(c, "Successfully unfollowed user", nil)
+	}
+}
+
+func GetFollowers(app *app.Application) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		user, ok := getFollowee(app, c)
+		if !ok {
+			return
+		}
+
+		options, ok := parseListQuery(c, listQuerySpec{SortColumns: models.UserSortColumns, DefaultSort: "name"})
+		if !ok {
+			return
+		}
+
+		followers, total, err := app.Models.Follows.GetFollowers(user.ID, options)
+		if err != nil {
+			log.Printf("Error getting followers of user %d: %v", user.ID, err)
+			utils.ErrorResponse(c, "Failed to get followers", http.StatusInternalServerError)
+			return
+		}
+
+		utils.PaginatedResponse(c, "Successfully retrieved followers", serializeUsersFor(followers, viewerFromContext(c)), utils.NewPagination(options.Page, options.Limit, total))
+	}
+}
+
+func GetFollowing(app *app.Application) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		user, ok := getFollowee(app, c)
+		if !ok {
+			return
+		}
+
+		options, ok := parseListQuery(c, listQuerySpec{SortColumns: models.UserSortColumns, DefaultSort: "name"})
+		if !ok {
+			return
+		}
+
+		following, total, err := app.Models.Follows.GetFollowing(user.ID, options)
+		if err != nil {
+			log.Printf("Error getting users followed by user %d: %v", user.ID, err)
+			utils.ErrorResponse(c, "Failed to get followed users", http.StatusInternalServerError)
+			return
+		}
+
+		utils.PaginatedResponse(c, "Successfully retrieved followed users", serializeUsersFor(following, viewerFromContext(c)), utils.NewPagination(options.Page, options.Limit, total))
+	}
+}
+
+// GetMyFeed lists the upcoming events of the organizers the user follows.
+// Pass the returned nextCursor or prevCursor as ?cursor= to move between pages.
+func GetMyFeed(app *app.Application) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		options, ok := parseListQuery(c, listQuerySpec{AllowCursor: true})
+		if !ok {
+			return
+		}
+
+		contextUser := middlewares.GetUserFromContext(c)
+
+		events, page, err := app.Models.Events.GetFeed(contextUser.ID, options)
+		if err != nil {
+			log.Printf("Error getting feed for user %d: %v", contextUser.ID, err)
+			utils.ErrorResponse(c, "Failed to get feed", http.StatusInternalServerError)
+			return
+		}
+
+		pagination, err := keysetPagination(c, options, nil, page)
+		if err != nil {
+			log.Printf("Error signing feed cursors: %v", err)
+			utils.ErrorResponse(c, "Failed to get feed", http.StatusInternalServerError)
+			return
+		}
+
+		if err := loadAttendeeCounts(app, events...); err != nil {
+			log.Printf("Error counting attendees: %v", err)
+			utils.ErrorResponse(c, "Failed to get feed", http.StatusInternalServerError)
+			return
+		}
+
+		viewer := viewerFromContext(c)
+
+		serializedEvents := []models.EventSerializer{}
+		for _, event := range events {
+			serializedEvents = append(serializedEvents, models.CreateResponseEvent(event, viewer))
+		}
+
+		utils.PaginatedResponse(c, "Successfully retrieved feed", serializedEvents, pagination)
+	}
+}
+
+// getFollowee loads the user named by the :id param, writing the error response when there is none.
+func getFollowee(app *app.Application, c *gin.Context) (*models.User, bool) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		utils.ErrorResponse(c, "Invalid user Id", http.StatusBadRequest)
+		return nil, false
+	}
+
+	user, err := app.Models.Users.Get(id)
+	if err != nil {
+		utils.ErrorResponse(c, "Failed to get user", http.StatusInternalServerError)
+		return nil, false
+	}
+	if user == nil || user.DeletedAt != nil {
+		utils.ErrorResponse(c, "User not found", http.StatusNotFound)
+		return nil, false
+	}
+
+	return user, true
+}
+
+func serializeUsersFor(users []*models.User, viewer *models.Viewer) []models.UserSerializer {
+	serialized := []models.UserSerializer{}
+	for _, user := range users {
+		serialized = append(serialized, models.CreateResponseUserFor(user, viewer))
+	}
+
+	return serialized
+}
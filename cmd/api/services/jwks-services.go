@@ -0,0 +1,25 @@
+package services
+
+import (
+	"log"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/vickon16/go-gin-rest-api/internal/utils"
+)
+
+// GetJWKS publishes the public keys other services use to verify our tokens.
+// It is served as a plain JWK Set, not wrapped in ApiResponse.
+func GetJWKS() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		jwks, err := utils.GetJWKS()
+		if err != nil {
+			log.Printf("Failed to load JWKS: %v", err)
+			utils.ErrorResponse(c, "Something went wrong", http.StatusInternalServerError)
+			return
+		}
+
+		c.Header("Cache-Control", "public, max-age=300")
+		c.JSON(http.StatusOK, jwks)
+	}
+}
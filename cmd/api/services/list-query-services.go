@@ -0,0 +1,245 @@
+package services
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/vickon16/go-gin-rest-api/internal/database/models"
+	"github.com/vickon16/go-gin-rest-api/internal/utils"
+)
+
+// listQuerySpec declares what a list endpoint accepts besides its filters.
+type listQuerySpec struct {
+	// Fields accepted by ?sort=, none means the order is fixed
+	SortColumns map[string]string
+	DefaultSort string
+	// Whether the list is keyset paginated with ?cursor=, such lists do not take ?page=
+	AllowCursor bool
+	// Filters of a keyset paginated list, its cursors are only accepted with the same ones
+	Filters any
+}
+
+// parseListQuery reads ?page=, ?limit=, ?cursor= and ?sort= from the query string.
+// A leading "-" sorts descending, e.g. ?sort=-date. On invalid values it writes
+// the 400 response and returns false.
+func parseListQuery(c *gin.Context, spec listQuerySpec) (*models.ListOptions, bool) {
+	options := models.NewListOptions()
+	options.Sort = spec.DefaultSort
+
+	if value := c.Query("limit"); value != "" {
+		limit, err := strconv.Atoi(value)
+		if err != nil || limit < 1 || limit > models.MaxListLimit {
+			utils.ErrorResponse(c, "limit must be between 1 and "+strconv.Itoa(models.MaxListLimit), http.StatusBadRequest)
+			return nil, false
+		}
+		options.Limit = limit
+	}
+
+	if value := c.Query("cursor"); value != "" {
+		if !spec.AllowCursor {
+			utils.ErrorResponse(c, "cursor is not supported for this list, use page", http.StatusBadRequest)
+			return nil, false
+		}
+	}
+
+	if value := c.Query("page"); value != "" {
+		if spec.AllowCursor {
+			utils.ErrorResponse(c, "page is not supported for this list, use cursor", http.StatusBadRequest)
+			return nil, false
+		}
+
+		page, err := strconv.Atoi(value)
+		if err != nil || page < 1 {
+			utils.ErrorResponse(c, "page must be a positive number", http.StatusBadRequest)
+			return nil, false
+		}
+		// Checked by division so a huge page cannot overflow the offset
+		if page-1 > models.MaxListOffset/options.Limit {
+			utils.ErrorResponse(c, fmt.Sprintf("page must be at most %d with a limit of %d, narrow the filters to reach older items", models.MaxListOffset/options.Limit+1, options.Limit), http.StatusBadRequest)
+			return nil, false
+		}
+		options.Page = page
+	}
+
+	if value := c.Query("sort"); value != "" {
+		field := strings.TrimPrefix(value, "-")
+		if _, ok := spec.SortColumns[field]; !ok {
+			if len(spec.SortColumns) == 0 {
+				utils.ErrorResponse(c, "sort is not supported for this list", http.StatusBadRequest)
+				return nil, false
+			}
+
+			fields := make([]string, 0, len(spec.SortColumns))
+			for name := range spec.SortColumns {
+				fields = append(fields, name)
+			}
+			slices.Sort(fields)
+
+			utils.ErrorResponse(c, "sort must be one of: "+strings.Join(fields, ", "), http.StatusBadRequest)
+			return nil, false
+		}
+
+		options.Sort = field
+		options.Desc = strings.HasPrefix(value, "-")
+	}
+
+	// Checked once the sort is known, a cursor is only valid for the order it was issued for
+	if value := c.Query("cursor"); value != "" {
+		var keyset models.Keyset
+		if err := utils.VerifyCursor(value, cursorScope(c, options, spec.Filters), &keyset); err != nil {
+			if !errors.Is(err, utils.ErrInvalidCursor) {
+				log.Printf("Error verifying cursor: %v", err)
+			}
+			utils.ErrorResponse(c, "Invalid cursor", http.StatusBadRequest)
+			return nil, false
+		}
+		options.Keyset = &keyset
+		options.Desc = keyset.Desc
+	}
+
+	return options, true
+}
+
+// keysetPagination signs the positions around a keyset paginated page into the response's cursors.
+// The filters must be the ones given to parseListQuery.
+func keysetPagination(c *gin.Context, options *models.ListOptions, filters any, page *models.KeysetPage) (*utils.Pagination, error) {
+	pagination := &utils.Pagination{Limit: options.Limit}
+	scope := cursorScope(c, options, filters)
+
+	if page.Next != nil {
+		cursor, err := utils.SignCursor(scope, page.Next)
+		if err != nil {
+			return nil, err
+		}
+		pagination.NextCursor = cursor
+	}
+	if page.Prev != nil {
+		cursor, err := utils.SignCursor(scope, page.Prev)
+		if err != nil {
+			return nil, err
+		}
+		pagination.PrevCursor = cursor
+	}
+
+	return pagination, nil
+}
+
+// cursorScope names the list a cursor belongs to: the request path, which includes ids
+// such as the event of its attendees, the sort field and the filters.
+func cursorScope(c *gin.Context, options *models.ListOptions, filters any) string {
+	// Filters are plain values, so encoding them cannot fail
+	encodedFilters, _ := json.Marshal(filters)
+	return c.Request.URL.Path + " " + options.Sort + " " + string(encodedFilters)
+}
+
+// queryInt64 reads an optional id filter. Zero means it was not given.
+func queryInt64(c *gin.Context, name string) (int64, bool) {
+	value := c.Query(name)
+	if value == "" {
+		return 0, true
+	}
+
+	parsed, err := strconv.ParseInt(value, 10, 64)
+	if err != nil || parsed < 1 {
+		utils.ErrorResponse(c, name+" must be a positive number", http.StatusBadRequest)
+		return 0, false
+	}
+
+	return parsed, true
+}
+
+// queryTime reads an optional RFC 3339 time or YYYY-MM-DD date filter.
+func queryTime(c *gin.Context, name string) (*time.Time, bool) {
+	value := c.Query(name)
+	if value == "" {
+		return nil, true
+	}
+
+	for _, layout := range []string{time.RFC3339, time.DateOnly} {
+		if parsed, err := time.Parse(layout, value); err == nil {
+			return &parsed, true
+		}
+	}
+
+	utils.ErrorResponse(c, name+" must be an RFC 3339 time or a YYYY-MM-DD date", http.StatusBadRequest)
+	return nil, false
+}
+
+// eventFiltersFromQuery reads ?from=, ?to=, ?location= and ?organizerId=.
+func eventFiltersFromQuery(c *gin.Context) (*models.EventFilters, bool) {
+	filters := &models.EventFilters{Location: c.Query("location")}
+
+	var ok bool
+	if filters.From, ok = queryTime(c, "from"); !ok {
+		return nil, false
+	}
+	if filters.To, ok = queryTime(c, "to"); !ok {
+		return nil, false
+	}
+	if filters.OrganizerID, ok = queryInt64(c, "organizerId"); !ok {
+		return nil, false
+	}
+
+	if filters.From != nil && filters.To != nil && filters.To.Before(*filters.From) {
+		utils.ErrorResponse(c, "to must not be before from", http.StatusBadRequest)
+		return nil, false
+	}
+
+	return filters, true
+}
+
+// userFiltersFromQuery reads ?role= and ?name=.
+func userFiltersFromQuery(c *gin.Context) (*models.UserFilters, bool) {
+	filters := &models.UserFilters{Role: c.Query("role"), Name: c.Query("name")}
+
+	if filters.Role != "" && !slices.Contains([]string{models.RoleUser, models.RoleOrganizer, models.RoleAdmin}, filters.Role) {
+		utils.ErrorResponse(c, "role must be one of: user, organizer, admin", http.StatusBadRequest)
+		return nil, false
+	}
+
+	return filters, true
+}
+
+// attendeeFiltersFromQuery reads ?eventId=, ?userId= and ?status=.
+func attendeeFiltersFromQuery(c *gin.Context) (*models.AttendeeFilters, bool) {
+	filters := &models.AttendeeFilters{}
+
+	var ok bool
+	if filters.EventID, ok = queryInt64(c, "eventId"); !ok {
+		return nil, false
+	}
+	if filters.UserID, ok = queryInt64(c, "userId"); !ok {
+		return nil, false
+	}
+	if filters.Statuses, ok = queryAttendeeStatuses(c); !ok {
+		return nil, false
+	}
+
+	return filters, true
+}
+
+// queryAttendeeStatuses reads an optional comma separated ?status= filter, e.g. ?status=going,maybe.
+func queryAttendeeStatuses(c *gin.Context) ([]string, bool) {
+	value := c.Query("status")
+	if value == "" {
+		return nil, true
+	}
+
+	statuses := strings.Split(value, ",")
+	for _, status := range statuses {
+		if !models.IsAttendeeStatus(status) {
+			utils.ErrorResponse(c, "status must be one of: "+strings.Join(models.AttendeeStatuses, ", "), http.StatusBadRequest)
+			return nil, false
+		}
+	}
+
+	return statuses, true
+}
@@ -0,0 +1,91 @@
+package services
+
+import (
+	"math"
+	"strings"
+	"time"
+
+	"github.com/vickon16/go-gin-rest-api/internal/app"
+	"github.com/vickon16/go-gin-rest-api/internal/env"
+	"github.com/vickon16/go-gin-rest-api/internal/utils"
+)
+
+const (
+	loginScopeEmail = "email"
+	loginScopeIP    = "ip"
+
+	// Failures allowed for an email before each new failure adds a delay
+	loginFreeAttempts = 3
+	loginMaxDelay     = time.Minute
+)
+
+// loginLockRemaining returns how long the email or the client IP must still wait before logging in.
+func loginLockRemaining(app *app.Application, email, ip string) (time.Duration, error) {
+	var remaining time.Duration
+
+	for _, key := range []string{
+		utils.ConstructRedisLoginLockKey(loginScopeEmail, normalizeLoginEmail(email)),
+		utils.ConstructRedisLoginLockKey(loginScopeIP, ip),
+	} {
+		ttl, err := app.Redis.TTL(key)
+		if err != nil {
+			return 0, err
+		}
+		remaining = max(remaining, ttl)
+	}
+
+	return remaining, nil
+}
+
+// recordLoginFailure counts a failed attempt and locks the email or IP when needed.
+// Delays for an email grow exponentially after a few failures, until the full lockout.
+func recordLoginFailure(app *app.Application, email, ip string) error {
+	window := time.Duration(env.GetEnvInt("LOGIN_ATTEMPT_WINDOW_MINUTES", 15)) * time.Minute
+	lockout := time.Duration(env.GetEnvInt("LOGIN_LOCKOUT_MINUTES", 15)) * time.Minute
+	maxEmailAttempts := int64(env.GetEnvInt("LOGIN_MAX_ATTEMPTS", 10))
+	maxIPAttempts := int64(env.GetEnvInt("LOGIN_IP_MAX_ATTEMPTS", 50))
+
+	email = normalizeLoginEmail(email)
+	emailFailures, err := app.Redis.Increment(utils.ConstructRedisLoginFailuresKey(loginScopeEmail, email), window)
+	if err != nil {
+		return err
+	}
+
+	var emailLock time.Duration
+	if emailFailures >= maxEmailAttempts {
+		emailLock = lockout
+	} else if emailFailures > loginFreeAttempts {
+		delay := time.Duration(math.Pow(2, float64(emailFailures-loginFreeAttempts))) * time.Second
+		emailLock = min(delay, loginMaxDelay)
+	}
+	if emailLock > 0 {
+		if err := app.Redis.Set(utils.ConstructRedisLoginLockKey(loginScopeEmail, email), emailFailures, emailLock); err != nil {
+			return err
+		}
+	}
+
+	ipFailures, err := app.Redis.Increment(utils.ConstructRedisLoginFailuresKey(loginScopeIP, ip), window)
+	if err != nil {
+		return err
+	}
+	if ipFailures >= maxIPAttempts {
+		return app.Redis.Set(utils.ConstructRedisLoginLockKey(loginScopeIP, ip), ipFailures, lockout)
+	}
+
+	return nil
+}
+
+// resetLoginFailures clears the counters and lock for an email.
+func resetLoginFailures(app *app.Application, email string) error {
+	email = normalizeLoginEmail(email)
+
+	if err := app.Redis.Delete(utils.ConstructRedisLoginFailuresKey(loginScopeEmail, email)); err != nil {
+		return err
+	}
+
+	return app.Redis.Delete(utils.ConstructRedisLoginLockKey(loginScopeEmail, email))
+}
+
+func normalizeLoginEmail(email string) string {
+	return strings.ToLower(strings.TrimSpace(email))
+}
@@ -0,0 +1,140 @@
+package services
+
+import (
+	"fmt"
+	"log"
+	"math"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/vickon16/go-gin-rest-api/internal/app"
+	"github.com/vickon16/go-gin-rest-api/internal/database/models"
+	"github.com/vickon16/go-gin-rest-api/internal/env"
+	"github.com/vickon16/go-gin-rest-api/internal/mailer"
+	"github.com/vickon16/go-gin-rest-api/internal/utils"
+)
+
+// RequestMagicLink emails a single use login link to the address.
+func RequestMagicLink(app *app.Application) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var dto models.MagicLinkDto
+
+		if err := c.ShouldBindJSON(&dto); err != nil {
+			utils.ErrorResponse(c, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		// Throttle before looking the user up so the limit does not reveal which emails exist
+		requestsKey := utils.ConstructRedisMagicLinkRequestsKey(normalizeLoginEmail(dto.Email))
+		window := time.Duration(env.GetEnvInt("MAGIC_LINK_WINDOW_MINUTES", 15)) * time.Minute
+
+		requests, err := app.Redis.Increment(requestsKey, window)
+		if err != nil {
+			utils.ErrorResponse(c, "Something went wrong", http.StatusInternalServerError)
+			return
+		}
+		if requests > int64(env.GetEnvInt("MAGIC_LINK_MAX_REQUESTS", 3)) {
+			if ttl, err := app.Redis.TTL(requestsKey); err == nil && ttl > 0 {
+				c.Header("Retry-After", strconv.Itoa(int(math.Ceil(ttl.Seconds()))))
+			}
+			utils.ErrorResponse(c, "Too many login links requested, please try again later", http.StatusTooManyRequests)
+			return
+		}
+
+		// Same response whether the account exists or not
+		const message = "If an account exists for this email, a login link has been sent"
+
+		user, err := app.Models.Users.GetUserByEmail(dto.Email)
+		if err != nil {
+			utils.ErrorResponse(c, "Failed to get user by email", http.StatusInternalServerError)
+			return
+		}
+		if user == nil {
+			utils.SuccessResponse(c, message, nil)
+			return
+		}
+
+		expirationMinutes := env.GetEnvInt("MAGIC_LINK_EXPIRATION_MINUTES", 15)
+		token, err := IssueOneTimeToken(app, user.ID, models.TokenPurposeMagicLink, time.Duration(expirationMinutes)*time.Minute)
+		if err != nil {
+			log.Printf("Failed to create magic link token: %v", err)
+			utils.ErrorResponse(c, "Something went wrong", http.StatusInternalServerError)
+			return
+		}
+
+		loginUrl := env.GetEnvString("APP_URL", "http://localhost:3000") + "/magic-link?token=" + url.QueryEscape(token)
+		err = app.Mailer.Send(mailer.Message{
+			To:      user.Email,
+			Subject: "Your login link",
+			Body: fmt.Sprintf(
+				"Hi %s,\n\nUse the link below to sign in. It can only be used once and expires in %d minutes.\n\n%s\n\nIf you did not request this, you can ignore this email.\n",
+				user.Name, expirationMinutes, loginUrl,
+			),
+		})
+		if err != nil {
+			log.Printf("Failed to send magic link email: %v", err)
+		}
+
+		utils.SuccessResponse(c, message, nil)
+	}
+}
+
+// VerifyMagicLink exchanges the token from the link for the normal login response.
+func VerifyMagicLink(app *app.Application) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var dto models.VerifyMagicLinkDto
+
+		if err := c.ShouldBindJSON(&dto); err != nil {
+			utils.ErrorResponse(c, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		token, err := app.Models.OneTimeTokens.Consume(utils.HashToken(dto.Token), models.TokenPurposeMagicLink)
+		if err != nil {
+			utils.ErrorResponse(c, "Failed to verify login link", http.StatusInternalServerError)
+			return
+		}
+		if token == nil {
+			utils.ErrorResponse(c, "Invalid or expired login link", http.StatusUnauthorized)
+			return
+		}
+
+		user, err := app.Models.Users.Get(token.UserID)
+		if err != nil || user == nil {
+			utils.ErrorResponse(c, "Failed to get user", http.StatusInternalServerError)
+			return
+		}
+
+		// Opening the link proves the user owns the address
+		if user.EmailVerifiedAt == nil {
+			if err := app.Models.Users.MarkEmailVerified(user.ID); err != nil {
+				log.Printf("Failed to mark email verified: %v", err)
+			}
+			app.Redis.Delete(utils.ConstructRedisUserKey(user.ID))
+		}
+
+		if user.TOTPEnabledAt != nil {
+			challenge, err := createTwoFactorChallenge(app, user.ID)
+			if err != nil {
+				log.Printf("Failed to create two-factor challenge: %v", err)
+				utils.ErrorResponse(c, "Something went wrong", http.StatusInternalServerError)
+				return
+			}
+
+			utils.SuccessResponse(c, "Two-factor authentication required", challenge)
+			return
+		}
+
+		loginResponse, err := createLoginResponse(app, c, user, "")
+		if err != nil {
+			log.Printf("Failed to create login response: %v", err)
+			utils.ErrorResponse(c, "Something went wrong", http.StatusInternalServerError)
+			return
+		}
+
+		utils.SuccessResponse(c, "Login successful", loginResponse)
+	}
+}
@@ -0,0 +1,258 @@
+package services
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/redis/go-redis/v9"
+	"github.com/vickon16/go-gin-rest-api/internal/app"
+	"github.com/vickon16/go-gin-rest-api/internal/database/models"
+	"github.com/vickon16/go-gin-rest-api/internal/oidc"
+	"github.com/vickon16/go-gin-rest-api/internal/utils"
+)
+
+const oidcStateDuration = 10 * time.Minute
+
+// oidcLoginState is kept in Redis between the redirect and the callback.
+type oidcLoginState struct {
+	Provider     string `json:"provider"`
+	Nonce        string `json:"nonce"`
+	CodeVerifier string `json:"codeVerifier"`
+}
+
+// StartOIDCLogin redirects the browser to the provider's authorization endpoint.
+func StartOIDCLogin(app *app.Application) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		provider, err := app.OIDC.Get(c.Param("provider"))
+		if err != nil {
+			utils.ErrorResponse(c, "Unknown login provider", http.StatusNotFound)
+			return
+		}
+
+		state, err := utils.GenerateOpaqueToken()
+		if err != nil {
+			utils.ErrorResponse(c, "Something went wrong", http.StatusInternalServerError)
+			return
+		}
+		nonce, err := utils.GenerateOpaqueToken()
+		if err != nil {
+			utils.ErrorResponse(c, "Something went wrong", http.StatusInternalServerError)
+			return
+		}
+		codeVerifier, err := utils.GenerateOpaqueToken()
+		if err != nil {
+			utils.ErrorResponse(c, "Something went wrong", http.StatusInternalServerError)
+			return
+		}
+
+		authUrl, err := provider.AuthCodeURL(c.Request.Context(), state, nonce, codeVerifier)
+		if err != nil {
+			log.Printf("Failed to build OIDC authorization url: %v", err)
+			utils.ErrorResponse(c, "Login provider is unavailable", http.StatusBadGateway)
+			return
+		}
+
+		value, err := json.Marshal(oidcLoginState{Provider: provider.Config.Name, Nonce: nonce, CodeVerifier: codeVerifier})
+		if err != nil {
+			utils.ErrorResponse(c, "Something went wrong", http.StatusInternalServerError)
+			return
+		}
+
+		stateKey := utils.ConstructRedisOIDCStateKey(utils.HashToken(state))
+		if err := app.Redis.Set(stateKey, value, oidcStateDuration); err != nil {
+			utils.ErrorResponse(c, "Something went wrong", http.StatusInternalServerError)
+			return
+		}
+
+		c.Redirect(http.StatusFound, authUrl)
+	}
+}
+
+// OIDCCallback completes the authorization code flow and logs the user in with our own tokens.
+func OIDCCallback(app *app.Application) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var dto models.OIDCCallbackDto
+
+		if err := c.ShouldBindQuery(&dto); err != nil {
+			utils.ErrorResponse(c, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		// The state is single use, whatever the outcome of the callback
+		value, err := app.Redis.GetDelete(utils.ConstructRedisOIDCStateKey(utils.HashToken(dto.State)))
+		if err != nil {
+			if errors.Is(err, redis.Nil) {
+				utils.ErrorResponse(c, "Invalid or expired login state", http.StatusBadRequest)
+				return
+			}
+			utils.ErrorResponse(c, "Something went wrong", http.StatusInternalServerError)
+			return
+		}
+
+		var loginState oidcLoginState
+		if err := json.Unmarshal([]byte(value), &loginState); err != nil || loginState.Provider != c.Param("provider") {
+			utils.ErrorResponse(c, "Invalid or expired login state", http.StatusBadRequest)
+			return
+		}
+
+		if dto.Error != "" {
+			utils.ErrorResponse(c, "Login was not completed: "+dto.Error, http.StatusUnauthorized)
+			return
+		}
+		if dto.Code == "" {
+			utils.ErrorResponse(c, "Missing authorization code", http.StatusBadRequest)
+			return
+		}
+
+		provider, err := app.OIDC.Get(loginState.Provider)
+		if err != nil {
+			utils.ErrorResponse(c, "Unknown login provider", http.StatusNotFound)
+			return
+		}
+
+		ctx := c.Request.Context()
+		tokens, err := provider.Exchange(ctx, dto.Code, loginState.CodeVerifier)
+		if err != nil {
+			log.Printf("OIDC code exchange with %s failed: %v", provider.Config.Name, err)
+			utils.ErrorResponse(c, "Login with provider failed", http.StatusUnauthorized)
+			return
+		}
+
+		claims, err := provider.VerifyIDToken(ctx, tokens.IDToken, loginState.Nonce)
+		if err != nil {
+			log.Printf("OIDC id token from %s rejected: %v", provider.Config.Name, err)
+			utils.ErrorResponse(c, "Login with provider failed", http.StatusUnauthorized)
+			return
+		}
+
+		user, err := findOrLinkOIDCUser(app, provider.Config.Name, claims)
+		if err != nil {
+			if errors.Is(err, errOIDCEmailNotVerified) {
+				utils.ErrorResponse(c, "The provider did not return a verified email", http.StatusForbidden)
+				return
+			}
+			if errors.Is(err, errOIDCAccountNotVerified) {
+				utils.ErrorResponse(c, "An account with this email exists but its email is not verified, verify it before logging in with the provider", http.StatusConflict)
+				return
+			}
+			log.Printf("Failed to resolve OIDC user: %v", err)
+			utils.ErrorResponse(c, "Something went wrong", http.StatusInternalServerError)
+			return
+		}
+
+		// The provider replaces the password, not the second factor
+		if user.TOTPEnabledAt != nil {
+			challenge, err := createTwoFactorChallenge(app, user.ID)
+			if err != nil {
+				log.Printf("Failed to create two-factor challenge: %v", err)
+				utils.ErrorResponse(c, "Something went wrong", http.StatusInternalServerError)
+				return
+			}
+
+			utils.SuccessResponse(c, "Two-factor authentication required", challenge)
+			return
+		}
+
+		loginResponse, err := createLoginResponse(app, c, user, "")
+		if err != nil {
+			log.Printf("Failed to create login response: %v", err)
+			utils.ErrorResponse(c, "Something went wrong", http.StatusInternalServerError)
+			return
+		}
+
+		utils.SuccessResponse(c, "Login successful", loginResponse)
+	}
+}
+
+var (
+	errOIDCEmailNotVerified   = errors.New("oidc email not verified")
+	errOIDCAccountNotVerified = errors.New("account with the oidc email is not verified")
+)
+
+// findOrLinkOIDCUser returns the user already linked to the provider account.
+// Otherwise it links the user with the same verified email, or creates one.
+func findOrLinkOIDCUser(app *app.Application, provider string, claims *oidc.IDTokenClaims) (*models.User, error) {
+	identity, err := app.Models.Identities.GetByProviderSubject(provider, claims.Subject)
+	if err != nil {
+		return nil, err
+	}
+	if identity != nil {
+		user, err := app.Models.Users.Get(identity.UserID)
+		if err != nil {
+			return nil, err
+		}
+		if user == nil {
+			return nil, errors.New("linked user no longer exists")
+		}
+
+		return user, nil
+	}
+
+	user, err := app.Models.Users.GetUserByEmail(claims.Email)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkOIDCLink(claims, user); err != nil {
+		return nil, err
+	}
+
+	if user == nil {
+		// Users created here have no usable password until they reset it
+		password, err := utils.GenerateOpaqueToken()
+		if err != nil {
+			return nil, err
+		}
+		hashedPassword, err := utils.HashPassword(password)
+		if err != nil {
+			return nil, err
+		}
+
+		name := claims.Name
+		if name == "" {
+			name, _, _ = strings.Cut(claims.Email, "@")
+		}
+
+		user = &models.User{Email: claims.Email, Name: name, Password: hashedPassword}
+		if err := app.Models.Users.Insert(user); err != nil {
+			return nil, err
+		}
+
+		if err := app.Models.Users.MarkEmailVerified(user.ID); err != nil {
+			return nil, err
+		}
+		now := time.Now()
+		user.EmailVerifiedAt = &now
+	}
+
+	err = app.Models.Identities.Insert(&models.UserIdentity{
+		UserID:   user.ID,
+		Provider: provider,
+		Subject:  claims.Subject,
+		Email:    claims.Email,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return user, nil
+}
+
+// checkOIDCLink decides whether the provider account may be linked to the user with its
+// email, or to a new user when there is none.
+func checkOIDCLink(claims *oidc.IDTokenClaims, user *models.User) error {
+	// Linking by an unverified email would let anyone take over the account
+	if claims.Email == "" || !claims.IsEmailVerified() {
+		return errOIDCEmailNotVerified
+	}
+	// Whoever registered the address without verifying it still knows the password
+	if user != nil && user.EmailVerifiedAt == nil {
+		return errOIDCAccountNotVerified
+	}
+
+	return nil
+}
@@ -0,0 +1,36 @@
+package services
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/vickon16/go-gin-rest-api/internal/database/models"
+	"github.com/vickon16/go-gin-rest-api/internal/oidc"
+)
+
+func TestCheckOIDCLink(t *testing.T) {
+	verifiedAt := time.Now()
+	verifiedUser := &models.User{ID: 1, Email: "ada@example.com", EmailVerifiedAt: &verifiedAt}
+	unverifiedUser := &models.User{ID: 2, Email: "ada@example.com"}
+
+	tests := []struct {
+		name   string
+		claims *oidc.IDTokenClaims
+		user   *models.User
+		want   error
+	}{
+		{name: "new user", claims: &oidc.IDTokenClaims{Email: "ada@example.com", EmailVerified: true}},
+		{name: "verified user", claims: &oidc.IDTokenClaims{Email: "ada@example.com", EmailVerified: "true"}, user: verifiedUser},
+		{name: "unverified user", claims: &oidc.IDTokenClaims{Email: "ada@example.com", EmailVerified: true}, user: unverifiedUser, want: errOIDCAccountNotVerified},
+		{name: "unverified provider email", claims: &oidc.IDTokenClaims{Email: "ada@example.com", EmailVerified: false}, user: verifiedUser, want: errOIDCEmailNotVerified},
+		{name: "missing verified claim", claims: &oidc.IDTokenClaims{Email: "ada@example.com"}, want: errOIDCEmailNotVerified},
+		{name: "missing email", claims: &oidc.IDTokenClaims{EmailVerified: true}, want: errOIDCEmailNotVerified},
+	}
+
+	for _, tt := range tests {
+		if err := checkOIDCLink(tt.claims, tt.user); !errors.Is(err, tt.want) {
+			t.Errorf("%s: checkOIDCLink returned %v, want %v", tt.name, err, tt.want)
+		}
+	}
+}
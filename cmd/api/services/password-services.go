@@ -0,0 +1,149 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/vickon16/go-gin-rest-api/internal/app"
+	"github.com/vickon16/go-gin-rest-api/internal/database/models"
+	"github.com/vickon16/go-gin-rest-api/internal/env"
+	"github.com/vickon16/go-gin-rest-api/internal/mailer"
+	"github.com/vickon16/go-gin-rest-api/internal/passwords"
+	"github.com/vickon16/go-gin-rest-api/internal/utils"
+)
+
+func ForgotPassword(app *app.Application) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var dto models.ForgotPasswordDto
+
+		if err := c.ShouldBindJSON(&dto); err != nil {
+			utils.ErrorResponse(c, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		// Same response whether the account exists or not
+		const message = "If an account exists for this email, a password reset link has been sent"
+
+		user, err := app.Models.Users.GetUserByEmail(dto.Email)
+		if err != nil {
+			utils.ErrorResponse(c, "Failed to get user by email", http.StatusInternalServerError)
+			return
+		}
+		if user == nil {
+			utils.SuccessResponse(c, message, nil)
+			return
+		}
+
+		expirationMinutes := env.GetEnvInt("PASSWORD_RESET_EXPIRATION_MINUTES", 30)
+		token, err := IssueOneTimeToken(app, user.ID, models.TokenPurposePasswordReset, time.Duration(expirationMinutes)*time.Minute)
+		if err != nil {
+			log.Printf("Failed to create password reset token: %v", err)
+			utils.ErrorResponse(c, "Something went wrong", http.StatusInternalServerError)
+			return
+		}
+
+		resetUrl := env.GetEnvString("APP_URL", "http://localhost:3000") + "/reset-password?token=" + url.QueryEscape(token)
+		err = app.Mailer.Send(mailer.Message{
+			To:      user.Email,
+			Subject: "Reset your password",
+			Body: fmt.Sprintf(
+				"Hi %s,\n\nUse the link below to reset your password. It expires in %d minutes.\n\n%s\n\nIf you did not request this, you can ignore this email.\n",
+				user.Name, expirationMinutes, resetUrl,
+			),
+		})
+		if err != nil {
+			log.Printf("Failed to send password reset email: %v", err)
+		}
+
+		utils.SuccessResponse(c, message, nil)
+	}
+}
+
+func ResetPassword(app *app.Application) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var dto models.ResetPasswordDto
+
+		if err := c.ShouldBindJSON(&dto); err != nil {
+			utils.ErrorResponse(c, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		// The token is only used up once the new password is accepted, a rejected one can be retried
+		tokenHash := utils.HashToken(dto.Token)
+		token, err := app.Models.OneTimeTokens.GetPending(tokenHash, models.TokenPurposePasswordReset)
+		if err != nil {
+			utils.ErrorResponse(c, "Failed to verify reset token", http.StatusInternalServerError)
+			return
+		}
+		if token == nil {
+			utils.ErrorResponse(c, "Invalid or expired reset token", http.StatusBadRequest)
+			return
+		}
+
+		user, err := app.Models.Users.Get(token.UserID)
+		if err != nil || user == nil {
+			utils.ErrorResponse(c, "Failed to get user", http.StatusInternalServerError)
+			return
+		}
+
+		if !validateNewPassword(app, c, dto.Password, user.Name, user.Email) {
+			return
+		}
+
+		hashedPassword, err := utils.HashPassword(dto.Password)
+		if err != nil {
+			utils.ErrorResponse(c, "Something went wrong", http.StatusInternalServerError)
+			return
+		}
+
+		// Another request may have used the token since it was read
+		token, err = app.Models.OneTimeTokens.Consume(tokenHash, models.TokenPurposePasswordReset)
+		if err != nil {
+			utils.ErrorResponse(c, "Failed to verify reset token", http.StatusInternalServerError)
+			return
+		}
+		if token == nil {
+			utils.ErrorResponse(c, "Invalid or expired reset token", http.StatusBadRequest)
+			return
+		}
+
+		if err := app.Models.Users.UpdatePassword(token.UserID, hashedPassword); err != nil {
+			log.Printf("Failed to reset password: %v", err)
+			utils.ErrorResponse(c, "Failed to reset password", http.StatusInternalServerError)
+			return
+		}
+
+		cacheKey := utils.ConstructRedisUserKey(token.UserID)
+		app.Redis.Delete(cacheKey)
+
+		if err := revokeAllSessions(app, token.UserID); err != nil {
+			log.Printf("Failed to revoke sessions for user %d: %v", token.UserID, err)
+		}
+
+		utils.SuccessResponse(c, "Password reset successfully", nil)
+	}
+}
+
+// validateNewPassword checks the password against the policy and writes a 400
+// listing every problem when it is rejected.
+func validateNewPassword(app *app.Application, c *gin.Context, password string, identifiers ...string) bool {
+	err := app.Passwords.Validate(password, identifiers...)
+	if err == nil {
+		return true
+	}
+
+	var policyErr *passwords.PolicyError
+	if errors.As(err, &policyErr) {
+		utils.ErrorResponse(c, "Password does not meet the requirements", http.StatusBadRequest, policyErr.Problems)
+		return false
+	}
+
+	log.Printf("Error validating password: %v", err)
+	utils.ErrorResponse(c, "Something went wrong", http.StatusInternalServerError)
+	return false
+}
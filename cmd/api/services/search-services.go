@@ -0,0 +1,123 @@
+package services
+
+import (
+	"context"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/vickon16/go-gin-rest-api/internal/app"
+	"github.com/vickon16/go-gin-rest-api/internal/database/models"
+	"github.com/vickon16/go-gin-rest-api/internal/search"
+	"github.com/vickon16/go-gin-rest-api/internal/utils"
+)
+
+// Longer queries are rejected rather than handed to the search backend
+const maxSearchQueryLength = 200
+
+type SearchHitSerializer struct {
+	Event      models.EventSerializer `json:"event"`
+	Rank       float64                `json:"rank"`
+	Highlights search.Highlights      `json:"highlights"`
+}
+
+// SearchEvents finds events by keywords in their name, location and description, best match first.
+// ?q= takes web search syntax: "quoted phrases", or, and -excluded words.
+func SearchEvents(app *app.Application) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		query := strings.TrimSpace(c.Query("q"))
+		if query == "" {
+			utils.ErrorResponse(c, "q is required", http.StatusBadRequest)
+			return
+		}
+		if len(query) > maxSearchQueryLength {
+			utils.ErrorResponse(c, "q must be at most 200 characters", http.StatusBadRequest)
+			return
+		}
+
+		options, ok := parseListQuery(c, listQuerySpec{})
+		if !ok {
+			return
+		}
+
+		hits, total, err := app.Search.Search(c.Request.Context(), query, options)
+		if err != nil {
+			log.Printf("Error searching events: %v", err)
+			utils.ErrorResponse(c, "Failed to search events", http.StatusInternalServerError)
+			return
+		}
+
+		events := make([]*models.Event, len(hits))
+		for i, hit := range hits {
+			events[i] = hit.Event
+		}
+		if err := loadAttendeeCounts(app, events...); err != nil {
+			log.Printf("Error counting attendees: %v", err)
+			utils.ErrorResponse(c, "Failed to search events", http.StatusInternalServerError)
+			return
+		}
+
+		viewer := viewerFromContext(c)
+
+		serializedHits := []SearchHitSerializer{}
+		for _, hit := range hits {
+			serializedHits = append(serializedHits, SearchHitSerializer{
+				Event:      models.CreateResponseEvent(hit.Event, viewer),
+				Rank:       hit.Rank,
+				Highlights: hit.Highlights,
+			})
+		}
+
+		utils.PaginatedResponse(c, "Successfully searched events", serializedHits, utils.NewPagination(options.Page, options.Limit, total))
+	}
+}
+
+// IndexAllEvents adds every event to the search backend. Only needed for backends
+// that do not read the events table themselves, such as the in-memory one.
+func IndexAllEvents(app *app.Application) error {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+
+	options := models.NewListOptions()
+	options.Limit = models.MaxListLimit
+
+	for {
+		events, page, err := app.Models.Events.GetAll(&models.EventFilters{}, options)
+		if err != nil {
+			return err
+		}
+
+		for _, event := range events {
+			if err := app.Search.Index(ctx, event); err != nil {
+				return err
+			}
+		}
+
+		if page.Next == nil {
+			return nil
+		}
+		options.Keyset = page.Next
+	}
+}
+
+// indexEvent updates the event in the search backend. Failures are only logged,
+// the event is saved either way and shows up in search once reindexed.
+func indexEvent(app *app.Application, event *models.Event) {
+	ctx, cancel := utils.CreateContext()
+	defer cancel()
+
+	if err := app.Search.Index(ctx, event); err != nil {
+		log.Printf("Failed to index event %d: %v", event.ID, err)
+	}
+}
+
+func removeEventFromIndex(app *app.Application, eventId int64) {
+	ctx, cancel := utils.CreateContext()
+	defer cancel()
+
+	if err := app.Search.Remove(ctx, eventId); err != nil {
+		log.Printf("Failed to remove event %d from the search index: %v", eventId, err)
+	}
+}
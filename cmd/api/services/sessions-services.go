@@ -0,0 +1,147 @@
+package services
+
+import (
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/vickon16/go-gin-rest-api/cmd/api/middlewares"
+	"github.com/vickon16/go-gin-rest-api/internal/app"
+	"github.com/vickon16/go-gin-rest-api/internal/database/models"
+	"github.com/vickon16/go-gin-rest-api/internal/env"
+	"github.com/vickon16/go-gin-rest-api/internal/utils"
+)
+
+const maxUserAgentLength = 512
+
+func GetMySessions(app *app.Application) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		contextUser := middlewares.GetUserFromContext(c)
+		claims := middlewares.GetClaimsFromContext(c)
+
+		sessions, err := app.Models.Sessions.GetActiveByUser(contextUser.ID)
+		if err != nil {
+			log.Printf("Error getting sessions: %v", err)
+			utils.ErrorResponse(c, "Failed to get sessions", http.StatusInternalServerError)
+			return
+		}
+
+		sessionIds := make([]int64, 0, len(sessions))
+		for _, session := range sessions {
+			sessionIds = append(sessionIds, session.ID)
+		}
+
+		// Activity between refreshes is only tracked in Redis
+		lastSeen, err := app.Redis.GetSessionsLastSeen(sessionIds)
+		if err != nil {
+			log.Printf("Error getting session activity: %v", err)
+		}
+
+		serializedSessions := []models.SessionSerializer{}
+		for _, session := range sessions {
+			serialized := models.CreateResponseSession(session)
+			if seen, ok := lastSeen[session.ID]; ok && (serialized.LastSeenAt == nil || seen.After(*serialized.LastSeenAt)) {
+				serialized.LastSeenAt = &seen
+			}
+			serialized.Current = session.ID == claims.SessionID
+
+			serializedSessions = append(serializedSessions, serialized)
+		}
+
+		utils.SuccessResponse(c, "Successfully retrieved sessions", serializedSessions)
+	}
+}
+
+func RevokeMySession(app *app.Application) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id, err := strconv.ParseInt(c.Param("sessionId"), 10, 64)
+		if err != nil {
+			utils.ErrorResponse(c, "Invalid session Id", http.StatusBadRequest)
+			return
+		}
+
+		contextUser := middlewares.GetUserFromContext(c)
+
+		revoked, err := revokeSession(app, contextUser.ID, id)
+		if err != nil {
+			log.Printf("Failed to revoke session: %v", err)
+			utils.ErrorResponse(c, "Failed to revoke session", http.StatusInternalServerError)
+			return
+		}
+		if !revoked {
+			utils.ErrorResponse(c, "Session not found", http.StatusNotFound)
+			return
+		}
+
+		utils.SuccessResponse(c, "Successfully revoked session", nil)
+	}
+}
+
+// startOrExtendSession creates the session for a new refresh token family, or
+// moves the expiry of the existing one when its refresh token is rotated.
+func startOrExtendSession(app *app.Application, c *gin.Context, userId int64, familyId string, expiresAt time.Time) (*models.Session, error) {
+	session, err := app.Models.Sessions.GetByFamily(familyId)
+	if err != nil {
+		return nil, err
+	}
+
+	if session != nil {
+		if err := app.Models.Sessions.Extend(session.ID, expiresAt); err != nil {
+			return nil, err
+		}
+
+		return session, nil
+	}
+
+	userAgent := c.Request.UserAgent()
+	if len(userAgent) > maxUserAgentLength {
+		userAgent = userAgent[:maxUserAgentLength]
+	}
+
+	now := time.Now()
+	session = &models.Session{
+		UserID:     userId,
+		FamilyID:   familyId,
+		UserAgent:  userAgent,
+		IPAddress:  c.ClientIP(),
+		LastSeenAt: &now,
+		ExpiresAt:  expiresAt,
+	}
+	if err := app.Models.Sessions.Insert(session); err != nil {
+		return nil, err
+	}
+
+	return session, nil
+}
+
+// revokeSession ends a session of the user along with its refresh tokens and
+// any access token still carrying its sid. It returns false when the session
+// does not exist or was already revoked.
+func revokeSession(app *app.Application, userId, sessionId int64) (bool, error) {
+	session, err := app.Models.Sessions.Get(sessionId, userId)
+	if err != nil || session == nil {
+		return false, err
+	}
+
+	revoked, err := app.Models.Sessions.Revoke(session.ID, userId)
+	if err != nil || !revoked {
+		return false, err
+	}
+
+	if err := app.Models.RefreshTokens.RevokeFamily(session.FamilyID); err != nil {
+		return false, err
+	}
+
+	if err := app.Redis.RevokeSession(session.ID, accessTokenLifetime()); err != nil {
+		return false, err
+	}
+
+	return true, nil
+}
+
+// accessTokenLifetime is how long an access token stays valid, and so how long a revoked session must be remembered.
+func accessTokenLifetime() time.Duration {
+	return time.Duration(env.GetEnvInt("JWT_EXPIRATION_MINUTES", 10)) * time.Minute
+}
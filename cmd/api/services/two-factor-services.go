@@ -0,0 +1,344 @@
+package services
+
+import (
+	"crypto/rand"
+	"encoding/base32"
+	"errors"
+	"log"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/redis/go-redis/v9"
+	"github.com/vickon16/go-gin-rest-api/cmd/api/middlewares"
+	"github.com/vickon16/go-gin-rest-api/internal/app"
+	"github.com/vickon16/go-gin-rest-api/internal/database/models"
+	"github.com/vickon16/go-gin-rest-api/internal/env"
+	"github.com/vickon16/go-gin-rest-api/internal/utils"
+)
+
+const (
+	twoFactorChallengeDuration = 5 * time.Minute
+	twoFactorMaxAttempts       = 5
+	recoveryCodeCount          = 10
+)
+
+func EnrollTwoFactor(app *app.Application) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		contextUser := middlewares.GetUserFromContext(c)
+		if contextUser.TwoFactorEnabled {
+			utils.ErrorResponse(c, "Two-factor authentication is already enabled", http.StatusConflict)
+			return
+		}
+
+		secret, err := utils.GenerateTOTPSecret()
+		if err != nil {
+			utils.ErrorResponse(c, "Something went wrong", http.StatusInternalServerError)
+			return
+		}
+
+		encryptedSecret, err := utils.Encrypt(secret)
+		if err != nil {
+			utils.ErrorResponse(c, "Something went wrong", http.StatusInternalServerError)
+			return
+		}
+
+		if err := app.Models.Users.SetTOTPSecret(contextUser.ID, encryptedSecret); err != nil {
+			log.Printf("Failed to store TOTP secret: %v", err)
+			utils.ErrorResponse(c, "Failed to start two-factor enrollment", http.StatusInternalServerError)
+			return
+		}
+
+		issuer := env.GetEnvString("TOTP_ISSUER", "Go Gin Rest API")
+		enrollment := models.TwoFactorEnrollmentSerializer{
+			Secret:     secret,
+			OtpauthURI: utils.TOTPAuthURI(issuer, contextUser.Email, secret),
+		}
+
+		utils.SuccessResponse(c, "Scan the code with your authenticator app and confirm it", enrollment)
+	}
+}
+
+func ConfirmTwoFactor(app *app.Application) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var dto models.TwoFactorCodeDto
+
+		if err := c.ShouldBindJSON(&dto); err != nil {
+			utils.ErrorResponse(c, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		contextUser := middlewares.GetUserFromContext(c)
+		if contextUser.TwoFactorEnabled {
+			utils.ErrorResponse(c, "Two-factor authentication is already enabled", http.StatusConflict)
+			return
+		}
+
+		encryptedSecret, err := app.Models.Users.GetTOTPSecret(contextUser.ID)
+		if err != nil {
+			utils.ErrorResponse(c, "Failed to get two-factor secret", http.StatusInternalServerError)
+			return
+		}
+		if encryptedSecret == "" {
+			utils.ErrorResponse(c, "Two-factor enrollment has not been started", http.StatusBadRequest)
+			return
+		}
+
+		valid, err := verifyTOTP(app, contextUser.ID, encryptedSecret, dto.Code)
+		if err != nil {
+			log.Printf("Failed to verify TOTP code: %v", err)
+			utils.ErrorResponse(c, "Something went wrong", http.StatusInternalServerError)
+			return
+		}
+		if !valid {
+			utils.ErrorResponse(c, "Invalid two-factor code", http.StatusBadRequest)
+			return
+		}
+
+		recoveryCodes, err := generateRecoveryCodes(app, contextUser.ID)
+		if err != nil {
+			log.Printf("Failed to generate recovery codes: %v", err)
+			utils.ErrorResponse(c, "Failed to enable two-factor authentication", http.StatusInternalServerError)
+			return
+		}
+
+		if err := app.Models.Users.EnableTOTP(contextUser.ID); err != nil {
+			log.Printf("Failed to enable TOTP: %v", err)
+			utils.ErrorResponse(c, "Failed to enable two-factor authentication", http.StatusInternalServerError)
+			return
+		}
+
+		cacheKey := utils.ConstructRedisUserKey(contextUser.ID)
+		app.Redis.Delete(cacheKey)
+
+		utils.SuccessResponse(c, "Two-factor authentication enabled. Store these recovery codes safely, they are only shown once", models.RecoveryCodesSerializer{RecoveryCodes: recoveryCodes})
+	}
+}
+
+func DisableTwoFactor(app *app.Application) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var dto models.DisableTwoFactorDto
+
+		if err := c.ShouldBindJSON(&dto); err != nil {
+			utils.ErrorResponse(c, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		contextUser := middlewares.GetUserFromContext(c)
+		if !contextUser.TwoFactorEnabled {
+			utils.ErrorResponse(c, "Two-factor authentication is not enabled", http.StatusBadRequest)
+			return
+		}
+
+		user, err := app.Models.Users.GetUserByEmail(contextUser.Email, true)
+		if err != nil || user == nil {
+			utils.ErrorResponse(c, "Failed to get user", http.StatusInternalServerError)
+			return
+		}
+		if !checkCurrentPassword(app, c, user, dto.Password) {
+			return
+		}
+
+		valid, err := verifySecondFactor(app, user.ID, dto.Code)
+		if err != nil {
+			log.Printf("Failed to verify second factor: %v", err)
+			utils.ErrorResponse(c, "Something went wrong", http.StatusInternalServerError)
+			return
+		}
+		if !valid {
+			utils.ErrorResponse(c, "Invalid two-factor code", http.StatusUnauthorized)
+			return
+		}
+
+		if err := app.Models.Users.DisableTOTP(user.ID); err != nil {
+			log.Printf("Failed to disable TOTP: %v", err)
+			utils.ErrorResponse(c, "Failed to disable two-factor authentication", http.StatusInternalServerError)
+			return
+		}
+		if err := app.Models.RecoveryCodes.ReplaceForUser(user.ID, nil); err != nil {
+			log.Printf("Failed to delete recovery codes: %v", err)
+		}
+
+		cacheKey := utils.ConstructRedisUserKey(user.ID)
+		app.Redis.Delete(cacheKey)
+
+		utils.SuccessResponse(c, "Two-factor authentication disabled", nil)
+	}
+}
+
+// VerifyTwoFactorLogin exchanges the challenge returned by LoginUser and a
+// TOTP or recovery code for the normal login response.
+func VerifyTwoFactorLogin(app *app.Application) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var dto models.TwoFactorLoginDto
+
+		if err := c.ShouldBindJSON(&dto); err != nil {
+			utils.ErrorResponse(c, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		challengeHash := utils.HashToken(dto.ChallengeToken)
+		challengeKey := utils.ConstructRedisTwoFactorChallengeKey(challengeHash)
+
+		value, err := app.Redis.Get(challengeKey)
+		if err != nil {
+			if errors.Is(err, redis.Nil) {
+				utils.ErrorResponse(c, "Invalid or expired challenge", http.StatusUnauthorized)
+				return
+			}
+			utils.ErrorResponse(c, "Something went wrong", http.StatusInternalServerError)
+			return
+		}
+
+		userId, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			utils.ErrorResponse(c, "Invalid or expired challenge", http.StatusUnauthorized)
+			return
+		}
+
+		user, err := app.Models.Users.Get(userId)
+		if err != nil || user == nil {
+			utils.ErrorResponse(c, "Failed to get user", http.StatusInternalServerError)
+			return
+		}
+
+		// Every new password login earns a fresh challenge, so wrong codes also count against the account
+		clientIP := c.ClientIP()
+		remaining, err := loginLockRemaining(app, user.Email, clientIP)
+		if err != nil {
+			log.Printf("Failed to check login lock: %v", err)
+			utils.ErrorResponse(c, "Something went wrong", http.StatusInternalServerError)
+			return
+		}
+		if remaining > 0 {
+			c.Header("Retry-After", strconv.Itoa(int(math.Ceil(remaining.Seconds()))))
+			utils.ErrorResponse(c, "Too many login attempts, please try again later", http.StatusTooManyRequests)
+			return
+		}
+
+		attempts, err := app.Redis.Increment(utils.ConstructRedisTwoFactorAttemptsKey(challengeHash), twoFactorChallengeDuration)
+		if err != nil {
+			utils.ErrorResponse(c, "Something went wrong", http.StatusInternalServerError)
+			return
+		}
+		if attempts > twoFactorMaxAttempts {
+			app.Redis.Delete(challengeKey)
+			utils.ErrorResponse(c, "Too many attempts, please login again", http.StatusUnauthorized)
+			return
+		}
+
+		valid, err := verifySecondFactor(app, userId, dto.Code)
+		if err != nil {
+			log.Printf("Failed to verify second factor: %v", err)
+			utils.ErrorResponse(c, "Something went wrong", http.StatusInternalServerError)
+			return
+		}
+		if !valid {
+			if err := recordLoginFailure(app, user.Email, clientIP); err != nil {
+				log.Printf("Failed to record login failure: %v", err)
+			}
+			utils.ErrorResponse(c, "Invalid two-factor code", http.StatusUnauthorized)
+			return
+		}
+
+		// The challenge is single use
+		app.Redis.Delete(challengeKey)
+
+		if err := resetLoginFailures(app, user.Email); err != nil {
+			log.Printf("Failed to reset login failures: %v", err)
+		}
+
+		loginResponse, err := createLoginResponse(app, c, user, "")
+		if err != nil {
+			log.Printf("Failed to create login response: %v", err)
+			utils.ErrorResponse(c, "Something went wrong", http.StatusInternalServerError)
+			return
+		}
+
+		utils.SuccessResponse(c, "Login successful", loginResponse)
+	}
+}
+
+// createTwoFactorChallenge stores a short-lived challenge that stands in for the
+// access token until the second factor is verified.
+func createTwoFactorChallenge(app *app.Application, userId int64) (*models.TwoFactorChallengeSerializer, error) {
+	challenge, err := utils.GenerateOpaqueToken()
+	if err != nil {
+		return nil, err
+	}
+
+	challengeKey := utils.ConstructRedisTwoFactorChallengeKey(utils.HashToken(challenge))
+	if err := app.Redis.Set(challengeKey, userId, twoFactorChallengeDuration); err != nil {
+		return nil, err
+	}
+
+	return &models.TwoFactorChallengeSerializer{
+		TwoFactorRequired: true,
+		ChallengeToken:    challenge,
+	}, nil
+}
+
+// verifySecondFactor accepts either a current TOTP code or an unused recovery code.
+func verifySecondFactor(app *app.Application, userId int64, code string) (bool, error) {
+	encryptedSecret, err := app.Models.Users.GetTOTPSecret(userId)
+	if err != nil {
+		return false, err
+	}
+	if encryptedSecret == "" {
+		return false, nil
+	}
+
+	valid, err := verifyTOTP(app, userId, encryptedSecret, code)
+	if err != nil || valid {
+		return valid, err
+	}
+
+	return app.Models.RecoveryCodes.Consume(userId, utils.HashToken(normalizeRecoveryCode(code)))
+}
+
+// verifyTOTP checks a code against the secret and rejects a time step that was already used.
+func verifyTOTP(app *app.Application, userId int64, encryptedSecret, code string) (bool, error) {
+	secret, err := utils.Decrypt(encryptedSecret)
+	if err != nil {
+		return false, err
+	}
+
+	step, ok := utils.ValidateTOTPCode(secret, code, time.Now())
+	if !ok {
+		return false, nil
+	}
+
+	return app.Redis.UseTOTPStep(userId, step, 5*time.Minute)
+}
+
+// generateRecoveryCodes replaces the user's recovery codes and returns the new plain codes.
+func generateRecoveryCodes(app *app.Application, userId int64) ([]string, error) {
+	codes := make([]string, 0, recoveryCodeCount)
+	hashes := make([]string, 0, recoveryCodeCount)
+
+	for range recoveryCodeCount {
+		bytes := make([]byte, 7)
+		if _, err := rand.Read(bytes); err != nil {
+			return nil, err
+		}
+
+		raw := strings.ToLower(base32.StdEncoding.EncodeToString(bytes))
+		code := raw[:5] + "-" + raw[5:10]
+		codes = append(codes, code)
+		hashes = append(hashes, utils.HashToken(normalizeRecoveryCode(code)))
+	}
+
+	if err := app.Models.RecoveryCodes.ReplaceForUser(userId, hashes); err != nil {
+		return nil, err
+	}
+
+	return codes, nil
+}
+
+func normalizeRecoveryCode(code string) string {
+	code = strings.ToLower(strings.TrimSpace(code))
+	return strings.ReplaceAll(code, "-", "")
+}
@@ -1,35 +1,46 @@
 package services
 
 import (
+	"log"
+	"math"
 	"net/http"
 	"strconv"
 
 	"github.com/gin-gonic/gin"
 	"github.com/vickon16/go-gin-rest-api/cmd/api/middlewares"
 	"github.com/vickon16/go-gin-rest-api/internal/app"
+	"github.com/vickon16/go-gin-rest-api/internal/authz"
 	"github.com/vickon16/go-gin-rest-api/internal/database/models"
 	"github.com/vickon16/go-gin-rest-api/internal/utils"
 )
 
 func GetAllUsers(app *app.Application) gin.HandlerFunc {
 	return func(c *gin.Context) {
-
-		allUsers, err := app.Models.Users.GetAll()
-		if err != nil {
-			utils.ErrorResponse(c, "Failed to get users", http.StatusInternalServerError)
+		options, ok := parseListQuery(c, listQuerySpec{SortColumns: models.UserSortColumns, DefaultSort: "createdAt"})
+		if !ok {
 			return
 		}
-		if allUsers == nil {
-			utils.ErrorResponse(c, "Users not found", http.StatusNotFound)
+		filters, ok := userFiltersFromQuery(c)
+		if !ok {
+			return
+		}
+
+		viewer := viewerFromContext(c)
+
+		// Roles are not part of the public profile
+		if filters.Role != "" && viewer.Role != models.RoleAdmin {
+			utils.ErrorResponse(c, "Only admins can filter users by role", http.StatusForbidden)
 			return
 		}
 
-		var serializedUsers []models.UserSerializer
-		for _, user := range allUsers {
-			serializedUsers = append(serializedUsers, models.CreateResponseUser(user))
+		allUsers, total, err := app.Models.Users.GetAll(filters, options)
+		if err != nil {
+			log.Printf("Error getting users: %v", err)
+			utils.ErrorResponse(c, "Failed to get users", http.StatusInternalServerError)
+			return
 		}
 
-		utils.SuccessResponse(c, "Successfully retrieved users", serializedUsers)
+		utils.PaginatedResponse(c, "Successfully retrieved users", serializeUsersFor(allUsers, viewer), utils.NewPagination(options.Page, options.Limit, total))
 	}
 }
 
@@ -51,7 +62,25 @@ func GetUser(app *app.Application) gin.HandlerFunc {
 			return
 		}
 
-		utils.SuccessResponse(c, "Successfully retrieved user", models.CreateResponseUser(user))
+		organizedEvents, err := app.Models.Events.GetByOrganizer(user.ID)
+		if err != nil {
+			log.Printf("Error getting events organized by user %d: %v", user.ID, err)
+			utils.ErrorResponse(c, "Failed to get user", http.StatusInternalServerError)
+			return
+		}
+		if err := loadAttendeeCounts(app, organizedEvents...); err != nil {
+			log.Printf("Error counting attendees: %v", err)
+			utils.ErrorResponse(c, "Failed to get user", http.StatusInternalServerError)
+			return
+		}
+
+		viewer := viewerFromContext(c)
+		response := models.CreateResponseUserFor(user, viewer)
+		for _, event := range organizedEvents {
+			response.OrganizedEvents = append(response.OrganizedEvents, models.CreateResponseEvent(event, viewer))
+		}
+
+		utils.SuccessResponse(c, "Successfully retrieved user", response)
 	}
 }
 
@@ -92,22 +121,130 @@ func UpdateUser(app *app.Application) gin.HandlerFunc {
 			return
 		}
 
+		if !app.Authz.Authorize(c, authz.ActionUpdateUser, authz.UserResource(existingUser)) {
+			return
+		}
+
 		var updatedUser models.UpdateUserDto
 		if err := c.ShouldBindJSON(&updatedUser); err != nil {
 			utils.ErrorResponse(c, err.Error(), http.StatusBadRequest)
 			return
 		}
 
-		user, err := app.Models.Users.Update(id, &updatedUser)
+		// The email is where password resets go, so changing it is as sensitive as changing the password
+		changingEmail := updatedUser.Email != "" && updatedUser.Email != existingUser.Email
+
+		if _, impersonating := middlewares.GetActorFromContext(c); impersonating {
+			if updatedUser.Password != "" {
+				utils.ErrorResponse(c, "Passwords cannot be changed while impersonating", http.StatusForbidden)
+				return
+			}
+			if changingEmail {
+				utils.ErrorResponse(c, "Emails cannot be changed while impersonating", http.StatusForbidden)
+				return
+			}
+		}
+
+		if _, isAPIKey := middlewares.GetAPIKeyScopesFromContext(c); isAPIKey && (updatedUser.Password != "" || changingEmail) {
+			utils.ErrorResponse(c, "Passwords and emails cannot be changed with an API key", http.StatusForbidden)
+			return
+		}
+
+		if updatedUser.Password != "" || changingEmail {
+			if updatedUser.CurrentPassword == "" {
+				utils.ErrorResponse(c, "Current password is required to change the password or email", http.StatusBadRequest)
+				return
+			}
+			if !checkCurrentPassword(app, c, existingUser, updatedUser.CurrentPassword) {
+				return
+			}
+		}
+
+		if updatedUser.Password != "" {
+			if !validateNewPassword(app, c, updatedUser.Password, existingUser.Name, existingUser.Email, updatedUser.Name, updatedUser.Email) {
+				return
+			}
+
+			hashedPassword, err := utils.HashPassword(updatedUser.Password)
+			if err != nil {
+				utils.ErrorResponse(c, "Something went wrong", http.StatusInternalServerError)
+				return
+			}
+
+			if err := app.Models.Users.UpdatePassword(id, hashedPassword); err != nil {
+				utils.ErrorResponse(c, "Failed to update user", http.StatusInternalServerError)
+				return
+			}
+		}
+
+		user := existingUser
+		if updatedUser.HasProfileChanges() {
+			user, err = app.Models.Users.Update(id, &updatedUser)
+			if err != nil {
+				utils.ErrorResponse(c, "Failed to update user", http.StatusInternalServerError)
+				return
+			}
+		}
+
+		cacheKey := utils.ConstructRedisUserKey(id)
+		app.Redis.Delete(cacheKey)
+
+		// A new password must log the user out everywhere
+		if updatedUser.Password != "" {
+			if err := revokeAllSessions(app, id); err != nil {
+				log.Printf("Failed to revoke sessions for user %d: %v", id, err)
+			}
+		}
+
+		if changingEmail {
+			if err := sendVerificationEmail(app, user); err != nil {
+				log.Printf("Failed to send verification email: %v", err)
+			}
+		}
+
+		utils.SuccessResponse(c, "Successfully updated user", models.CreateResponseUserFor(user, viewerFromContext(c)))
+	}
+}
+
+func UpdateUserRole(app *app.Application) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+		if err != nil {
+			utils.ErrorResponse(c, "Invalid user Id", http.StatusBadRequest)
+			return
+		}
+
+		var dto models.UpdateUserRoleDto
+		if err := c.ShouldBindJSON(&dto); err != nil {
+			utils.ErrorResponse(c, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		existingUser, err := app.Models.Users.Get(id)
 		if err != nil {
-			utils.ErrorResponse(c, "Failed to update user", http.StatusInternalServerError)
+			utils.ErrorResponse(c, "Failed to get user", http.StatusInternalServerError)
+			return
+		}
+		if existingUser == nil {
+			utils.ErrorResponse(c, "User not found", http.StatusNotFound)
+			return
+		}
+
+		user, err := app.Models.Users.UpdateRole(id, dto.Role)
+		if err != nil {
+			utils.ErrorResponse(c, "Failed to update user role", http.StatusInternalServerError)
 			return
 		}
 
 		cacheKey := utils.ConstructRedisUserKey(id)
 		app.Redis.Delete(cacheKey)
 
-		utils.SuccessResponse(c, "Successfully updated user", models.CreateResponseUser(user))
+		// Existing tokens still carry the old role
+		if err := revokeAllSessions(app, id); err != nil {
+			log.Printf("Failed to revoke sessions for user %d: %v", id, err)
+		}
+
+		utils.SuccessResponse(c, "Successfully updated user role", models.CreateResponseUserFor(user, viewerFromContext(c)))
 	}
 }
 
@@ -129,14 +266,56 @@ func DeleteUser(app *app.Application) gin.HandlerFunc {
 			return
 		}
 
-		if err := app.Models.Users.Delete(id); err != nil {
-			utils.ErrorResponse(c, "Failed to delete user", http.StatusInternalServerError)
+		if !app.Authz.Authorize(c, authz.ActionDeleteUser, authz.UserResource(user)) {
 			return
 		}
 
-		cacheKey := utils.ConstructRedisUserKey(id)
-		app.Redis.Delete(cacheKey)
+		// Anonymize instead of deleting, so the user's events and attendance survive
+		if err := anonymizeAccount(app, id); err != nil {
+			log.Printf("Failed to anonymize user %d: %v", id, err)
+			utils.ErrorResponse(c, "Failed to delete user", http.StatusInternalServerError)
+			return
+		}
 
 		utils.SuccessResponse(c, "Successfully deleted user", nil)
 	}
 }
+
+// checkCurrentPassword confirms the user's password before a sensitive change,
+// writing the error response when it does not match. Failures count towards the
+// login limits of the user's email, so it cannot be used to guess the password.
+func checkCurrentPassword(app *app.Application, c *gin.Context, user *models.User, password string) bool {
+	clientIP := c.ClientIP()
+
+	remaining, err := loginLockRemaining(app, user.Email, clientIP)
+	if err != nil {
+		log.Printf("Failed to check login lock: %v", err)
+		utils.ErrorResponse(c, "Something went wrong", http.StatusInternalServerError)
+		return false
+	}
+	if remaining > 0 {
+		c.Header("Retry-After", strconv.Itoa(int(math.Ceil(remaining.Seconds()))))
+		utils.ErrorResponse(c, "Too many password attempts, please try again later", http.StatusTooManyRequests)
+		return false
+	}
+
+	withPassword, err := app.Models.Users.GetUserByEmail(user.Email, true)
+	if err != nil || withPassword == nil {
+		utils.ErrorResponse(c, "Failed to get user", http.StatusInternalServerError)
+		return false
+	}
+
+	if !utils.CheckPasswordHash(withPassword.Password, password) {
+		if err := recordLoginFailure(app, user.Email, clientIP); err != nil {
+			log.Printf("Failed to record login failure: %v", err)
+		}
+		utils.ErrorResponse(c, "Current password is incorrect", http.StatusForbidden)
+		return false
+	}
+
+	if err := resetLoginFailures(app, user.Email); err != nil {
+		log.Printf("Failed to reset login failures: %v", err)
+	}
+
+	return true
+}
@@ -0,0 +1,95 @@
+package services
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/vickon16/go-gin-rest-api/cmd/api/middlewares"
+	"github.com/vickon16/go-gin-rest-api/internal/app"
+	"github.com/vickon16/go-gin-rest-api/internal/database/models"
+	"github.com/vickon16/go-gin-rest-api/internal/env"
+	"github.com/vickon16/go-gin-rest-api/internal/mailer"
+	"github.com/vickon16/go-gin-rest-api/internal/utils"
+)
+
+func VerifyEmail(app *app.Application) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var dto models.VerifyEmailDto
+
+		if err := c.ShouldBindJSON(&dto); err != nil {
+			utils.ErrorResponse(c, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		token, err := app.Models.OneTimeTokens.Consume(utils.HashToken(dto.Token), models.TokenPurposeEmailVerification)
+		if err != nil {
+			utils.ErrorResponse(c, "Failed to verify email", http.StatusInternalServerError)
+			return
+		}
+		if token == nil {
+			utils.ErrorResponse(c, "Invalid or expired verification token", http.StatusBadRequest)
+			return
+		}
+
+		if err := app.Models.Users.MarkEmailVerified(token.UserID); err != nil {
+			log.Printf("Failed to mark email as verified: %v", err)
+			utils.ErrorResponse(c, "Failed to verify email", http.StatusInternalServerError)
+			return
+		}
+
+		cacheKey := utils.ConstructRedisUserKey(token.UserID)
+		app.Redis.Delete(cacheKey)
+
+		utils.SuccessResponse(c, "Email verified successfully", nil)
+	}
+}
+
+func ResendVerification(app *app.Application) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		contextUser := middlewares.GetUserFromContext(c)
+
+		user, err := app.Models.Users.Get(contextUser.ID)
+		if err != nil {
+			utils.ErrorResponse(c, "Failed to get user", http.StatusInternalServerError)
+			return
+		}
+		if user == nil {
+			utils.ErrorResponse(c, "User not found", http.StatusNotFound)
+			return
+		}
+		if user.EmailVerifiedAt != nil {
+			utils.ErrorResponse(c, "Email is already verified", http.StatusConflict)
+			return
+		}
+
+		if err := sendVerificationEmail(app, user); err != nil {
+			log.Printf("Failed to send verification email: %v", err)
+			utils.ErrorResponse(c, "Failed to send verification email", http.StatusInternalServerError)
+			return
+		}
+
+		utils.SuccessResponse(c, "Verification email sent", nil)
+	}
+}
+
+func sendVerificationEmail(app *app.Application, user *models.User) error {
+	expirationHours := env.GetEnvInt("EMAIL_VERIFICATION_EXPIRATION_HOURS", 24)
+	token, err := IssueOneTimeToken(app, user.ID, models.TokenPurposeEmailVerification, time.Duration(expirationHours)*time.Hour)
+	if err != nil {
+		return err
+	}
+
+	verifyUrl := env.GetEnvString("APP_URL", "http://localhost:3000") + "/verify-email?token=" + url.QueryEscape(token)
+	return app.Mailer.Send(mailer.Message{
+		To:      user.Email,
+		Subject: "Verify your email address",
+		Body: fmt.Sprintf(
+			"Hi %s,\n\nPlease confirm your email address using the link below. It expires in %d hours.\n\n%s\n",
+			user.Name, expirationHours, verifyUrl,
+		),
+	})
+}
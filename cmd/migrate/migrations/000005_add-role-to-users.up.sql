@@ -0,0 +1,4 @@
+ALTER TABLE users ADD COLUMN IF NOT EXISTS role TEXT NOT NULL DEFAULT 'user' CHECK (role IN ('user', 'organizer', 'admin'));
+
+-- Existing event owners keep the ability to manage their events
+UPDATE users SET role = 'organizer' WHERE id IN (SELECT DISTINCT user_id FROM events);
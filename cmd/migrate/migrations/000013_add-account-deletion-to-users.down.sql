@@ -0,0 +1,8 @@
+ALTER TABLE attendees DROP CONSTRAINT IF EXISTS attendees_user_id_fkey;
+ALTER TABLE attendees ADD CONSTRAINT attendees_user_id_fkey FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE;
+
+ALTER TABLE events DROP CONSTRAINT IF EXISTS events_user_id_fkey;
+ALTER TABLE events ADD CONSTRAINT events_user_id_fkey FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE;
+
+ALTER TABLE users DROP COLUMN IF EXISTS deleted_at;
+ALTER TABLE users DROP COLUMN IF EXISTS deletion_requested_at;
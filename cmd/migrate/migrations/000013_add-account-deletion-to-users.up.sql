@@ -0,0 +1,9 @@
+ALTER TABLE users ADD COLUMN IF NOT EXISTS deletion_requested_at TIMESTAMP;
+ALTER TABLE users ADD COLUMN IF NOT EXISTS deleted_at TIMESTAMP;
+
+-- Accounts are anonymized, never deleted, so removing a user must not take their events and attendance with it
+ALTER TABLE events DROP CONSTRAINT IF EXISTS events_user_id_fkey;
+ALTER TABLE events ADD CONSTRAINT events_user_id_fkey FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE RESTRICT;
+
+ALTER TABLE attendees DROP CONSTRAINT IF EXISTS attendees_user_id_fkey;
+ALTER TABLE attendees ADD CONSTRAINT attendees_user_id_fkey FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE RESTRICT;
@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS idx_events_user_id_date;
+DROP TABLE IF EXISTS follows;
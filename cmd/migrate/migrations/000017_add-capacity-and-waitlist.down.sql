@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS idx_attendees_event_id_waitlisted_at;
+ALTER TABLE attendees DROP COLUMN IF EXISTS waitlisted_at;
+ALTER TABLE events DROP COLUMN IF EXISTS capacity;
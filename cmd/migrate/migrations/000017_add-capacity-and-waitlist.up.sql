@@ -0,0 +1,8 @@
+-- NULL means the number of attendees is not limited
+ALTER TABLE events ADD COLUMN IF NOT EXISTS capacity INTEGER CHECK (capacity > 0);
+
+-- Attendees registered while the event was full wait here until a seat frees up,
+-- in order of waitlisted_at. NULL means the attendee has a seat.
+ALTER TABLE attendees ADD COLUMN IF NOT EXISTS waitlisted_at TIMESTAMP;
+
+CREATE INDEX IF NOT EXISTS idx_attendees_event_id_waitlisted_at ON attendees (event_id, waitlisted_at, id);
@@ -0,0 +1,22 @@
+-- Without a status every attendee reads as seated or waitlisted. Rather than delete
+-- the others or hand them seats that could overbook the event, refuse to go back.
+DO $$
+BEGIN
+  IF EXISTS (SELECT 1 FROM attendees WHERE status NOT IN ('going', 'waitlisted')) THEN
+    RAISE EXCEPTION 'attendees with statuses other than going and waitlisted exist, remove or resolve them before rolling back';
+  END IF;
+END $$;
+
+DROP INDEX IF EXISTS idx_attendees_event_id_status;
+
+-- Before statuses, waitlisted_at was only set while the attendee waited for a seat
+UPDATE attendees SET waitlisted_at = NULL WHERE status <> 'waitlisted';
+
+ALTER TABLE attendees DROP COLUMN IF EXISTS cancelled_at;
+ALTER TABLE attendees DROP COLUMN IF EXISTS invited_at;
+ALTER TABLE attendees DROP COLUMN IF EXISTS declined_at;
+ALTER TABLE attendees DROP COLUMN IF EXISTS maybe_at;
+ALTER TABLE attendees DROP COLUMN IF EXISTS going_at;
+ALTER TABLE attendees DROP COLUMN IF EXISTS status;
+
+CREATE INDEX IF NOT EXISTS idx_attendees_event_id_waitlisted_at ON attendees (event_id, waitlisted_at, id);
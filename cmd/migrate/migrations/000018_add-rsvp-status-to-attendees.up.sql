@@ -0,0 +1,16 @@
+ALTER TABLE attendees ADD COLUMN IF NOT EXISTS status VARCHAR(20) NOT NULL DEFAULT 'going'
+  CHECK (status IN ('going', 'maybe', 'declined', 'invited', 'waitlisted', 'cancelled'));
+
+-- When the attendee last entered each status, waitlisted_at already exists
+ALTER TABLE attendees ADD COLUMN IF NOT EXISTS going_at TIMESTAMP;
+ALTER TABLE attendees ADD COLUMN IF NOT EXISTS maybe_at TIMESTAMP;
+ALTER TABLE attendees ADD COLUMN IF NOT EXISTS declined_at TIMESTAMP;
+ALTER TABLE attendees ADD COLUMN IF NOT EXISTS invited_at TIMESTAMP;
+ALTER TABLE attendees ADD COLUMN IF NOT EXISTS cancelled_at TIMESTAMP;
+
+UPDATE attendees SET status = 'waitlisted' WHERE waitlisted_at IS NOT NULL;
+UPDATE attendees SET going_at = created_at WHERE waitlisted_at IS NULL;
+
+-- Per-status counts and filters, and the waitlist in order
+DROP INDEX IF EXISTS idx_attendees_event_id_waitlisted_at;
+CREATE INDEX IF NOT EXISTS idx_attendees_event_id_status ON attendees (event_id, status, waitlisted_at, id);
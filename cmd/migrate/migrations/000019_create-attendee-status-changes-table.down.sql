@@ -0,0 +1 @@
+DROP TABLE IF EXISTS attendee_status_changes;
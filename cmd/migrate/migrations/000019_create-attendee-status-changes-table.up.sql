@@ -0,0 +1,27 @@
+-- Every status an attendee entered, in order. The *_at columns of attendees only keep the last time.
+CREATE TABLE IF NOT EXISTS attendee_status_changes (
+  id SERIAL PRIMARY KEY,
+  attendee_id INTEGER NOT NULL REFERENCES attendees(id) ON DELETE CASCADE,
+  -- NULL for the status the attendee registered with
+  from_status VARCHAR(20),
+  to_status VARCHAR(20) NOT NULL,
+  changed_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_attendee_status_changes_attendee_id ON attendee_status_changes (attendee_id, changed_at, id);
+
+-- Earlier changes are lost, the current status is the best known history
+INSERT INTO attendee_status_changes (attendee_id, to_status, changed_at)
+SELECT id, status, COALESCE(
+  CASE status
+    WHEN 'going' THEN going_at
+    WHEN 'maybe' THEN maybe_at
+    WHEN 'declined' THEN declined_at
+    WHEN 'invited' THEN invited_at
+    WHEN 'waitlisted' THEN waitlisted_at
+    WHEN 'cancelled' THEN cancelled_at
+  END,
+  created_at,
+  CURRENT_TIMESTAMP
+)
+FROM attendees;
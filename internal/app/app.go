@@ -1,12 +1,27 @@
 package app
 
 import (
+	"github.com/vickon16/go-gin-rest-api/internal/authz"
 	"github.com/vickon16/go-gin-rest-api/internal/database/models"
+	"github.com/vickon16/go-gin-rest-api/internal/mailer"
+	"github.com/vickon16/go-gin-rest-api/internal/oidc"
+	"github.com/vickon16/go-gin-rest-api/internal/passwords"
 	"github.com/vickon16/go-gin-rest-api/internal/redisDb"
+	"github.com/vickon16/go-gin-rest-api/internal/search"
+	"github.com/vickon16/go-gin-rest-api/internal/storage"
 )
 
 type Application struct {
 	Port   int
 	Models models.Models
 	Redis  *redisDb.RedisClient
+	Authz  *authz.Authorizer
+	Mailer mailer.Mailer
+	OIDC   *oidc.Providers
+	// Rules for new passwords
+	Passwords *passwords.Policy
+	// Uploaded files such as avatars
+	Storage storage.Storage
+	// Full-text search over events
+	Search search.Backend
 }
@@ -0,0 +1,75 @@
+package authz
+
+import (
+	"log"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/vickon16/go-gin-rest-api/internal/database/models"
+	"github.com/vickon16/go-gin-rest-api/internal/utils"
+)
+
+type Authorizer struct {
+	models models.Models
+}
+
+func NewAuthorizer(models models.Models) *Authorizer {
+	return &Authorizer{models: models}
+}
+
+// Can reports whether the user may perform the action on the resource.
+func (a *Authorizer) Can(subject *models.UserSerializer, action Action, resource Resource) bool {
+	policy, ok := policies[action]
+	if !ok {
+		return false
+	}
+
+	return policy(a, subject, resource)
+}
+
+// Authorize checks the action for the authenticated user. On denial it writes
+// the 403 response, records an audit entry and returns false.
+func (a *Authorizer) Authorize(ctx *gin.Context, action Action, resource Resource) bool {
+	if a.Can(subjectFromContext(ctx), action, resource) {
+		return true
+	}
+
+	a.Deny(ctx, action, resource)
+	return false
+}
+
+// Deny aborts the request with a uniform 403 and records the denial.
+func (a *Authorizer) Deny(ctx *gin.Context, action Action, resource Resource) {
+	subject := subjectFromContext(ctx)
+
+	entry := models.AuditLog{
+		Action:       string(action),
+		ResourceType: resource.Type,
+		Outcome:      models.AuditOutcomeDenied,
+		Method:       ctx.Request.Method,
+		Path:         ctx.FullPath(),
+		IPAddress:    ctx.ClientIP(),
+	}
+	if subject.ID != 0 {
+		entry.ActorID = &subject.ID
+	}
+	if resource.ID != 0 {
+		entry.ResourceID = &resource.ID
+	}
+
+	if err := a.models.AuditLogs.Insert(&entry); err != nil {
+		log.Printf("Failed to write audit log: %v", err)
+	}
+
+	utils.ErrorResponse(ctx, "You are not authorized to perform this action", http.StatusForbidden)
+	ctx.Abort()
+}
+
+// subjectFromContext returns the user stored by middlewares.AuthMiddleware.
+func subjectFromContext(ctx *gin.Context) *models.UserSerializer {
+	if user, ok := ctx.Value("user").(*models.UserSerializer); ok {
+		return user
+	}
+
+	return &models.UserSerializer{}
+}
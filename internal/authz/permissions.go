@@ -0,0 +1,64 @@
+package authz
+
+import (
+	"slices"
+
+	"github.com/vickon16/go-gin-rest-api/internal/database/models"
+)
+
+type Permission string
+
+const (
+	PermissionReadUsers     Permission = "users:read"
+	PermissionUpdateUsers   Permission = "users:update"
+	PermissionDeleteUsers   Permission = "users:delete"
+	PermissionModerateUsers Permission = "users:moderate"
+
+	PermissionReadEvents     Permission = "events:read"
+	PermissionCreateEvents   Permission = "events:create"
+	PermissionUpdateEvents   Permission = "events:update"
+	PermissionDeleteEvents   Permission = "events:delete"
+	PermissionModerateEvents Permission = "events:moderate"
+
+	PermissionReadAttendees     Permission = "attendees:read"
+	PermissionCreateAttendees   Permission = "attendees:create"
+	PermissionUpdateAttendees   Permission = "attendees:update"
+	PermissionDeleteAttendees   Permission = "attendees:delete"
+	PermissionModerateAttendees Permission = "attendees:moderate"
+)
+
+// Permissions granted to every authenticated user.
+// Update and delete permissions only cover the user's own resources,
+// acting on someone else's requires the matching moderate permission.
+var userPermissions = []Permission{
+	PermissionReadUsers, PermissionUpdateUsers, PermissionDeleteUsers,
+	PermissionReadEvents, PermissionUpdateEvents,
+	PermissionReadAttendees, PermissionCreateAttendees, PermissionUpdateAttendees, PermissionDeleteAttendees,
+}
+
+var rolePermissions = map[string][]Permission{
+	models.RoleUser: userPermissions,
+	models.RoleOrganizer: append(slices.Clone(userPermissions),
+		PermissionCreateEvents, PermissionDeleteEvents,
+	),
+	models.RoleAdmin: append(slices.Clone(userPermissions),
+		PermissionModerateUsers,
+		PermissionCreateEvents, PermissionDeleteEvents, PermissionModerateEvents,
+		PermissionModerateAttendees,
+	),
+}
+
+// HasPermission reports whether the role grants the permission.
+func HasPermission(role string, permission Permission) bool {
+	return slices.Contains(rolePermissions[role], permission)
+}
+
+// IsPermission reports whether the value names a known permission.
+func IsPermission(value string) bool {
+	for _, permissions := range rolePermissions {
+		if slices.Contains(permissions, Permission(value)) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,105 @@
+package authz
+
+import (
+	"log"
+
+	"github.com/vickon16/go-gin-rest-api/internal/database/models"
+)
+
+type Action string
+
+const (
+	ActionUpdateUser Action = "user:update"
+	ActionDeleteUser Action = "user:delete"
+
+	ActionUpdateEvent           Action = "event:update"
+	ActionDeleteEvent           Action = "event:delete"
+	ActionManageEventAttendees  Action = "event:manage-attendees"
+	ActionManageEventOrganizers Action = "event:manage-organizers"
+
+	ActionCreateAttendee Action = "attendee:create"
+	ActionUpdateAttendee Action = "attendee:update"
+	ActionDeleteAttendee Action = "attendee:delete"
+)
+
+// Resource describes the record an action is performed on.
+type Resource struct {
+	Type         string
+	ID           int64
+	OwnerID      int64 // user the resource belongs to
+	EventID      int64 // event the resource belongs to, if any
+	EventOwnerID int64
+}
+
+func UserResource(user *models.User) Resource {
+	return Resource{Type: "user", ID: user.ID, OwnerID: user.ID}
+}
+
+func EventResource(event *models.Event) Resource {
+	return Resource{Type: "event", ID: event.ID, OwnerID: event.UserID, EventID: event.ID, EventOwnerID: event.UserID}
+}
+
+func AttendeeResource(attendee *models.Attendee, event *models.Event) Resource {
+	return Resource{Type: "attendee", ID: attendee.ID, OwnerID: attendee.UserID, EventID: event.ID, EventOwnerID: event.UserID}
+}
+
+type rule func(a *Authorizer, subject *models.UserSerializer, resource Resource) bool
+
+// policies declares who may perform each action. An action without a policy is always denied.
+var policies = map[Action]rule{
+	ActionUpdateUser: anyOf(isOwner, can(PermissionModerateUsers)),
+	ActionDeleteUser: anyOf(isOwner, can(PermissionModerateUsers)),
+
+	ActionUpdateEvent:           anyOf(isEventOrganizer, can(PermissionModerateEvents)),
+	ActionDeleteEvent:           anyOf(isEventOwner, can(PermissionModerateEvents)),
+	ActionManageEventAttendees:  anyOf(isEventOrganizer, can(PermissionModerateEvents)),
+	ActionManageEventOrganizers: anyOf(isEventOwner, can(PermissionModerateEvents)),
+
+	// Users manage their own attendance, organizers manage attendance to their events
+	ActionCreateAttendee: anyOf(isOwner, isEventOrganizer, can(PermissionModerateAttendees)),
+	ActionUpdateAttendee: anyOf(isOwner, isEventOrganizer, can(PermissionModerateAttendees)),
+	ActionDeleteAttendee: anyOf(isOwner, isEventOrganizer, can(PermissionModerateAttendees)),
+}
+
+func anyOf(rules ...rule) rule {
+	return func(a *Authorizer, subject *models.UserSerializer, resource Resource) bool {
+		for _, r := range rules {
+			if r(a, subject, resource) {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+func can(permission Permission) rule {
+	return func(a *Authorizer, subject *models.UserSerializer, resource Resource) bool {
+		return HasPermission(subject.Role, permission)
+	}
+}
+
+func isOwner(a *Authorizer, subject *models.UserSerializer, resource Resource) bool {
+	return resource.OwnerID != 0 && resource.OwnerID == subject.ID
+}
+
+func isEventOwner(a *Authorizer, subject *models.UserSerializer, resource Resource) bool {
+	return resource.EventOwnerID != 0 && resource.EventOwnerID == subject.ID
+}
+
+// isEventOrganizer matches the event owner and its co-organizers.
+func isEventOrganizer(a *Authorizer, subject *models.UserSerializer, resource Resource) bool {
+	if isEventOwner(a, subject, resource) {
+		return true
+	}
+	if resource.EventID == 0 {
+		return false
+	}
+
+	isCoOrganizer, err := a.models.Events.IsCoOrganizer(resource.EventID, subject.ID)
+	if err != nil {
+		log.Printf("Error checking co-organizer for event %d: %v", resource.EventID, err)
+		return false
+	}
+
+	return isCoOrganizer
+}
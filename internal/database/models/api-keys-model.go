@@ -0,0 +1,59 @@
+package models
+
+import (
+	"database/sql"
+	"time"
+)
+
+type APIKeyModel struct {
+	DB *sql.DB
+}
+
+type APIKey struct {
+	ID         int64      `db:"id" json:"id"`
+	UserID     int64      `db:"user_id" json:"userId"`
+	Name       string     `db:"name" json:"name"`
+	Prefix     string     `db:"prefix" json:"prefix"`
+	KeyHash    string     `db:"key_hash" json:"-"`
+	Scopes     []string   `db:"scopes" json:"scopes"`
+	ExpiresAt  *time.Time `db:"expires_at" json:"expiresAt,omitempty"`
+	LastUsedAt *time.Time `db:"last_used_at" json:"lastUsedAt,omitempty"`
+	RevokedAt  *time.Time `db:"revoked_at" json:"revokedAt,omitempty"`
+	BaseModel
+}
+
+type CreateAPIKeyDto struct {
+	Name          string   `json:"name" binding:"required,min=3,max=100"`
+	Scopes        []string `json:"scopes" binding:"required,min=1,dive,required"`
+	ExpiresInDays int      `json:"expiresInDays" binding:"omitempty,min=1,max=365"`
+}
+
+type UpdateAPIKeyDto struct {
+	Name   string   `json:"name,omitempty" binding:"omitempty,min=3,max=100"`
+	Scopes []string `json:"scopes,omitempty" binding:"omitempty,min=1,dive,required"`
+}
+
+type APIKeySerializer struct {
+	ID         int64      `json:"id"`
+	Name       string     `json:"name"`
+	Prefix     string     `json:"prefix"`
+	Scopes     []string   `json:"scopes"`
+	ExpiresAt  *time.Time `json:"expiresAt,omitempty"`
+	LastUsedAt *time.Time `json:"lastUsedAt,omitempty"`
+	BaseModel
+
+	// Only set once, in the create response
+	Key string `json:"key,omitempty"`
+}
+
+func CreateResponseAPIKey(apiKey *APIKey) APIKeySerializer {
+	return APIKeySerializer{
+		ID:         apiKey.ID,
+		Name:       apiKey.Name,
+		Prefix:     apiKey.Prefix,
+		Scopes:     apiKey.Scopes,
+		ExpiresAt:  apiKey.ExpiresAt,
+		LastUsedAt: apiKey.LastUsedAt,
+		BaseModel:  BaseModel{CreatedAt: apiKey.CreatedAt, UpdatedAt: apiKey.UpdatedAt},
+	}
+}
@@ -0,0 +1,207 @@
+package models
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	sq "github.com/Masterminds/squirrel"
+	"github.com/lib/pq"
+	"github.com/vickon16/go-gin-rest-api/internal/utils"
+)
+
+var apiKeyColumns = []string{"id", "user_id", "name", "prefix", "key_hash", "scopes", "expires_at", "last_used_at", "revoked_at", "created_at", "updated_at"}
+
+func apiKeyScanFields(apiKey *APIKey) []any {
+	return []any{
+		&apiKey.ID, &apiKey.UserID, &apiKey.Name, &apiKey.Prefix, &apiKey.KeyHash, pq.Array(&apiKey.Scopes),
+		&apiKey.ExpiresAt, &apiKey.LastUsedAt, &apiKey.RevokedAt, &apiKey.CreatedAt, &apiKey.UpdatedAt,
+	}
+}
+
+func (m *APIKeyModel) Insert(apiKey *APIKey) error {
+	ctx, cancel := utils.CreateContext()
+	defer cancel()
+
+	query := sq.Insert("api_keys").
+		Columns("user_id", "name", "prefix", "key_hash", "scopes", "expires_at").
+		Values(apiKey.UserID, apiKey.Name, apiKey.Prefix, apiKey.KeyHash, pq.Array(apiKey.Scopes), apiKey.ExpiresAt).
+		Suffix("RETURNING id, created_at, updated_at").
+		PlaceholderFormat(sq.Dollar)
+
+	sqlStr, args, err := query.ToSql()
+	if err != nil {
+		return err
+	}
+
+	return m.DB.QueryRowContext(ctx, sqlStr, args...).Scan(&apiKey.ID, &apiKey.CreatedAt, &apiKey.UpdatedAt)
+}
+
+// GetActiveByUser lists the keys of a user that are not revoked.
+func (m *APIKeyModel) GetActiveByUser(userId int64) ([]*APIKey, error) {
+	ctx, cancel := utils.CreateContext()
+	defer cancel()
+
+	query := sq.Select(apiKeyColumns...).
+		From("api_keys").
+		Where(sq.Eq{"user_id": userId, "revoked_at": nil}).
+		OrderBy("created_at DESC").
+		PlaceholderFormat(sq.Dollar)
+
+	sqlStr, args, err := query.ToSql()
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := m.DB.QueryContext(ctx, sqlStr, args...)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	var apiKeys []*APIKey
+
+	for rows.Next() {
+		var apiKey APIKey
+
+		if err := rows.Scan(apiKeyScanFields(&apiKey)...); err != nil {
+			return nil, err
+		}
+
+		apiKeys = append(apiKeys, &apiKey)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return apiKeys, nil
+}
+
+func (m *APIKeyModel) Get(id, userId int64) (*APIKey, error) {
+	return m.getOne(sq.Eq{"id": id, "user_id": userId, "revoked_at": nil})
+}
+
+func (m *APIKeyModel) GetByPrefix(prefix string) (*APIKey, error) {
+	return m.getOne(sq.Eq{"prefix": prefix})
+}
+
+func (m *APIKeyModel) getOne(where sq.Eq) (*APIKey, error) {
+	ctx, cancel := utils.CreateContext()
+	defer cancel()
+
+	query := sq.Select(apiKeyColumns...).
+		From("api_keys").
+		Where(where).
+		PlaceholderFormat(sq.Dollar)
+
+	sqlStr, args, err := query.ToSql()
+	if err != nil {
+		return nil, err
+	}
+
+	var apiKey APIKey
+	err = m.DB.QueryRowContext(ctx, sqlStr, args...).Scan(apiKeyScanFields(&apiKey)...)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+
+		// For other errors
+		return nil, err
+	}
+
+	return &apiKey, nil
+}
+
+func (m *APIKeyModel) Update(id, userId int64, apiKey *UpdateAPIKeyDto) (*APIKey, error) {
+	ctx, cancel := utils.CreateContext()
+	defer cancel()
+
+	if apiKey.Name == "" && len(apiKey.Scopes) == 0 {
+		return nil, fmt.Errorf("no fields to update")
+	}
+
+	query := sq.Update("api_keys").PlaceholderFormat(sq.Dollar)
+
+	if apiKey.Name != "" {
+		query = query.Set("name", apiKey.Name)
+	}
+	if len(apiKey.Scopes) > 0 {
+		query = query.Set("scopes", pq.Array(apiKey.Scopes))
+	}
+
+	query = query.Set("updated_at", time.Now()).
+		Where(sq.Eq{"id": id, "user_id": userId, "revoked_at": nil}).
+		Suffix("RETURNING " + strings.Join(apiKeyColumns, ", "))
+
+	sqlStr, args, err := query.ToSql()
+	if err != nil {
+		return nil, err
+	}
+
+	var updated APIKey
+	err = m.DB.QueryRowContext(ctx, sqlStr, args...).Scan(apiKeyScanFields(&updated)...)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+
+		// For other errors
+		return nil, err
+	}
+
+	return &updated, nil
+}
+
+// TouchLastUsed records a use of the key, at most once a minute to keep writes cheap.
+func (m *APIKeyModel) TouchLastUsed(id int64) error {
+	ctx, cancel := utils.CreateContext()
+	defer cancel()
+
+	now := time.Now()
+	query := sq.Update("api_keys").
+		Set("last_used_at", now).
+		Where(sq.Eq{"id": id}).
+		Where(sq.Or{sq.Eq{"last_used_at": nil}, sq.Lt{"last_used_at": now.Add(-time.Minute)}}).
+		PlaceholderFormat(sq.Dollar)
+
+	sqlStr, args, err := query.ToSql()
+	if err != nil {
+		return err
+	}
+
+	_, err = m.DB.ExecContext(ctx, sqlStr, args...)
+	return err
+}
+
+func (m *APIKeyModel) Revoke(id, userId int64) (bool, error) {
+	ctx, cancel := utils.CreateContext()
+	defer cancel()
+
+	query := sq.Update("api_keys").
+		Set("revoked_at", time.Now()).
+		Set("updated_at", time.Now()).
+		Where(sq.Eq{"id": id, "user_id": userId, "revoked_at": nil}).
+		PlaceholderFormat(sq.Dollar)
+
+	sqlStr, args, err := query.ToSql()
+	if err != nil {
+		return false, err
+	}
+
+	result, err := m.DB.ExecContext(ctx, sqlStr, args...)
+	if err != nil {
+		return false, err
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+
+	return affected > 0, nil
+}
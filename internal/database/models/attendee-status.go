@@ -0,0 +1,103 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+)
+
+// RSVP statuses of an attendee. Only going attendees take up a seat of the event.
+const (
+	AttendeeStatusGoing      = "going"
+	AttendeeStatusMaybe      = "maybe"
+	AttendeeStatusDeclined   = "declined"
+	AttendeeStatusInvited    = "invited"
+	AttendeeStatusWaitlisted = "waitlisted"
+	AttendeeStatusCancelled  = "cancelled"
+)
+
+// AttendeeStatuses lists every status, in the order counts are reported.
+var AttendeeStatuses = []string{
+	AttendeeStatusGoing, AttendeeStatusMaybe, AttendeeStatusDeclined,
+	AttendeeStatusInvited, AttendeeStatusWaitlisted, AttendeeStatusCancelled,
+}
+
+var ErrInvalidStatusTransition = errors.New("invalid status transition")
+
+// StatusTransitionError is the ErrInvalidStatusTransition of one attendee, with
+// the status the attendee had when the change was refused.
+type StatusTransitionError struct {
+	From string
+	To   string
+}
+
+func (e *StatusTransitionError) Error() string {
+	return fmt.Sprintf("%v from %s to %s", ErrInvalidStatusTransition, e.From, e.To)
+}
+
+func (e *StatusTransitionError) Is(target error) bool {
+	return target == ErrInvalidStatusTransition
+}
+
+// attendeeStatusTransitions are the statuses each status can move to. Asking for
+// going at a full event puts the attendee on the waitlist instead, and only
+// promotion moves them from there to going. Invited is only ever a starting status.
+var attendeeStatusTransitions = map[string][]string{
+	AttendeeStatusInvited:    {AttendeeStatusGoing, AttendeeStatusMaybe, AttendeeStatusDeclined, AttendeeStatusCancelled},
+	AttendeeStatusGoing:      {AttendeeStatusMaybe, AttendeeStatusDeclined, AttendeeStatusCancelled},
+	AttendeeStatusMaybe:      {AttendeeStatusGoing, AttendeeStatusDeclined, AttendeeStatusCancelled},
+	AttendeeStatusDeclined:   {AttendeeStatusGoing, AttendeeStatusMaybe, AttendeeStatusCancelled},
+	AttendeeStatusWaitlisted: {AttendeeStatusMaybe, AttendeeStatusDeclined, AttendeeStatusCancelled},
+	AttendeeStatusCancelled:  {AttendeeStatusGoing, AttendeeStatusMaybe},
+}
+
+// IsAttendeeStatus reports whether the status is one of AttendeeStatuses.
+func IsAttendeeStatus(status string) bool {
+	return slices.Contains(AttendeeStatuses, status)
+}
+
+// CanTransitionAttendeeStatus reports whether an attendee may move from one status to the other.
+func CanTransitionAttendeeStatus(from, to string) bool {
+	return slices.Contains(attendeeStatusTransitions[from], to)
+}
+
+// AttendeeStatusTimes holds when the attendee last entered each status. Nil when they never did.
+// The full history, including statuses entered more than once, is in AttendeeStatusChange.
+type AttendeeStatusTimes struct {
+	GoingAt      *time.Time `json:"goingAt,omitempty"`
+	MaybeAt      *time.Time `json:"maybeAt,omitempty"`
+	DeclinedAt   *time.Time `json:"declinedAt,omitempty"`
+	InvitedAt    *time.Time `json:"invitedAt,omitempty"`
+	WaitlistedAt *time.Time `json:"waitlistedAt,omitempty"`
+	CancelledAt  *time.Time `json:"cancelledAt,omitempty"`
+}
+
+// AttendeeStatusChange records one status an attendee entered.
+type AttendeeStatusChange struct {
+	ID         int64 `json:"id"`
+	AttendeeID int64 `json:"attendeeId"`
+	// Nil for the status the attendee registered with
+	FromStatus *string   `json:"fromStatus"`
+	ToStatus   string    `json:"toStatus"`
+	ChangedAt  time.Time `json:"changedAt"`
+}
+
+// attendeeStatusColumns maps each status to the column recording when it was last entered.
+var attendeeStatusColumns = map[string]string{
+	AttendeeStatusGoing:      "going_at",
+	AttendeeStatusMaybe:      "maybe_at",
+	AttendeeStatusDeclined:   "declined_at",
+	AttendeeStatusInvited:    "invited_at",
+	AttendeeStatusWaitlisted: "waitlisted_at",
+	AttendeeStatusCancelled:  "cancelled_at",
+}
+
+// NewAttendeeCounts returns a count of zero for every status.
+func NewAttendeeCounts() map[string]int64 {
+	counts := make(map[string]int64, len(AttendeeStatuses))
+	for _, status := range AttendeeStatuses {
+		counts[status] = 0
+	}
+	return counts
+}
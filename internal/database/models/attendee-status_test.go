@@ -0,0 +1,80 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"testing"
+)
+
+func TestCanTransitionAttendeeStatus(t *testing.T) {
+	// Every allowed move, anything else must be refused
+	allowed := map[[2]string]bool{
+		{AttendeeStatusInvited, AttendeeStatusGoing}:     true,
+		{AttendeeStatusInvited, AttendeeStatusMaybe}:     true,
+		{AttendeeStatusInvited, AttendeeStatusDeclined}:  true,
+		{AttendeeStatusInvited, AttendeeStatusCancelled}: true,
+
+		{AttendeeStatusGoing, AttendeeStatusMaybe}:     true,
+		{AttendeeStatusGoing, AttendeeStatusDeclined}:  true,
+		{AttendeeStatusGoing, AttendeeStatusCancelled}: true,
+
+		{AttendeeStatusMaybe, AttendeeStatusGoing}:     true,
+		{AttendeeStatusMaybe, AttendeeStatusDeclined}:  true,
+		{AttendeeStatusMaybe, AttendeeStatusCancelled}: true,
+
+		{AttendeeStatusDeclined, AttendeeStatusGoing}:     true,
+		{AttendeeStatusDeclined, AttendeeStatusMaybe}:     true,
+		{AttendeeStatusDeclined, AttendeeStatusCancelled}: true,
+
+		{AttendeeStatusWaitlisted, AttendeeStatusMaybe}:     true,
+		{AttendeeStatusWaitlisted, AttendeeStatusDeclined}:  true,
+		{AttendeeStatusWaitlisted, AttendeeStatusCancelled}: true,
+
+		{AttendeeStatusCancelled, AttendeeStatusGoing}: true,
+		{AttendeeStatusCancelled, AttendeeStatusMaybe}: true,
+	}
+
+	statuses := append(slices.Clone(AttendeeStatuses), "", "unknown")
+	for _, from := range statuses {
+		for _, to := range statuses {
+			t.Run(fmt.Sprintf("%s to %s", from, to), func(t *testing.T) {
+				want := allowed[[2]string{from, to}]
+				if got := CanTransitionAttendeeStatus(from, to); got != want {
+					t.Errorf("CanTransitionAttendeeStatus(%q, %q) = %v, want %v", from, to, got, want)
+				}
+			})
+		}
+	}
+}
+
+func TestAttendeeStatusesHaveColumns(t *testing.T) {
+	for _, status := range AttendeeStatuses {
+		if !IsAttendeeStatus(status) {
+			t.Errorf("IsAttendeeStatus(%q) = false", status)
+		}
+		if attendeeStatusColumns[status] == "" {
+			t.Errorf("status %q has no timestamp column", status)
+		}
+	}
+
+	if IsAttendeeStatus("registered") {
+		t.Error(`IsAttendeeStatus("registered") = true`)
+	}
+}
+
+func TestStatusTransitionError(t *testing.T) {
+	var err error = fmt.Errorf("set status: %w", &StatusTransitionError{From: AttendeeStatusWaitlisted, To: AttendeeStatusGoing})
+
+	if !errors.Is(err, ErrInvalidStatusTransition) {
+		t.Errorf("errors.Is(%v, ErrInvalidStatusTransition) = false", err)
+	}
+
+	var transitionErr *StatusTransitionError
+	if !errors.As(err, &transitionErr) {
+		t.Fatalf("errors.As(%v, *StatusTransitionError) = false", err)
+	}
+	if transitionErr.From != AttendeeStatusWaitlisted || transitionErr.To != AttendeeStatusGoing {
+		t.Errorf("transition = %s to %s, want waitlisted to going", transitionErr.From, transitionErr.To)
+	}
+}
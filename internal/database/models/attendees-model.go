@@ -2,6 +2,9 @@ package models
 
 import (
 	"database/sql"
+	"errors"
+
+	sq "github.com/Masterminds/squirrel"
 )
 
 type AttendeesModel struct {
@@ -12,26 +15,76 @@ type Attendee struct {
 	ID      int64 `db:"id" json:"id"`
 	UserID  int64 `db:"user_id" json:"userId" binding:"required"`
 	EventID int64 `db:"event_id" json:"eventId" binding:"required"`
+	// RSVP status, one of AttendeeStatuses
+	Status string `db:"status" json:"status"`
+	AttendeeStatusTimes
 	BaseModel
 
 	User  *User  `json:"user,omitempty"`
 	Event *Event `json:"event,omitempty"`
 }
 
+var (
+	ErrEventNotFound    = errors.New("event not found")
+	ErrAlreadyAttending = errors.New("user is already registered for this event")
+	ErrAttendeeNotFound = errors.New("attendee not found")
+)
+
 type CreateAttendeeDto struct {
 	UserID  int64 `json:"userId" binding:"required"`
 	EventID int64 `json:"eventId" binding:"required"`
+	// Defaults to going, only organizers can invite
+	Status string `json:"status" binding:"omitempty,oneof=going maybe invited"`
+}
+
+// UpdateAttendeeStatusDto takes the statuses an attendee can ask for. Invited and
+// waitlisted are only ever set by the server.
+type UpdateAttendeeStatusDto struct {
+	Status string `json:"status" binding:"required,oneof=going maybe declined cancelled"`
+}
+
+// AttendeeFilters narrow attendee lists. Zero values do not filter.
+type AttendeeFilters struct {
+	EventID int64
+	UserID  int64
+	// Attendees with any of these statuses
+	Statuses []string
+}
+
+// AttendeeSortColumns are the fields attendee lists can be sorted by.
+var AttendeeSortColumns = map[string]string{
+	"createdAt": "a.created_at",
 }
 
-type UpdateAttendeeDto struct {
-	UserID  int64 `json:"userId"`
-	EventID int64 `json:"eventId"`
+// AttendeeKeysetColumns are the fields keyset paginated attendee lists can be sorted by.
+var AttendeeKeysetColumns = map[string]string{
+	"createdAt": "a.created_at",
+}
+
+func (f *AttendeeFilters) apply(query sq.SelectBuilder) sq.SelectBuilder {
+	if f == nil {
+		return query
+	}
+
+	if f.EventID != 0 {
+		query = query.Where(sq.Eq{"a.event_id": f.EventID})
+	}
+	if f.UserID != 0 {
+		query = query.Where(sq.Eq{"a.user_id": f.UserID})
+	}
+	if len(f.Statuses) > 0 {
+		query = query.Where(sq.Eq{"a.status": f.Statuses})
+	}
+
+	return query
 }
 
 type AttendeeSerializer struct {
-	ID      int64 `json:"id,omitempty"`
-	UserID  int64 `json:"userId,omitempty"`
-	EventID int64 `json:"eventId,omitempty"`
+	ID      int64  `json:"id,omitempty"`
+	UserID  int64  `json:"userId,omitempty"`
+	EventID int64  `json:"eventId,omitempty"`
+	Status  string `json:"status,omitempty"`
+	AttendeeStatusTimes
 	BaseModel
 
 	// Joins
@@ -39,22 +92,28 @@ type AttendeeSerializer struct {
 	Event *EventSerializer `json:"event,omitempty"`
 }
 
-func CreateResponseAttendee(attendee *Attendee) AttendeeSerializer {
+func CreateResponseAttendee(attendee *Attendee, viewer *Viewer) AttendeeSerializer {
 
 	response := AttendeeSerializer{
-		ID:        attendee.ID,
-		UserID:    attendee.UserID,
-		EventID:   attendee.EventID,
-		BaseModel: BaseModel{CreatedAt: attendee.CreatedAt, UpdatedAt: attendee.UpdatedAt},
+		ID:                  attendee.ID,
+		UserID:              attendee.UserID,
+		EventID:             attendee.EventID,
+		Status:              attendee.Status,
+		AttendeeStatusTimes: attendee.AttendeeStatusTimes,
+		BaseModel:           BaseModel{CreatedAt: attendee.CreatedAt, UpdatedAt: attendee.UpdatedAt},
 	}
 
 	if attendee.User != nil {
-		userResponse := CreateResponseUser(attendee.User)
+		userResponse := CreateResponseUserFor(attendee.User, viewer)
+		// Organizers need the emails of the people coming to their events
+		if viewer.Organizes(attendee.EventID) {
+			userResponse.Email = attendee.User.Email
+		}
 		response.User = &userResponse
 	}
 
 	if attendee.Event != nil {
-		eventResponse := CreateResponseEvent(attendee.Event)
+		eventResponse := CreateResponseEvent(attendee.Event, viewer)
 		response.Event = &eventResponse
 	}
 
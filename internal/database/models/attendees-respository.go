@@ -1,46 +1,201 @@
 package models
 
 import (
+	"context"
 	"database/sql"
 	"errors"
-	"fmt"
+	"strings"
+	"time"
 
 	sq "github.com/Masterminds/squirrel"
 	"github.com/vickon16/go-gin-rest-api/internal/utils"
 )
 
-func (m *AttendeesModel) Insert(attendee *Attendee) error {
+// Register adds the user to the event with the attendee's status, going when it is empty.
+// Going to a full event puts the attendee on the waitlist instead. The event row stays
+// locked until the registration is stored, so concurrent registrations are counted one
+// after the other and cannot overbook it.
+func (m *AttendeesModel) Register(attendee *Attendee) error {
 	ctx, cancel := utils.CreateContext()
 	defer cancel()
 
-	query := sq.Insert("attendees").
-		Columns("user_id", "event_id").
-		Values(attendee.UserID, attendee.EventID).
-		Suffix("RETURNING id, user_id, event_id, created_at").
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	capacity, err := lockEvent(ctx, tx, attendee.EventID)
+	if err != nil {
+		return err
+	}
+
+	existsQuery := sq.Select("1").
+		Prefix("SELECT EXISTS (").
+		From("attendees").
+		Where(sq.Eq{"event_id": attendee.EventID, "user_id": attendee.UserID}).
+		Suffix(")").
 		PlaceholderFormat(sq.Dollar)
 
-	sqlStr, args, err := query.ToSql()
+	sqlStr, args, err := existsQuery.ToSql()
 	if err != nil {
 		return err
 	}
 
-	// Scan the returned row
-	return m.DB.QueryRowContext(ctx, sqlStr, args...).
-		Scan(&attendee.ID, &attendee.UserID, &attendee.EventID, &attendee.CreatedAt)
+	var exists bool
+	if err := tx.QueryRowContext(ctx, sqlStr, args...).Scan(&exists); err != nil {
+		return err
+	}
+	if exists {
+		return ErrAlreadyAttending
+	}
+
+	status := attendee.Status
+	if status == "" {
+		status = AttendeeStatusGoing
+	}
+	if status, err = seatOrWaitlist(ctx, tx, attendee.EventID, capacity, status); err != nil {
+		return err
+	}
+
+	now := time.Now()
+	insertQuery := sq.Insert("attendees").
+		Columns("user_id", "event_id", "status", attendeeStatusColumns[status]).
+		Values(attendee.UserID, attendee.EventID, status, now).
+		Suffix("RETURNING " + strings.Join(attendeeColumns(""), ", ")).
+		PlaceholderFormat(sq.Dollar)
+
+	sqlStr, args, err = insertQuery.ToSql()
+	if err != nil {
+		return err
+	}
+
+	if err := tx.QueryRowContext(ctx, sqlStr, args...).Scan(attendee.fields()...); err != nil {
+		return err
+	}
+
+	if err := recordStatusChange(ctx, tx, attendee.ID, "", status, now); err != nil {
+		return err
+	}
+
+	return tx.Commit()
 }
 
-func (m *AttendeesModel) GetAll() ([]*Attendee, error) {
+// SetStatus moves the attendee to another status if the state machine allows it,
+// recording when. Going to a full event puts the attendee on the waitlist instead,
+// and a seat given up goes to the first attendee on the waitlist.
+// Returns the updated attendee and the attendees promoted to a seat.
+func (m *AttendeesModel) SetStatus(id int64, status string) (*Attendee, []*Attendee, error) {
 	ctx, cancel := utils.CreateContext()
 	defer cancel()
 
-	query := sq.Select(
-		"a.id", "a.user_id", "a.event_id", "a.created_at",
-		"u.id", "u.name", "u.email, u.created_at",
-		"e.id", "e.user_id", "e.name", "e.description", "e.date", "e.location", "e.created_at",
-	).
-		From("attendees a").
-		LeftJoin("users u ON a.user_id = u.id").
-		LeftJoin("events e ON a.event_id = e.id").
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer tx.Rollback()
+
+	sqlStr, args, err := sq.Select("event_id").
+		From("attendees").
+		Where(sq.Eq{"id": id}).
+		PlaceholderFormat(sq.Dollar).
+		ToSql()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var eventId int64
+	if err := tx.QueryRowContext(ctx, sqlStr, args...).Scan(&eventId); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil, ErrAttendeeNotFound
+		}
+		return nil, nil, err
+	}
+
+	// The event is locked before the attendee, in the same order as everywhere else
+	capacity, err := lockEvent(ctx, tx, eventId)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	sqlStr, args, err = sq.Select("status").
+		From("attendees").
+		Where(sq.Eq{"id": id}).
+		Suffix("FOR UPDATE").
+		PlaceholderFormat(sq.Dollar).
+		ToSql()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var current string
+	if err := tx.QueryRowContext(ctx, sqlStr, args...).Scan(&current); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil, ErrAttendeeNotFound
+		}
+		return nil, nil, err
+	}
+
+	if !CanTransitionAttendeeStatus(current, status) {
+		return nil, nil, &StatusTransitionError{From: current, To: status}
+	}
+
+	if status, err = seatOrWaitlist(ctx, tx, eventId, capacity, status); err != nil {
+		return nil, nil, err
+	}
+
+	now := time.Now()
+	updateQuery := sq.Update("attendees").
+		Set("status", status).
+		Set(attendeeStatusColumns[status], now).
+		Set("updated_at", now).
+		Where(sq.Eq{"id": id}).
+		Suffix("RETURNING updated_at, " + strings.Join(attendeeColumns(""), ", ")).
+		PlaceholderFormat(sq.Dollar)
+
+	sqlStr, args, err = updateQuery.ToSql()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var updated Attendee
+	if err := tx.QueryRowContext(ctx, sqlStr, args...).Scan(append([]any{&updated.UpdatedAt}, updated.fields()...)...); err != nil {
+		return nil, nil, err
+	}
+
+	if err := recordStatusChange(ctx, tx, id, current, status, now); err != nil {
+		return nil, nil, err
+	}
+
+	promoted := []*Attendee{}
+	if current == AttendeeStatusGoing {
+		if promoted, err = promoteWaitlisted(ctx, tx, eventId, capacity); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	return &updated, promoted, tx.Commit()
+}
+
+// Unregister removes the user from the event and gives the freed seat to the first
+// attendee on the waitlist. Returns the attendees promoted to a seat.
+func (m *AttendeesModel) Unregister(eventId, userId int64) ([]*Attendee, error) {
+	ctx, cancel := utils.CreateContext()
+	defer cancel()
+
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	capacity, err := lockEvent(ctx, tx, eventId)
+	if err != nil {
+		return nil, err
+	}
+
+	query := sq.Delete("attendees").
+		Where(sq.Eq{"event_id": eventId, "user_id": userId}).
 		PlaceholderFormat(sq.Dollar)
 
 	sqlStr, args, err := query.ToSql()
@@ -48,52 +203,61 @@ func (m *AttendeesModel) GetAll() ([]*Attendee, error) {
 		return nil, err
 	}
 
-	// Use QueryContext for multiple rows
-	rows, err := m.DB.QueryContext(ctx, sqlStr, args...)
+	if _, err := tx.ExecContext(ctx, sqlStr, args...); err != nil {
+		return nil, err
+	}
+
+	promoted, err := promoteWaitlisted(ctx, tx, eventId, capacity)
 	if err != nil {
 		return nil, err
 	}
 
-	defer rows.Close()
+	return promoted, tx.Commit()
+}
 
-	var attendees []*Attendee
+// PromoteWaitlisted gives every free seat of the event to the waitlist, e.g. after its capacity was raised.
+// Returns the attendees promoted to a seat.
+func (m *AttendeesModel) PromoteWaitlisted(eventId int64) ([]*Attendee, error) {
+	ctx, cancel := utils.CreateContext()
+	defer cancel()
 
-	for rows.Next() {
-		var attendee Attendee
-		attendee.User = &User{}
-		attendee.Event = &Event{}
-
-		if err := rows.Scan(&attendee.ID, &attendee.UserID, &attendee.EventID, &attendee.CreatedAt,
-			&attendee.User.ID, &attendee.User.Name, &attendee.User.Email, &attendee.User.CreatedAt,
-			&attendee.Event.ID, &attendee.Event.UserID, &attendee.Event.Name, &attendee.Event.Description,
-			&attendee.Event.Date, &attendee.Event.Location, &attendee.Event.CreatedAt,
-		); err != nil {
-			return nil, err
-		}
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
 
-		attendees = append(attendees, &attendee)
+	capacity, err := lockEvent(ctx, tx, eventId)
+	if err != nil {
+		return nil, err
 	}
 
-	if err := rows.Err(); err != nil {
+	promoted, err := promoteWaitlisted(ctx, tx, eventId, capacity)
+	if err != nil {
 		return nil, err
 	}
 
-	return attendees, nil
+	return promoted, tx.Commit()
 }
 
-func (m *AttendeesModel) Get(id int64) (*Attendee, error) {
+// CountByStatus returns how many attendees each of the events has per status.
+// Every status of every event is present, zero when no attendee has it.
+func (m *AttendeesModel) CountByStatus(eventIds []int64) (map[int64]map[string]int64, error) {
+	counts := map[int64]map[string]int64{}
+	for _, id := range eventIds {
+		counts[id] = NewAttendeeCounts()
+	}
+	if len(eventIds) == 0 {
+		return counts, nil
+	}
+
 	ctx, cancel := utils.CreateContext()
 	defer cancel()
 
-	query := sq.Select(
-		"a.id", "a.user_id", "a.event_id", "a.created_at",
-		"u.id", "u.name", "u.email", "u.created_at",
-		"e.id", "e.user_id", "e.name", "e.description", "e.date", "e.location", "e.created_at",
-	).
-		From("attendees a").
-		LeftJoin("users u ON a.user_id = u.id").
-		LeftJoin("events e ON a.event_id = e.id").
-		Where(sq.Eq{"a.id": id}).
+	query := sq.Select("event_id", "status", "COUNT(*)").
+		From("attendees").
+		Where(sq.Eq{"event_id": eventIds}).
+		GroupBy("event_id", "status").
 		PlaceholderFormat(sq.Dollar)
 
 	sqlStr, args, err := query.ToSql()
@@ -101,70 +265,171 @@ func (m *AttendeesModel) Get(id int64) (*Attendee, error) {
 		return nil, err
 	}
 
-	var attendee Attendee
-	attendee.User = &User{}
-	attendee.Event = &Event{}
+	rows, err := m.DB.QueryContext(ctx, sqlStr, args...)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
 
-	err = m.DB.QueryRowContext(ctx, sqlStr, args...).Scan(
-		&attendee.ID, &attendee.UserID, &attendee.EventID, &attendee.CreatedAt,
-		&attendee.User.ID, &attendee.User.Name, &attendee.User.Email, &attendee.User.CreatedAt,
-		&attendee.Event.ID, &attendee.Event.UserID, &attendee.Event.Name, &attendee.Event.Description, &attendee.Event.Date, &attendee.Event.Location, &attendee.Event.CreatedAt)
+	for rows.Next() {
+		var (
+			eventId int64
+			status  string
+			count   int64
+		)
+		if err := rows.Scan(&eventId, &status, &count); err != nil {
+			return nil, err
+		}
+		counts[eventId][status] = count
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return counts, nil
+}
+
+// lockEvent locks the event row for the rest of the transaction and returns its capacity.
+// Every change to who holds a seat takes this lock first.
+func lockEvent(ctx context.Context, tx *sql.Tx, eventId int64) (*int, error) {
+	query := sq.Select("capacity").
+		From("events").
+		Where(sq.Eq{"id": eventId}).
+		Suffix("FOR UPDATE").
+		PlaceholderFormat(sq.Dollar)
+
+	sqlStr, args, err := query.ToSql()
 	if err != nil {
+		return nil, err
+	}
+
+	var capacity *int
+	if err := tx.QueryRowContext(ctx, sqlStr, args...).Scan(&capacity); err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
-			return nil, nil
+			return nil, ErrEventNotFound
 		}
-
-		// For other errors
 		return nil, err
 	}
 
-	return &attendee, nil
+	return capacity, nil
 }
 
-func (m *AttendeesModel) Update(id int64, attendee *UpdateAttendeeDto) (*Attendee, error) {
-	ctx, cancel := utils.CreateContext()
-	defer cancel()
+// seatOrWaitlist turns going into waitlisted when the event has no free seat. The event has to be locked.
+func seatOrWaitlist(ctx context.Context, tx *sql.Tx, eventId int64, capacity *int, status string) (string, error) {
+	if status != AttendeeStatusGoing || capacity == nil {
+		return status, nil
+	}
+
+	seated, err := countSeated(ctx, tx, eventId)
+	if err != nil {
+		return "", err
+	}
+	if seatsLeft(*capacity, seated) == 0 {
+		return AttendeeStatusWaitlisted, nil
+	}
 
-	query := sq.Update("attendees").PlaceholderFormat(sq.Dollar)
+	return status, nil
+}
 
-	if attendee.UserID != 0 {
-		query = query.Set("user_id", attendee.UserID)
+// seatsLeft is how many more attendees can go. Never negative, a lowered capacity may leave more seated than it allows.
+func seatsLeft(capacity, seated int) int {
+	return max(capacity-seated, 0)
+}
+
+func countSeated(ctx context.Context, tx *sql.Tx, eventId int64) (int, error) {
+	query := sq.Select("COUNT(*)").
+		From("attendees").
+		Where(sq.Eq{"event_id": eventId, "status": AttendeeStatusGoing}).
+		PlaceholderFormat(sq.Dollar)
+
+	sqlStr, args, err := query.ToSql()
+	if err != nil {
+		return 0, err
 	}
-	if attendee.EventID != 0 {
-		query = query.Set("event_id", attendee.EventID)
+
+	var seated int
+	err = tx.QueryRowContext(ctx, sqlStr, args...).Scan(&seated)
+	return seated, err
+}
+
+// promoteWaitlisted moves attendees from the head of the waitlist to the free seats.
+// Without a capacity every waiting attendee gets a seat. The event has to be locked.
+func promoteWaitlisted(ctx context.Context, tx *sql.Tx, eventId int64, capacity *int) ([]*Attendee, error) {
+	waiting := sq.Select("id").
+		From("attendees").
+		Where(sq.Eq{"event_id": eventId, "status": AttendeeStatusWaitlisted}).
+		OrderBy("waitlisted_at ASC", "id ASC")
+
+	if capacity != nil {
+		seated, err := countSeated(ctx, tx, eventId)
+		if err != nil {
+			return nil, err
+		}
+
+		free := seatsLeft(*capacity, seated)
+		if free == 0 {
+			return []*Attendee{}, nil
+		}
+		waiting = waiting.Limit(uint64(free))
 	}
 
-	query = query.Where(sq.Eq{"id": id}).Suffix("RETURNING id, user_id, event_id, created_at")
+	now := time.Now()
+	query := sq.Update("attendees").
+		Set("status", AttendeeStatusGoing).
+		Set("going_at", now).
+		Set("updated_at", now).
+		Where(sq.Expr("id IN (?)", waiting)).
+		Suffix("RETURNING id, user_id, event_id, created_at").
+		PlaceholderFormat(sq.Dollar)
 
 	sqlStr, args, err := query.ToSql()
 	if err != nil {
 		return nil, err
 	}
 
-	if len(args) == 0 {
-		return nil, fmt.Errorf("no fields to update")
+	rows, err := tx.QueryContext(ctx, sqlStr, args...)
+	if err != nil {
+		return nil, err
 	}
 
-	var updated Attendee
-	err = m.DB.QueryRowContext(ctx, sqlStr, args...).Scan(
-		&updated.ID,
-		&updated.UserID,
-		&updated.EventID,
-		&updated.CreatedAt,
-	)
-	if err != nil {
+	defer rows.Close()
+
+	promoted := []*Attendee{}
+
+	for rows.Next() {
+		var attendee Attendee
+		if err := rows.Scan(&attendee.ID, &attendee.UserID, &attendee.EventID, &attendee.CreatedAt); err != nil {
+			return nil, err
+		}
+		promoted = append(promoted, &attendee)
+	}
+
+	if err := rows.Err(); err != nil {
 		return nil, err
 	}
+	rows.Close()
+
+	for _, attendee := range promoted {
+		if err := recordStatusChange(ctx, tx, attendee.ID, AttendeeStatusWaitlisted, AttendeeStatusGoing, now); err != nil {
+			return nil, err
+		}
+	}
 
-	return &updated, nil
+	return promoted, nil
 }
 
-func (m *AttendeesModel) Delete(id int64) error {
-	ctx, cancel := utils.CreateContext()
-	defer cancel()
+// recordStatusChange adds a status the attendee entered to its history. An empty from is the status it registered with.
+func recordStatusChange(ctx context.Context, tx *sql.Tx, attendeeId int64, from, to string, changedAt time.Time) error {
+	var fromStatus *string
+	if from != "" {
+		fromStatus = &from
+	}
 
-	query := sq.Delete("attendees").
-		Where(sq.Eq{"id": id}).
+	query := sq.Insert("attendee_status_changes").
+		Columns("attendee_id", "from_status", "to_status", "changed_at").
+		Values(attendeeId, fromStatus, to, changedAt).
 		PlaceholderFormat(sq.Dollar)
 
 	sqlStr, args, err := query.ToSql()
@@ -172,51 +437,114 @@ func (m *AttendeesModel) Delete(id int64) error {
 		return err
 	}
 
-	_, err = m.DB.ExecContext(ctx, sqlStr, args...)
-	if err != nil {
-		return err
-	}
-
-	return nil
+	_, err = tx.ExecContext(ctx, sqlStr, args...)
+	return err
 }
 
-func (m *AttendeesModel) DeleteByIdAndEventId(eventId, userId int64) error {
+// GetStatusChanges returns every status the attendee entered, oldest first.
+func (m *AttendeesModel) GetStatusChanges(attendeeId int64) ([]*AttendeeStatusChange, error) {
 	ctx, cancel := utils.CreateContext()
 	defer cancel()
 
-	query := sq.Delete("attendees").
-		Where(sq.Eq{"event_id": eventId, "user_id": userId}).
+	query := sq.Select("id", "attendee_id", "from_status", "to_status", "changed_at").
+		From("attendee_status_changes").
+		Where(sq.Eq{"attendee_id": attendeeId}).
+		OrderBy("changed_at ASC", "id ASC").
 		PlaceholderFormat(sq.Dollar)
 
 	sqlStr, args, err := query.ToSql()
 	if err != nil {
-		return err
+		return nil, err
 	}
 
-	_, err = m.DB.ExecContext(ctx, sqlStr, args...)
+	rows, err := m.DB.QueryContext(ctx, sqlStr, args...)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
-	return nil
+	defer rows.Close()
+
+	changes := []*AttendeeStatusChange{}
+
+	for rows.Next() {
+		var change AttendeeStatusChange
+		if err := rows.Scan(&change.ID, &change.AttendeeID, &change.FromStatus, &change.ToStatus, &change.ChangedAt); err != nil {
+			return nil, err
+		}
+		changes = append(changes, &change)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return changes, nil
 }
 
-func (m *AttendeesModel) GetAttendeesByEventId(eventId int64) ([]*Attendee, error) {
-	ctx, cancel := utils.CreateContext()
-	defer cancel()
+// GetAll returns one page of the attendees matching the filters, with their user and event, and the number of all matches.
+func (m *AttendeesModel) GetAll(filters *AttendeeFilters, options *ListOptions) ([]*Attendee, int64, error) {
+	return m.listAttendees(filters.apply(sq.Select().From("attendees a")), options)
+}
 
-	query := sq.Select(
-		"a.id", "a.user_id", "a.event_id", "a.created_at",
-		"u.id", "u.name", "u.email", "u.created_at",
-		"e.id", "e.user_id", "e.name", "e.description", "e.date", "e.location", "e.created_at",
-	).
-		From("attendees a").
+// listAttendees pages through the attendees matched by a filtered query without columns.
+func (m *AttendeesModel) listAttendees(filtered sq.SelectBuilder, options *ListOptions) ([]*Attendee, int64, error) {
+	total, err := countRows(m.DB, filtered)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	query := paginate(withAttendeeJoins(filtered), options, AttendeeSortColumns, "a.id")
+
+	attendees, err := m.queryAttendees(query)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return attendees, total, nil
+}
+
+// withAttendeeJoins adds the columns queryAttendees scans to a query on attendees a.
+func withAttendeeJoins(query sq.SelectBuilder) sq.SelectBuilder {
+	return query.Columns(attendeeColumns("a.")...).
+		Columns(
+			"u.id", "u.name", "u.email", "u.created_at",
+			"e.id", "e.user_id", "e.name", "e.description", "e.date", "e.location", "e.created_at",
+		).
 		LeftJoin("users u ON a.user_id = u.id").
-		LeftJoin("events e ON a.event_id = e.id").
-		Where(sq.Eq{"a.event_id": eventId}).
-		PlaceholderFormat(sq.Dollar)
+		LeftJoin("events e ON a.event_id = e.id")
+}
 
-	sqlStr, args, err := query.ToSql()
+// attendeeColumns are the columns of attendees that fields scans, prefixed with a table alias such as "a.".
+func attendeeColumns(prefix string) []string {
+	columns := []string{"id", "user_id", "event_id", "created_at", "status", "going_at", "maybe_at", "declined_at", "invited_at", "waitlisted_at", "cancelled_at"}
+	for i, column := range columns {
+		columns[i] = prefix + column
+	}
+	return columns
+}
+
+func (a *Attendee) fields() []any {
+	return []any{&a.ID, &a.UserID, &a.EventID, &a.CreatedAt,
+		&a.Status, &a.GoingAt, &a.MaybeAt, &a.DeclinedAt, &a.InvitedAt, &a.WaitlistedAt, &a.CancelledAt,
+	}
+}
+
+// joinedFields are fields followed by the user and event columns withAttendeeJoins adds.
+func (a *Attendee) joinedFields() []any {
+	a.User = &User{}
+	a.Event = &Event{}
+
+	return append(a.fields(),
+		&a.User.ID, &a.User.Name, &a.User.Email, &a.User.CreatedAt,
+		&a.Event.ID, &a.Event.UserID, &a.Event.Name, &a.Event.Description, &a.Event.Date, &a.Event.Location, &a.Event.CreatedAt,
+	)
+}
+
+func (m *AttendeesModel) queryAttendees(query sq.SelectBuilder) ([]*Attendee, error) {
+	ctx, cancel := utils.CreateContext()
+	defer cancel()
+
+	sqlStr, args, err := query.PlaceholderFormat(sq.Dollar).ToSql()
 	if err != nil {
 		return nil, err
 	}
@@ -229,17 +557,12 @@ func (m *AttendeesModel) GetAttendeesByEventId(eventId int64) ([]*Attendee, erro
 
 	defer rows.Close()
 
-	var attendees []*Attendee
+	attendees := []*Attendee{}
 
 	for rows.Next() {
 		var attendee Attendee
-		attendee.User = &User{}
-		attendee.Event = &Event{}
 
-		if err := rows.Scan(&attendee.ID, &attendee.UserID, &attendee.EventID, &attendee.CreatedAt,
-			&attendee.User.ID, &attendee.User.Name, &attendee.User.Email, &attendee.User.CreatedAt,
-			&attendee.Event.ID, &attendee.Event.UserID, &attendee.Event.Name, &attendee.Event.Description,
-			&attendee.Event.Date, &attendee.Event.Location, &attendee.Event.CreatedAt); err != nil {
+		if err := rows.Scan(attendee.joinedFields()...); err != nil {
 			return nil, err
 		}
 
@@ -253,14 +576,61 @@ func (m *AttendeesModel) GetAttendeesByEventId(eventId int64) ([]*Attendee, erro
 	return attendees, nil
 }
 
+func attendeeKeyset(attendee *Attendee) Keyset {
+	keyset := Keyset{ID: attendee.ID}
+	if attendee.CreatedAt != nil {
+		keyset.Value = *attendee.CreatedAt
+	}
+	return keyset
+}
+
+func (m *AttendeesModel) Get(id int64) (*Attendee, error) {
+	ctx, cancel := utils.CreateContext()
+	defer cancel()
+
+	query := withAttendeeJoins(sq.Select().From("attendees a")).
+		Where(sq.Eq{"a.id": id}).
+		PlaceholderFormat(sq.Dollar)
+
+	sqlStr, args, err := query.ToSql()
+	if err != nil {
+		return nil, err
+	}
+
+	var attendee Attendee
+
+	err = m.DB.QueryRowContext(ctx, sqlStr, args...).Scan(attendee.joinedFields()...)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+
+		// For other errors
+		return nil, err
+	}
+
+	return &attendee, nil
+}
+
+// GetAttendeesByEventId returns the page of the event's attendees matching the filters that the options' keyset points at.
+// Attendees are ordered by registration time, then id.
+func (m *AttendeesModel) GetAttendeesByEventId(eventId int64, filters *AttendeeFilters, options *ListOptions) ([]*Attendee, *KeysetPage, error) {
+	query := filters.apply(withAttendeeJoins(sq.Select().From("attendees a"))).Where(sq.Eq{"a.event_id": eventId})
+
+	attendees, err := m.queryAttendees(seekPage(query, options, "a.created_at", "a.id"))
+	if err != nil {
+		return nil, nil, err
+	}
+
+	attendees, page := keysetPage(attendees, options, attendeeKeyset)
+	return attendees, page, nil
+}
+
 func (m *AttendeesModel) GetByEventAndAttendee(eventId, userId int64) (*Attendee, error) {
 	ctx, cancel := utils.CreateContext()
 	defer cancel()
 
-	query := sq.Select(
-		"a.id", "a.user_id", "a.event_id", "a.created_at",
-		// "u.id", "u.name", "u.email",
-	).
+	query := sq.Select(attendeeColumns("a.")...).
 		From("attendees a").
 		// LeftJoin("users u ON a.user_id = u.id").
 		Where(sq.Eq{"a.user_id": userId, "a.event_id": eventId}).
@@ -273,7 +643,7 @@ func (m *AttendeesModel) GetByEventAndAttendee(eventId, userId int64) (*Attendee
 
 	var attendee Attendee
 
-	err = m.DB.QueryRowContext(ctx, sqlStr, args...).Scan(&attendee.ID, &attendee.UserID, &attendee.EventID, &attendee.CreatedAt)
+	err = m.DB.QueryRowContext(ctx, sqlStr, args...).Scan(attendee.fields()...)
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			return nil, nil
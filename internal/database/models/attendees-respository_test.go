@@ -0,0 +1,64 @@
+package models
+
+import (
+	"context"
+	"testing"
+)
+
+func TestSeatsLeft(t *testing.T) {
+	tests := []struct {
+		capacity int
+		seated   int
+		want     int
+	}{
+		{capacity: 10, seated: 0, want: 10},
+		{capacity: 10, seated: 9, want: 1},
+		{capacity: 10, seated: 10, want: 0},
+		// The capacity was lowered below the attendees already going
+		{capacity: 5, seated: 8, want: 0},
+	}
+
+	for _, tt := range tests {
+		if got := seatsLeft(tt.capacity, tt.seated); got != tt.want {
+			t.Errorf("seatsLeft(%d, %d) = %d, want %d", tt.capacity, tt.seated, got, tt.want)
+		}
+	}
+}
+
+// Only going can be turned into waitlisted, and only at events with a capacity.
+// These cases never count the seats, so they run without a database.
+func TestSeatOrWaitlistWithoutCounting(t *testing.T) {
+	capacity := 1
+
+	tests := []struct {
+		name     string
+		capacity *int
+		status   string
+	}{
+		{"unlimited event", nil, AttendeeStatusGoing},
+		{"maybe", &capacity, AttendeeStatusMaybe},
+		{"declined", &capacity, AttendeeStatusDeclined},
+		{"invited", &capacity, AttendeeStatusInvited},
+		{"cancelled", &capacity, AttendeeStatusCancelled},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := seatOrWaitlist(context.Background(), nil, 1, tt.capacity, tt.status)
+			if err != nil {
+				t.Fatalf("seatOrWaitlist returned %v", err)
+			}
+			if got != tt.status {
+				t.Errorf("seatOrWaitlist(%q) = %q, want it unchanged", tt.status, got)
+			}
+		})
+	}
+}
+
+func TestAttendeeColumnsMatchFields(t *testing.T) {
+	var attendee Attendee
+
+	if columns, fields := len(attendeeColumns("")), len(attendee.fields()); columns != fields {
+		t.Errorf("attendeeColumns has %d columns but fields scans %d", columns, fields)
+	}
+}
@@ -0,0 +1,33 @@
+package models
+
+import (
+	"database/sql"
+	"time"
+)
+
+type AuditLogModel struct {
+	DB *sql.DB
+}
+
+const (
+	AuditOutcomeDenied  = "denied"
+	AuditOutcomeAllowed = "allowed"
+)
+
+const (
+	AuditActionImpersonate         = "user:impersonate"
+	AuditActionImpersonatedRequest = "user:impersonated-request"
+)
+
+type AuditLog struct {
+	ID           int64     `db:"id" json:"id"`
+	ActorID      *int64    `db:"actor_id" json:"actorId,omitempty"`
+	Action       string    `db:"action" json:"action"`
+	ResourceType string    `db:"resource_type" json:"resourceType,omitempty"`
+	ResourceID   *int64    `db:"resource_id" json:"resourceId,omitempty"`
+	Outcome      string    `db:"outcome" json:"outcome"`
+	Method       string    `db:"method" json:"method,omitempty"`
+	Path         string    `db:"path" json:"path,omitempty"`
+	IPAddress    string    `db:"ip_address" json:"ipAddress,omitempty"`
+	CreatedAt    time.Time `db:"created_at" json:"createdAt"`
+}
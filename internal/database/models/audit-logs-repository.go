@@ -0,0 +1,24 @@
+package models
+
+import (
+	sq "github.com/Masterminds/squirrel"
+	"github.com/vickon16/go-gin-rest-api/internal/utils"
+)
+
+func (m *AuditLogModel) Insert(entry *AuditLog) error {
+	ctx, cancel := utils.CreateContext()
+	defer cancel()
+
+	query := sq.Insert("audit_logs").
+		Columns("actor_id", "action", "resource_type", "resource_id", "outcome", "method", "path", "ip_address").
+		Values(entry.ActorID, entry.Action, entry.ResourceType, entry.ResourceID, entry.Outcome, entry.Method, entry.Path, entry.IPAddress).
+		Suffix("RETURNING id, created_at").
+		PlaceholderFormat(sq.Dollar)
+
+	sqlStr, args, err := query.ToSql()
+	if err != nil {
+		return err
+	}
+
+	return m.DB.QueryRowContext(ctx, sqlStr, args...).Scan(&entry.ID, &entry.CreatedAt)
+}
@@ -2,6 +2,7 @@ package models
 
 import (
 	"database/sql"
+	"time"
 )
 
 type AuthModel struct {
@@ -11,14 +12,74 @@ type AuthModel struct {
 type RegisterUserDto struct {
 	Email    string `json:"email" binding:"required,email"`
 	Name     string `json:"name" binding:"required,min=3"`
-	Password string `json:"password" binding:"required,min=6,max=64"`
+	Password string `json:"password" binding:"required"`
 }
 
 type LoginUserDto struct {
 	Email    string `json:"email" binding:"required,email"`
-	Password string `json:"password" binding:"required,min=6,max=64"`
+	Password string `json:"password" binding:"required"`
+}
+
+type ImpersonationSerializer struct {
+	Token     string         `json:"token"`
+	ExpiresAt time.Time      `json:"expiresAt"`
+	User      UserSerializer `json:"user"`
 }
 
 type LoginSerializer struct {
-	Token string `json:"token"`
+	Token        string `json:"token"`
+	RefreshToken string `json:"refreshToken"`
+}
+
+type LogoutDto struct {
+	RefreshToken string `json:"refreshToken"`
+}
+
+type ForgotPasswordDto struct {
+	Email string `json:"email" binding:"required,email"`
+}
+
+type ResetPasswordDto struct {
+	Token    string `json:"token" binding:"required"`
+	Password string `json:"password" binding:"required"`
+}
+
+type VerifyEmailDto struct {
+	Token string `json:"token" binding:"required"`
+}
+
+type MagicLinkDto struct {
+	Email string `json:"email" binding:"required,email"`
+}
+
+type VerifyMagicLinkDto struct {
+	Token string `json:"token" binding:"required"`
+}
+
+type TwoFactorCodeDto struct {
+	Code string `json:"code" binding:"required"`
+}
+
+type DisableTwoFactorDto struct {
+	Password string `json:"password" binding:"required"`
+	Code     string `json:"code" binding:"required"`
+}
+
+type TwoFactorLoginDto struct {
+	ChallengeToken string `json:"challengeToken" binding:"required"`
+	Code           string `json:"code" binding:"required"`
+}
+
+type TwoFactorChallengeSerializer struct {
+	TwoFactorRequired bool   `json:"twoFactorRequired"`
+	ChallengeToken    string `json:"challengeToken"`
+}
+
+type TwoFactorEnrollmentSerializer struct {
+	Secret     string `json:"secret"`
+	OtpauthURI string `json:"otpauthUri"`
+}
+
+type RecoveryCodesSerializer struct {
+	RecoveryCodes []string `json:"recoveryCodes"`
 }
@@ -0,0 +1,49 @@
+package models
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+)
+
+// Avatar is stored as JSON in users.avatar.
+type Avatar struct {
+	URL string `json:"url"`
+
+	// Square thumbnails, by edge length in pixels
+	Thumbnails map[string]string `json:"thumbnails"`
+
+	// Storage keys of every file above, so they can be deleted
+	Keys []string `json:"-"`
+}
+
+// avatarRecord is the stored form, which unlike the API response keeps the storage keys.
+type avatarRecord struct {
+	URL        string            `json:"url"`
+	Thumbnails map[string]string `json:"thumbnails"`
+	Keys       []string          `json:"keys"`
+}
+
+func (a Avatar) Value() (driver.Value, error) {
+	return json.Marshal(avatarRecord(a))
+}
+
+func (a *Avatar) Scan(value any) error {
+	var data []byte
+	switch v := value.(type) {
+	case []byte:
+		data = v
+	case string:
+		data = []byte(v)
+	default:
+		return errors.New("unsupported avatar value")
+	}
+
+	var record avatarRecord
+	if err := json.Unmarshal(data, &record); err != nil {
+		return err
+	}
+
+	*a = Avatar(record)
+	return nil
+}
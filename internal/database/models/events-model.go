@@ -3,6 +3,8 @@ package models
 import (
 	"database/sql"
 	"time"
+
+	sq "github.com/Masterminds/squirrel"
 )
 
 type EventModel struct {
@@ -16,8 +18,13 @@ type Event struct {
 	Description string    `db:"description" json:"description,omitempty" binding:"required,min=5"`
 	Date        time.Time `db:"date" json:"date,omitempty" binding:"required"`
 	Location    string    `db:"location" json:"location,omitempty" binding:"required"`
+	// Nil when the number of attendees is not limited
+	Capacity *int `db:"capacity" json:"capacity,omitempty"`
 	BaseModel
 
+	// Number of attendees per status, loaded with AttendeesModel.CountByStatus
+	AttendeeCounts map[string]int64 `json:"attendeeCounts,omitempty"`
+
 	// Joins
 	User *User `json:"user,omitempty"`
 }
@@ -28,6 +35,7 @@ type CreateEventDto struct {
 	Description string    `json:"description,omitempty" binding:"required,min=5"`
 	Date        time.Time `json:"date,omitempty" binding:"required"`
 	Location    string    `json:"location,omitempty" binding:"required"`
+	Capacity    *int      `json:"capacity,omitempty" binding:"omitempty,min=1"`
 }
 
 type UpdateEventDto struct {
@@ -36,6 +44,49 @@ type UpdateEventDto struct {
 	Description string    `json:"description,omitempty" binding:"omitempty,min=5"`
 	Date        time.Time `json:"date,omitempty" binding:"omitempty"`
 	Location    string    `json:"location,omitempty" binding:"omitempty"`
+	// Zero removes the limit
+	Capacity *int `json:"capacity,omitempty" binding:"omitempty,min=0"`
+}
+
+// EventFilters narrow event lists. Zero values do not filter.
+type EventFilters struct {
+	From        *time.Time
+	To          *time.Time
+	Location    string
+	OrganizerID int64
+}
+
+// EventSortColumns are the fields event lists can be sorted by.
+var EventSortColumns = map[string]string{
+	"date":      "e.date",
+	"name":      "e.name",
+	"createdAt": "e.created_at",
+}
+
+// EventKeysetColumns are the fields keyset paginated event lists can be sorted by.
+var EventKeysetColumns = map[string]string{
+	"date": "e.date",
+}
+
+func (f *EventFilters) apply(query sq.SelectBuilder) sq.SelectBuilder {
+	if f == nil {
+		return query
+	}
+
+	if f.From != nil {
+		query = query.Where(sq.GtOrEq{"e.date": *f.From})
+	}
+	if f.To != nil {
+		query = query.Where(sq.LtOrEq{"e.date": *f.To})
+	}
+	if f.Location != "" {
+		query = query.Where(sq.ILike{"e.location": "%" + escapeLike(f.Location) + "%"})
+	}
+	if f.OrganizerID != 0 {
+		query = query.Where(sq.Eq{"e.user_id": f.OrganizerID})
+	}
+
+	return query
 }
 
 type EventSerializer struct {
@@ -45,25 +96,30 @@ type EventSerializer struct {
 	Description string    `json:"description,omitempty"`
 	Date        time.Time `json:"date,omitempty"`
 	Location    string    `json:"location,omitempty"`
+	Capacity    *int      `json:"capacity,omitempty"`
 	BaseModel
 
+	AttendeeCounts map[string]int64 `json:"attendeeCounts,omitempty"`
+
 	// Joins
 	User *UserSerializer `json:"user,omitempty"`
 }
 
-func CreateResponseEvent(event *Event) EventSerializer {
+func CreateResponseEvent(event *Event, viewer *Viewer) EventSerializer {
 	response := EventSerializer{
-		ID:          event.ID,
-		UserID:      event.UserID,
-		Name:        event.Name,
-		Description: event.Description,
-		Date:        event.Date,
-		Location:    event.Location,
-		BaseModel:   BaseModel{CreatedAt: event.CreatedAt, UpdatedAt: event.UpdatedAt},
+		ID:             event.ID,
+		UserID:         event.UserID,
+		Name:           event.Name,
+		Description:    event.Description,
+		Date:           event.Date,
+		Location:       event.Location,
+		Capacity:       event.Capacity,
+		BaseModel:      BaseModel{CreatedAt: event.CreatedAt, UpdatedAt: event.UpdatedAt},
+		AttendeeCounts: event.AttendeeCounts,
 	}
 
 	if event.User != nil {
-		userResponse := CreateResponseUser(event.User)
+		userResponse := CreateResponseUserFor(event.User, viewer)
 		response.User = &userResponse
 	}
 
@@ -4,6 +4,7 @@ import (
 	"database/sql"
 	"errors"
 	"fmt"
+	"time"
 
 	sq "github.com/Masterminds/squirrel"
 	"github.com/vickon16/go-gin-rest-api/internal/utils"
@@ -14,9 +15,9 @@ func (m *EventModel) Insert(event *CreateEventDto) (*Event, error) {
 	defer cancel()
 
 	query := sq.Insert("events").
-		Columns("user_id", "name", "description", "date", "location").
-		Values(event.UserID, event.Name, event.Description, event.Date, event.Location).
-		Suffix("RETURNING id, user_id, name, description, date, location, created_at").
+		Columns("user_id", "name", "description", "date", "location", "capacity").
+		Values(event.UserID, event.Name, event.Description, event.Date, event.Location, event.Capacity).
+		Suffix("RETURNING id, user_id, name, description, date, location, capacity, created_at").
 		PlaceholderFormat(sq.Dollar)
 
 	sqlStr, args, err := query.ToSql()
@@ -27,7 +28,7 @@ func (m *EventModel) Insert(event *CreateEventDto) (*Event, error) {
 	// Scan the returned row
 	var newEvent Event
 	err = m.DB.QueryRowContext(ctx, sqlStr, args...).
-		Scan(&newEvent.ID, &newEvent.UserID, &newEvent.Name, &newEvent.Description, &newEvent.Date, &newEvent.Location, &newEvent.CreatedAt)
+		Scan(&newEvent.ID, &newEvent.UserID, &newEvent.Name, &newEvent.Description, &newEvent.Date, &newEvent.Location, &newEvent.Capacity, &newEvent.CreatedAt)
 	if err != nil {
 		return nil, err
 	}
@@ -35,49 +36,20 @@ func (m *EventModel) Insert(event *CreateEventDto) (*Event, error) {
 	return &newEvent, nil
 }
 
-func (m *EventModel) GetAll() ([]*Event, error) {
-	ctx, cancel := utils.CreateContext()
-	defer cancel()
-
-	query := sq.Select(
-		"e.id", "e.user_id", "e.name", "e.description", "e.date", "e.location", "e.created_at",
-		"u.id", "u.name", "u.email",
-	).
+// GetAll returns the page of events matching the filters that the options' keyset points at, with the organizer.
+// Events are ordered by date, then id.
+func (m *EventModel) GetAll(filters *EventFilters, options *ListOptions) ([]*Event, *KeysetPage, error) {
+	query := filters.apply(sq.Select(eventWithOrganizerColumns...).
 		From("events e").
-		LeftJoin("users u ON e.user_id = u.id").
-		PlaceholderFormat(sq.Dollar)
-
-	sqlStr, args, err := query.ToSql()
-	if err != nil {
-		return nil, err
-	}
+		LeftJoin("users u ON e.user_id = u.id"))
 
-	// Use QueryContext for multiple rows
-	rows, err := m.DB.QueryContext(ctx, sqlStr, args...)
+	events, err := m.queryEventsWithOrganizer(seekPage(query, options, "e.date", "e.id"))
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 
-	defer rows.Close()
-
-	var events []*Event
-
-	for rows.Next() {
-		var event Event
-		event.User = &User{}
-
-		if err := rows.Scan(&event.ID, &event.UserID, &event.Name, &event.Description, &event.Date, &event.Location, &event.CreatedAt, &event.User.ID, &event.User.Name, &event.User.Email); err != nil {
-			return nil, err
-		}
-
-		events = append(events, &event)
-	}
-
-	if err := rows.Err(); err != nil {
-		return nil, err
-	}
-
-	return events, nil
+	events, page := keysetPage(events, options, eventKeyset)
+	return events, page, nil
 }
 
 func (m *EventModel) Get(id int64) (*Event, error) {
@@ -85,7 +57,7 @@ func (m *EventModel) Get(id int64) (*Event, error) {
 	defer cancel()
 
 	query := sq.Select(
-		"e.id", "e.user_id", "e.name", "e.description", "e.date", "e.location", "e.created_at",
+		"e.id", "e.user_id", "e.name", "e.description", "e.date", "e.location", "e.capacity", "e.created_at",
 		"u.id", "u.name", "u.email",
 	).
 		From("events e").
@@ -101,7 +73,7 @@ func (m *EventModel) Get(id int64) (*Event, error) {
 	var event Event
 	event.User = &User{}
 
-	err = m.DB.QueryRowContext(ctx, sqlStr, args...).Scan(&event.ID, &event.UserID, &event.Name, &event.Description, &event.Date, &event.Location, &event.CreatedAt, &event.User.ID, &event.User.Name, &event.User.Email)
+	err = m.DB.QueryRowContext(ctx, sqlStr, args...).Scan(&event.ID, &event.UserID, &event.Name, &event.Description, &event.Date, &event.Location, &event.Capacity, &event.CreatedAt, &event.User.ID, &event.User.Name, &event.User.Email)
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			return nil, nil
@@ -114,21 +86,38 @@ func (m *EventModel) Get(id int64) (*Event, error) {
 	return &event, nil
 }
 
-func (m *EventModel) GetEventsByAttendeeId(attendeeId int64) ([]*Event, error) {
+func (m *EventModel) GetEventsByAttendeeId(attendeeId int64, options *ListOptions) ([]*Event, int64, error) {
+	return m.listEvents(sq.Select().From("events e").Where(sq.Eq{"e.user_id": attendeeId}), options)
+}
+
+// listEvents pages through the events matched by a filtered query without columns.
+func (m *EventModel) listEvents(filtered sq.SelectBuilder, options *ListOptions) ([]*Event, int64, error) {
+	total, err := countRows(m.DB, filtered)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	query := paginate(filtered.Columns(eventWithOrganizerColumns...).LeftJoin("users u ON e.user_id = u.id"), options, EventSortColumns, "e.id")
+
+	events, err := m.queryEventsWithOrganizer(query)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return events, total, nil
+}
+
+// eventWithOrganizerColumns are the columns queryEventsWithOrganizer scans, from events e joined with users u.
+var eventWithOrganizerColumns = []string{
+	"e.id", "e.user_id", "e.name", "e.description", "e.date", "e.location", "e.capacity", "e.created_at",
+	"u.id", "u.name", "u.email",
+}
+
+func (m *EventModel) queryEventsWithOrganizer(query sq.SelectBuilder) ([]*Event, error) {
 	ctx, cancel := utils.CreateContext()
 	defer cancel()
 
-	query := sq.Select(
-		"e.id", "e.user_id", "e.name", "e.description", "e.date", "e.location", "e.created_at",
-		"u.id", "u.name", "u.email",
-	).
-		From("events e").
-		LeftJoin("users u ON e.user_id = u.id").
-		Where(sq.Eq{"e.user_id": attendeeId}).
-		OrderBy("e.created_at ASC").
-		PlaceholderFormat(sq.Dollar)
-
-	sqlStr, args, err := query.ToSql()
+	sqlStr, args, err := query.PlaceholderFormat(sq.Dollar).ToSql()
 	if err != nil {
 		return nil, err
 	}
@@ -141,13 +130,13 @@ func (m *EventModel) GetEventsByAttendeeId(attendeeId int64) ([]*Event, error) {
 
 	defer rows.Close()
 
-	var events []*Event
+	events := []*Event{}
 
 	for rows.Next() {
 		var event Event
 		event.User = &User{}
 
-		if err := rows.Scan(&event.ID, &event.UserID, &event.Name, &event.Description, &event.Date, &event.Location, &event.CreatedAt, &event.User.ID, &event.User.Name, &event.User.Email); err != nil {
+		if err := rows.Scan(&event.ID, &event.UserID, &event.Name, &event.Description, &event.Date, &event.Location, &event.Capacity, &event.CreatedAt, &event.User.ID, &event.User.Name, &event.User.Email); err != nil {
 			return nil, err
 		}
 
@@ -161,6 +150,10 @@ func (m *EventModel) GetEventsByAttendeeId(attendeeId int64) ([]*Event, error) {
 	return events, nil
 }
 
+func eventKeyset(event *Event) Keyset {
+	return Keyset{Value: event.Date, ID: event.ID}
+}
+
 func (m *EventModel) Update(id int64, event *UpdateEventDto) (*Event, error) {
 	ctx, cancel := utils.CreateContext()
 	defer cancel()
@@ -179,8 +172,16 @@ func (m *EventModel) Update(id int64, event *UpdateEventDto) (*Event, error) {
 	if event.Location != "" {
 		query = query.Set("location", event.Location)
 	}
+	if event.Capacity != nil {
+		// Zero lifts the limit
+		if *event.Capacity == 0 {
+			query = query.Set("capacity", nil)
+		} else {
+			query = query.Set("capacity", *event.Capacity)
+		}
+	}
 
-	query = query.Where(sq.Eq{"id": id}).Suffix("RETURNING id, user_id, name, description, date, location, created_at, updated_at")
+	query = query.Where(sq.Eq{"id": id}).Suffix("RETURNING id, user_id, name, description, date, location, capacity, created_at, updated_at")
 
 	sqlStr, args, err := query.ToSql()
 	if err != nil {
@@ -199,6 +200,7 @@ func (m *EventModel) Update(id int64, event *UpdateEventDto) (*Event, error) {
 		&updated.Description,
 		&updated.Date,
 		&updated.Location,
+		&updated.Capacity,
 		&updated.CreatedAt,
 		&updated.UpdatedAt,
 	)
@@ -229,3 +231,178 @@ func (m *EventModel) Delete(id int64) error {
 
 	return nil
 }
+
+func (m *EventModel) AddCoOrganizer(eventId, userId int64) error {
+	ctx, cancel := utils.CreateContext()
+	defer cancel()
+
+	query := sq.Insert("event_organizers").
+		Columns("event_id", "user_id").
+		Values(eventId, userId).
+		Suffix("ON CONFLICT DO NOTHING").
+		PlaceholderFormat(sq.Dollar)
+
+	sqlStr, args, err := query.ToSql()
+	if err != nil {
+		return err
+	}
+
+	_, err = m.DB.ExecContext(ctx, sqlStr, args...)
+	return err
+}
+
+func (m *EventModel) RemoveCoOrganizer(eventId, userId int64) error {
+	ctx, cancel := utils.CreateContext()
+	defer cancel()
+
+	query := sq.Delete("event_organizers").
+		Where(sq.Eq{"event_id": eventId, "user_id": userId}).
+		PlaceholderFormat(sq.Dollar)
+
+	sqlStr, args, err := query.ToSql()
+	if err != nil {
+		return err
+	}
+
+	_, err = m.DB.ExecContext(ctx, sqlStr, args...)
+	return err
+}
+
+func (m *EventModel) IsCoOrganizer(eventId, userId int64) (bool, error) {
+	ctx, cancel := utils.CreateContext()
+	defer cancel()
+
+	query := sq.Select("1").
+		Prefix("SELECT EXISTS (").
+		From("event_organizers").
+		Where(sq.Eq{"event_id": eventId, "user_id": userId}).
+		Suffix(")").
+		PlaceholderFormat(sq.Dollar)
+
+	sqlStr, args, err := query.ToSql()
+	if err != nil {
+		return false, err
+	}
+
+	var exists bool
+	err = m.DB.QueryRowContext(ctx, sqlStr, args...).Scan(&exists)
+	if err != nil {
+		return false, err
+	}
+
+	return exists, nil
+}
+
+// GetOrganizedEventIDs returns the ids of the events the user owns or co-organizes.
+func (m *EventModel) GetOrganizedEventIDs(userId int64) (map[int64]bool, error) {
+	ctx, cancel := utils.CreateContext()
+	defer cancel()
+
+	query := sq.Select("id").
+		From("events").
+		Where(sq.Eq{"user_id": userId}).
+		Suffix("UNION SELECT event_id FROM event_organizers WHERE user_id = ?", userId).
+		PlaceholderFormat(sq.Dollar)
+
+	sqlStr, args, err := query.ToSql()
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := m.DB.QueryContext(ctx, sqlStr, args...)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	ids := map[int64]bool{}
+
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids[id] = true
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return ids, nil
+}
+
+// GetByOrganizer returns the events the user created.
+func (m *EventModel) GetByOrganizer(userId int64) ([]*Event, error) {
+	return m.queryEvents(
+		sq.Select("e.id", "e.user_id", "e.name", "e.description", "e.date", "e.location", "e.capacity", "e.created_at").
+			From("events e").
+			Where(sq.Eq{"e.user_id": userId}).
+			OrderBy("e.date ASC"),
+	)
+}
+
+// GetAttendedByUser returns the events the user is registered to attend.
+func (m *EventModel) GetAttendedByUser(userId int64) ([]*Event, error) {
+	return m.queryEvents(
+		sq.Select("e.id", "e.user_id", "e.name", "e.description", "e.date", "e.location", "e.capacity", "e.created_at").
+			From("events e").
+			Join("attendees a ON a.event_id = e.id").
+			Where(sq.Eq{"a.user_id": userId}).
+			OrderBy("e.date ASC"),
+	)
+}
+
+// GetFeed returns the page of upcoming events of the organizers the user follows
+// that the options' keyset points at. Events are ordered by date, then id.
+func (m *EventModel) GetFeed(userId int64, options *ListOptions) ([]*Event, *KeysetPage, error) {
+	query := sq.Select("e.id", "e.user_id", "e.name", "e.description", "e.date", "e.location", "e.capacity", "e.created_at").
+		From("events e").
+		Join("follows f ON f.followee_id = e.user_id").
+		Where(sq.Eq{"f.follower_id": userId}).
+		Where(sq.GtOrEq{"e.date": time.Now()})
+
+	events, err := m.queryEvents(seekPage(query, options, "e.date", "e.id"))
+	if err != nil {
+		return nil, nil, err
+	}
+
+	events, page := keysetPage(events, options, eventKeyset)
+	return events, page, nil
+}
+
+func (m *EventModel) queryEvents(query sq.SelectBuilder) ([]*Event, error) {
+	ctx, cancel := utils.CreateContext()
+	defer cancel()
+
+	sqlStr, args, err := query.PlaceholderFormat(sq.Dollar).ToSql()
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := m.DB.QueryContext(ctx, sqlStr, args...)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	events := []*Event{}
+
+	for rows.Next() {
+		var event Event
+
+		if err := rows.Scan(&event.ID, &event.UserID, &event.Name, &event.Description, &event.Date, &event.Location, &event.Capacity, &event.CreatedAt); err != nil {
+			return nil, err
+		}
+
+		events = append(events, &event)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return events, nil
+}
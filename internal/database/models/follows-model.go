@@ -0,0 +1,17 @@
+package models
+
+import (
+	"database/sql"
+	"time"
+)
+
+type FollowModel struct {
+	DB *sql.DB
+}
+
+// Follow records that a user follows an organizer to see their new events in the feed.
+type Follow struct {
+	FollowerID int64      `db:"follower_id" json:"followerId"`
+	FolloweeID int64      `db:"followee_id" json:"followeeId"`
+	CreatedAt  *time.Time `db:"created_at" json:"createdAt,omitempty"`
+}
@@ -0,0 +1,69 @@
+package models
+
+import (
+	sq "github.com/Masterminds/squirrel"
+	"github.com/vickon16/go-gin-rest-api/internal/utils"
+)
+
+// Insert follows the organizer. Following someone already followed is not an error,
+// the returned bool tells whether a new follow was created.
+func (m *FollowModel) Insert(followerId, followeeId int64) (bool, error) {
+	ctx, cancel := utils.CreateContext()
+	defer cancel()
+
+	query := sq.Insert("follows").
+		Columns("follower_id", "followee_id").
+		Values(followerId, followeeId).
+		Suffix("ON CONFLICT (follower_id, followee_id) DO NOTHING").
+		PlaceholderFormat(sq.Dollar)
+
+	sqlStr, args, err := query.ToSql()
+	if err != nil {
+		return false, err
+	}
+
+	result, err := m.DB.ExecContext(ctx, sqlStr, args...)
+	if err != nil {
+		return false, err
+	}
+
+	affected, err := result.RowsAffected()
+	return affected > 0, err
+}
+
+// Delete unfollows the organizer and reports whether there was a follow to remove.
+func (m *FollowModel) Delete(followerId, followeeId int64) (bool, error) {
+	ctx, cancel := utils.CreateContext()
+	defer cancel()
+
+	query := sq.Delete("follows").
+		Where(sq.Eq{"follower_id": followerId, "followee_id": followeeId}).
+		PlaceholderFormat(sq.Dollar)
+
+	sqlStr, args, err := query.ToSql()
+	if err != nil {
+		return false, err
+	}
+
+	result, err := m.DB.ExecContext(ctx, sqlStr, args...)
+	if err != nil {
+		return false, err
+	}
+
+	affected, err := result.RowsAffected()
+	return affected > 0, err
+}
+
+// GetFollowers returns one page of the users following the user.
+func (m *FollowModel) GetFollowers(userId int64, options *ListOptions) ([]*User, int64, error) {
+	return m.listUsers(sq.Expr("id IN (SELECT follower_id FROM follows WHERE followee_id = ?)", userId), options)
+}
+
+// GetFollowing returns one page of the users the user follows.
+func (m *FollowModel) GetFollowing(userId int64, options *ListOptions) ([]*User, int64, error) {
+	return m.listUsers(sq.Expr("id IN (SELECT followee_id FROM follows WHERE follower_id = ?)", userId), options)
+}
+
+func (m *FollowModel) listUsers(condition sq.Sqlizer, options *ListOptions) ([]*User, int64, error) {
+	return listUsers(m.DB, sq.Select().From("users").Where(condition).Where(sq.Eq{"deleted_at": nil}), options)
+}
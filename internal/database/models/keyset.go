@@ -0,0 +1,92 @@
+package models
+
+import (
+	"slices"
+	"time"
+
+	sq "github.com/Masterminds/squirrel"
+)
+
+// Keyset is a position in a list ordered by a time column and the id, e.g. (date, id).
+// Unlike an offset it stays valid while rows are added or removed before it.
+type Keyset struct {
+	Value time.Time `json:"v"`
+	ID    int64     `json:"i"`
+	// Whether the page is the one before the position, rather than after it
+	Before bool `json:"b,omitempty"`
+	// Sort direction of the list the position was taken from
+	Desc bool `json:"d,omitempty"`
+}
+
+// KeysetPage holds the positions of the pages around a keyset paginated page. Nil when there is no such page.
+type KeysetPage struct {
+	Next *Keyset
+	Prev *Keyset
+}
+
+// seekPage orders the query by (column, idColumn) and seeks to the page the options' keyset points at.
+// It asks for one row more than the limit, which keysetPage uses to tell whether more pages follow.
+func seekPage(query sq.SelectBuilder, options *ListOptions, column, idColumn string) sq.SelectBuilder {
+	cursor := options.Keyset
+
+	// The page before a position is read backwards from it, then put back in order
+	reverse := options.Desc != (cursor != nil && cursor.Before)
+
+	direction, comparison := " ASC", ">"
+	if reverse {
+		direction, comparison = " DESC", "<"
+	}
+
+	if cursor != nil {
+		query = query.Where(sq.Expr("("+column+", "+idColumn+") "+comparison+" (?, ?)", cursor.Value, cursor.ID))
+	}
+
+	return query.OrderBy(column+direction, idColumn+direction).Limit(uint64(options.Limit + 1))
+}
+
+// keysetPage trims the rows of a seekPage query to the page and works out the positions of the pages around it.
+func keysetPage[T any](rows []T, options *ListOptions, key func(T) Keyset) ([]T, *KeysetPage) {
+	cursor := options.Keyset
+	backward := cursor != nil && cursor.Before
+
+	hasMore := len(rows) > options.Limit
+	if hasMore {
+		rows = rows[:options.Limit]
+	}
+	if backward {
+		slices.Reverse(rows)
+	}
+
+	page := &KeysetPage{}
+	position := func(row T, before bool) *Keyset {
+		keyset := key(row)
+		keyset.Before = before
+		keyset.Desc = options.Desc
+		return &keyset
+	}
+
+	// Every row around the position was removed since the cursor was issued
+	if len(rows) == 0 {
+		return rows, page
+	}
+
+	first, last := rows[0], rows[len(rows)-1]
+
+	// Extra rows mean more pages in the direction of travel,
+	// coming from a cursor means there are rows on the side we came from
+	if backward {
+		if hasMore {
+			page.Prev = position(first, true)
+		}
+		page.Next = position(last, false)
+	} else {
+		if hasMore {
+			page.Next = position(last, false)
+		}
+		if cursor != nil {
+			page.Prev = position(first, true)
+		}
+	}
+
+	return rows, page
+}
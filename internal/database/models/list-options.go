@@ -0,0 +1,81 @@
+package models
+
+import (
+	"database/sql"
+	"strings"
+
+	sq "github.com/Masterminds/squirrel"
+	"github.com/vickon16/go-gin-rest-api/internal/utils"
+)
+
+const (
+	DefaultListLimit = 20
+	MaxListLimit     = 100
+	// Deeper pages are refused, the database would have to read and skip every row before them
+	MaxListOffset = 10000
+)
+
+// ListOptions is the page and order of a list query, parsed from the query string by the services.
+type ListOptions struct {
+	Page  int
+	Limit int
+	// Sort field as named in the API, each repository maps it to a column
+	Sort string
+	Desc bool
+	// Position in lists paginated by cursor instead of page, nil for the first page
+	Keyset *Keyset
+}
+
+func NewListOptions() *ListOptions {
+	return &ListOptions{Page: 1, Limit: DefaultListLimit}
+}
+
+// Offset is the number of rows before the page, at most MaxListOffset.
+func (o *ListOptions) Offset() uint64 {
+	if o.Page < 1 || o.Limit < 1 {
+		return 0
+	}
+	if o.Page-1 > MaxListOffset/o.Limit {
+		return MaxListOffset
+	}
+	return uint64((o.Page - 1) * o.Limit)
+}
+
+// paginate orders the query by the sort column, with the id breaking ties, and limits it to the page.
+// Unknown sort fields fall back to the id.
+func paginate(query sq.SelectBuilder, options *ListOptions, sortColumns map[string]string, idColumn string) sq.SelectBuilder {
+	direction := " ASC"
+	if options.Desc {
+		direction = " DESC"
+	}
+
+	if column, ok := sortColumns[options.Sort]; ok && column != idColumn {
+		query = query.OrderBy(column + direction)
+	}
+
+	return query.OrderBy(idColumn + direction).
+		Limit(uint64(options.Limit)).
+		Offset(options.Offset())
+}
+
+// countRows counts the rows matched by a query built without columns, so it can share the filters of the list query.
+func countRows(db *sql.DB, query sq.SelectBuilder) (int64, error) {
+	ctx, cancel := utils.CreateContext()
+	defer cancel()
+
+	sqlStr, args, err := query.Columns("COUNT(*)").PlaceholderFormat(sq.Dollar).ToSql()
+	if err != nil {
+		return 0, err
+	}
+
+	var total int64
+	err = db.QueryRowContext(ctx, sqlStr, args...).Scan(&total)
+	return total, err
+}
+
+var likeEscaper = strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`)
+
+// escapeLike makes user input match literally inside a LIKE pattern.
+func escapeLike(value string) string {
+	return likeEscaper.Replace(value)
+}
@@ -0,0 +1,29 @@
+package models
+
+import (
+	"math"
+	"testing"
+)
+
+func TestListOptionsOffset(t *testing.T) {
+	tests := []struct {
+		page  int
+		limit int
+		want  uint64
+	}{
+		{page: 1, limit: 20, want: 0},
+		{page: 3, limit: 20, want: 40},
+		{page: 501, limit: 20, want: MaxListOffset},
+		{page: 502, limit: 20, want: MaxListOffset},
+		// Large enough to overflow (page - 1) * limit
+		{page: math.MaxInt, limit: MaxListLimit, want: MaxListOffset},
+		{page: 0, limit: 20, want: 0},
+	}
+
+	for _, tt := range tests {
+		options := &ListOptions{Page: tt.page, Limit: tt.limit}
+		if got := options.Offset(); got != tt.want {
+			t.Errorf("Offset() with page %d and limit %d = %d, want %d", tt.page, tt.limit, got, tt.want)
+		}
+	}
+}
@@ -3,15 +3,31 @@ package models
 import "database/sql"
 
 type Models struct {
-	Users     UserModel
-	Events    EventModel
-	Attendees AttendeesModel
+	Users         UserModel
+	Events        EventModel
+	Attendees     AttendeesModel
+	RefreshTokens RefreshTokenModel
+	AuditLogs     AuditLogModel
+	OneTimeTokens OneTimeTokenModel
+	RecoveryCodes RecoveryCodeModel
+	APIKeys       APIKeyModel
+	Identities    UserIdentityModel
+	Sessions      SessionModel
+	Follows       FollowModel
 }
 
 func NewModels(db *sql.DB) Models {
 	return Models{
-		Users:     UserModel{DB: db},
-		Events:    EventModel{DB: db},
-		Attendees: AttendeesModel{DB: db},
+		Users:         UserModel{DB: db},
+		Events:        EventModel{DB: db},
+		Attendees:     AttendeesModel{DB: db},
+		RefreshTokens: RefreshTokenModel{DB: db},
+		AuditLogs:     AuditLogModel{DB: db},
+		OneTimeTokens: OneTimeTokenModel{DB: db},
+		RecoveryCodes: RecoveryCodeModel{DB: db},
+		APIKeys:       APIKeyModel{DB: db},
+		Identities:    UserIdentityModel{DB: db},
+		Sessions:      SessionModel{DB: db},
+		Follows:       FollowModel{DB: db},
 	}
 }
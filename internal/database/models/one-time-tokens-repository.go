@@ -0,0 +1,116 @@
+package models
+
+import (
+	"database/sql"
+	"errors"
+	"time"
+
+	sq "github.com/Masterminds/squirrel"
+	"github.com/vickon16/go-gin-rest-api/internal/utils"
+)
+
+func (m *OneTimeTokenModel) Insert(token *OneTimeToken) error {
+	ctx, cancel := utils.CreateContext()
+	defer cancel()
+
+	query := sq.Insert("one_time_tokens").
+		Columns("user_id", "purpose", "token_hash", "expires_at").
+		Values(token.UserID, token.Purpose, token.TokenHash, token.ExpiresAt).
+		Suffix("RETURNING id, created_at").
+		PlaceholderFormat(sq.Dollar)
+
+	sqlStr, args, err := query.ToSql()
+	if err != nil {
+		return err
+	}
+
+	return m.DB.QueryRowContext(ctx, sqlStr, args...).Scan(&token.ID, &token.CreatedAt)
+}
+
+// Consume marks an unused, unexpired token as used and returns it.
+// It returns nil when the token is unknown, expired or already used.
+func (m *OneTimeTokenModel) Consume(tokenHash, purpose string) (*OneTimeToken, error) {
+	ctx, cancel := utils.CreateContext()
+	defer cancel()
+
+	now := time.Now()
+	query := sq.Update("one_time_tokens").
+		Set("used_at", now).
+		Where(sq.Eq{"token_hash": tokenHash, "purpose": purpose, "used_at": nil}).
+		Where(sq.Gt{"expires_at": now}).
+		Suffix("RETURNING id, user_id, purpose, token_hash, expires_at, used_at, created_at").
+		PlaceholderFormat(sq.Dollar)
+
+	sqlStr, args, err := query.ToSql()
+	if err != nil {
+		return nil, err
+	}
+
+	var token OneTimeToken
+	err = m.DB.QueryRowContext(ctx, sqlStr, args...).Scan(
+		&token.ID, &token.UserID, &token.Purpose, &token.TokenHash,
+		&token.ExpiresAt, &token.UsedAt, &token.CreatedAt,
+	)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+
+		// For other errors
+		return nil, err
+	}
+
+	return &token, nil
+}
+
+// GetPending returns the token when it is unused and unexpired, without using it up.
+func (m *OneTimeTokenModel) GetPending(tokenHash, purpose string) (*OneTimeToken, error) {
+	ctx, cancel := utils.CreateContext()
+	defer cancel()
+
+	query := sq.Select("id", "user_id", "purpose", "token_hash", "expires_at", "used_at", "created_at").
+		From("one_time_tokens").
+		Where(sq.Eq{"token_hash": tokenHash, "purpose": purpose, "used_at": nil}).
+		Where(sq.Gt{"expires_at": time.Now()}).
+		PlaceholderFormat(sq.Dollar)
+
+	sqlStr, args, err := query.ToSql()
+	if err != nil {
+		return nil, err
+	}
+
+	var token OneTimeToken
+	err = m.DB.QueryRowContext(ctx, sqlStr, args...).Scan(
+		&token.ID, &token.UserID, &token.Purpose, &token.TokenHash,
+		&token.ExpiresAt, &token.UsedAt, &token.CreatedAt,
+	)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+
+		// For other errors
+		return nil, err
+	}
+
+	return &token, nil
+}
+
+// InvalidateForUser marks every pending token of the given purpose as used.
+func (m *OneTimeTokenModel) InvalidateForUser(userId int64, purpose string) error {
+	ctx, cancel := utils.CreateContext()
+	defer cancel()
+
+	query := sq.Update("one_time_tokens").
+		Set("used_at", time.Now()).
+		Where(sq.Eq{"user_id": userId, "purpose": purpose, "used_at": nil}).
+		PlaceholderFormat(sq.Dollar)
+
+	sqlStr, args, err := query.ToSql()
+	if err != nil {
+		return err
+	}
+
+	_, err = m.DB.ExecContext(ctx, sqlStr, args...)
+	return err
+}
@@ -0,0 +1,81 @@
+package models
+
+import (
+	"time"
+
+	sq "github.com/Masterminds/squirrel"
+	"github.com/vickon16/go-gin-rest-api/internal/utils"
+)
+
+// ReplaceForUser deletes the user's existing codes and stores the new hashes.
+func (m *RecoveryCodeModel) ReplaceForUser(userId int64, codeHashes []string) error {
+	ctx, cancel := utils.CreateContext()
+	defer cancel()
+
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	deleteQuery := sq.Delete("recovery_codes").
+		Where(sq.Eq{"user_id": userId}).
+		PlaceholderFormat(sq.Dollar)
+
+	sqlStr, args, err := deleteQuery.ToSql()
+	if err != nil {
+		return err
+	}
+
+	if _, err := tx.ExecContext(ctx, sqlStr, args...); err != nil {
+		return err
+	}
+
+	if len(codeHashes) > 0 {
+		insertQuery := sq.Insert("recovery_codes").
+			Columns("user_id", "code_hash").
+			PlaceholderFormat(sq.Dollar)
+		for _, hash := range codeHashes {
+			insertQuery = insertQuery.Values(userId, hash)
+		}
+
+		sqlStr, args, err = insertQuery.ToSql()
+		if err != nil {
+			return err
+		}
+
+		if _, err := tx.ExecContext(ctx, sqlStr, args...); err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+// Consume marks an unused code as used. It reports false when no such code exists.
+func (m *RecoveryCodeModel) Consume(userId int64, codeHash string) (bool, error) {
+	ctx, cancel := utils.CreateContext()
+	defer cancel()
+
+	query := sq.Update("recovery_codes").
+		Set("used_at", time.Now()).
+		Where(sq.Eq{"user_id": userId, "code_hash": codeHash, "used_at": nil}).
+		PlaceholderFormat(sq.Dollar)
+
+	sqlStr, args, err := query.ToSql()
+	if err != nil {
+		return false, err
+	}
+
+	result, err := m.DB.ExecContext(ctx, sqlStr, args...)
+	if err != nil {
+		return false, err
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+
+	return affected > 0, nil
+}
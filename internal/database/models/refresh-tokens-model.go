@@ -0,0 +1,25 @@
+package models
+
+import (
+	"database/sql"
+	"time"
+)
+
+type RefreshTokenModel struct {
+	DB *sql.DB
+}
+
+type RefreshToken struct {
+	ID        int64      `db:"id" json:"id"`
+	UserID    int64      `db:"user_id" json:"userId"`
+	TokenHash string     `db:"token_hash" json:"-"`
+	FamilyID  string     `db:"family_id" json:"familyId"`
+	ExpiresAt time.Time  `db:"expires_at" json:"expiresAt"`
+	UsedAt    *time.Time `db:"used_at" json:"usedAt,omitempty"`
+	RevokedAt *time.Time `db:"revoked_at" json:"revokedAt,omitempty"`
+	BaseModel
+}
+
+type RefreshTokenDto struct {
+	RefreshToken string `json:"refreshToken" binding:"required"`
+}
@@ -0,0 +1,127 @@
+package models
+
+import (
+	"database/sql"
+	"errors"
+	"time"
+
+	sq "github.com/Masterminds/squirrel"
+	"github.com/vickon16/go-gin-rest-api/internal/utils"
+)
+
+func (m *RefreshTokenModel) Insert(token *RefreshToken) error {
+	ctx, cancel := utils.CreateContext()
+	defer cancel()
+
+	query := sq.Insert("refresh_tokens").
+		Columns("user_id", "token_hash", "family_id", "expires_at").
+		Values(token.UserID, token.TokenHash, token.FamilyID, token.ExpiresAt).
+		Suffix("RETURNING id, created_at").
+		PlaceholderFormat(sq.Dollar)
+
+	sqlStr, args, err := query.ToSql()
+	if err != nil {
+		return err
+	}
+
+	return m.DB.QueryRowContext(ctx, sqlStr, args...).Scan(&token.ID, &token.CreatedAt)
+}
+
+func (m *RefreshTokenModel) GetByHash(tokenHash string) (*RefreshToken, error) {
+	ctx, cancel := utils.CreateContext()
+	defer cancel()
+
+	query := sq.Select("id", "user_id", "token_hash", "family_id", "expires_at", "used_at", "revoked_at", "created_at").
+		From("refresh_tokens").
+		Where(sq.Eq{"token_hash": tokenHash}).
+		PlaceholderFormat(sq.Dollar)
+
+	sqlStr, args, err := query.ToSql()
+	if err != nil {
+		return nil, err
+	}
+
+	var token RefreshToken
+	err = m.DB.QueryRowContext(ctx, sqlStr, args...).Scan(
+		&token.ID, &token.UserID, &token.TokenHash, &token.FamilyID,
+		&token.ExpiresAt, &token.UsedAt, &token.RevokedAt, &token.CreatedAt,
+	)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+
+		// For other errors
+		return nil, err
+	}
+
+	return &token, nil
+}
+
+// MarkUsed flags the token as consumed. It only succeeds for a token that is
+// still active, so two concurrent refreshes with the same token cannot both win.
+func (m *RefreshTokenModel) MarkUsed(id int64) (bool, error) {
+	ctx, cancel := utils.CreateContext()
+	defer cancel()
+
+	query := sq.Update("refresh_tokens").
+		Set("used_at", time.Now()).
+		Set("updated_at", time.Now()).
+		Where(sq.Eq{"id": id, "used_at": nil, "revoked_at": nil}).
+		PlaceholderFormat(sq.Dollar)
+
+	sqlStr, args, err := query.ToSql()
+	if err != nil {
+		return false, err
+	}
+
+	result, err := m.DB.ExecContext(ctx, sqlStr, args...)
+	if err != nil {
+		return false, err
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+
+	return affected == 1, nil
+}
+
+func (m *RefreshTokenModel) RevokeFamily(familyId string) error {
+	ctx, cancel := utils.CreateContext()
+	defer cancel()
+
+	query := sq.Update("refresh_tokens").
+		Set("revoked_at", time.Now()).
+		Set("updated_at", time.Now()).
+		Where(sq.Eq{"family_id": familyId, "revoked_at": nil}).
+		PlaceholderFormat(sq.Dollar)
+
+	sqlStr, args, err := query.ToSql()
+	if err != nil {
+		return err
+	}
+
+	_, err = m.DB.ExecContext(ctx, sqlStr, args...)
+	return err
+}
+
+func (m *RefreshTokenModel) RevokeAllForUser(userId int64) error {
+	ctx, cancel := utils.CreateContext()
+	defer cancel()
+
+	query := sq.Update("refresh_tokens").
+		Set("revoked_at", time.Now()).
+		Set("updated_at", time.Now()).
+		Where(sq.Eq{"user_id": userId, "revoked_at": nil}).
+		PlaceholderFormat(sq.Dollar)
+
+	sqlStr, args, err := query.ToSql()
+	if err != nil {
+		return err
+	}
+
+	_, err = m.DB.ExecContext(ctx, sqlStr, args...)
+	return err
+}
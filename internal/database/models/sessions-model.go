@@ -0,0 +1,44 @@
+package models
+
+import (
+	"database/sql"
+	"time"
+)
+
+type SessionModel struct {
+	DB *sql.DB
+}
+
+// Session is one login on one device. It lives as long as its refresh token family.
+type Session struct {
+	ID         int64      `db:"id" json:"id"`
+	UserID     int64      `db:"user_id" json:"userId"`
+	FamilyID   string     `db:"family_id" json:"-"`
+	UserAgent  string     `db:"user_agent" json:"userAgent"`
+	IPAddress  string     `db:"ip_address" json:"ipAddress"`
+	LastSeenAt *time.Time `db:"last_seen_at" json:"lastSeenAt,omitempty"`
+	ExpiresAt  time.Time  `db:"expires_at" json:"expiresAt"`
+	RevokedAt  *time.Time `db:"revoked_at" json:"revokedAt,omitempty"`
+	BaseModel
+}
+
+type SessionSerializer struct {
+	ID         int64      `json:"id"`
+	UserAgent  string     `json:"userAgent"`
+	IPAddress  string     `json:"ipAddress"`
+	LastSeenAt *time.Time `json:"lastSeenAt,omitempty"`
+	ExpiresAt  time.Time  `json:"expiresAt"`
+	Current    bool       `json:"current"`
+	BaseModel
+}
+
+func CreateResponseSession(session *Session) SessionSerializer {
+	return SessionSerializer{
+		ID:         session.ID,
+		UserAgent:  session.UserAgent,
+		IPAddress:  session.IPAddress,
+		LastSeenAt: session.LastSeenAt,
+		ExpiresAt:  session.ExpiresAt,
+		BaseModel:  BaseModel{CreatedAt: session.CreatedAt, UpdatedAt: session.UpdatedAt},
+	}
+}
@@ -0,0 +1,180 @@
+package models
+
+import (
+	"database/sql"
+	"errors"
+	"time"
+
+	sq "github.com/Masterminds/squirrel"
+	"github.com/vickon16/go-gin-rest-api/internal/utils"
+)
+
+var sessionColumns = []string{"id", "user_id", "family_id", "user_agent", "ip_address", "last_seen_at", "expires_at", "revoked_at", "created_at", "updated_at"}
+
+func sessionScanFields(session *Session) []any {
+	return []any{
+		&session.ID, &session.UserID, &session.FamilyID, &session.UserAgent, &session.IPAddress,
+		&session.LastSeenAt, &session.ExpiresAt, &session.RevokedAt, &session.CreatedAt, &session.UpdatedAt,
+	}
+}
+
+func (m *SessionModel) Insert(session *Session) error {
+	ctx, cancel := utils.CreateContext()
+	defer cancel()
+
+	query := sq.Insert("sessions").
+		Columns("user_id", "family_id", "user_agent", "ip_address", "last_seen_at", "expires_at").
+		Values(session.UserID, session.FamilyID, session.UserAgent, session.IPAddress, session.LastSeenAt, session.ExpiresAt).
+		Suffix("RETURNING id, created_at, updated_at").
+		PlaceholderFormat(sq.Dollar)
+
+	sqlStr, args, err := query.ToSql()
+	if err != nil {
+		return err
+	}
+
+	return m.DB.QueryRowContext(ctx, sqlStr, args...).Scan(&session.ID, &session.CreatedAt, &session.UpdatedAt)
+}
+
+// GetActiveByUser lists the sessions of a user that are neither revoked nor expired.
+func (m *SessionModel) GetActiveByUser(userId int64) ([]*Session, error) {
+	ctx, cancel := utils.CreateContext()
+	defer cancel()
+
+	query := sq.Select(sessionColumns...).
+		From("sessions").
+		Where(sq.Eq{"user_id": userId, "revoked_at": nil}).
+		Where(sq.Gt{"expires_at": time.Now()}).
+		OrderBy("created_at DESC").
+		PlaceholderFormat(sq.Dollar)
+
+	sqlStr, args, err := query.ToSql()
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := m.DB.QueryContext(ctx, sqlStr, args...)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	var sessions []*Session
+
+	for rows.Next() {
+		var session Session
+
+		if err := rows.Scan(sessionScanFields(&session)...); err != nil {
+			return nil, err
+		}
+
+		sessions = append(sessions, &session)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return sessions, nil
+}
+
+func (m *SessionModel) GetByFamily(familyId string) (*Session, error) {
+	return m.getOne(sq.Eq{"family_id": familyId})
+}
+
+func (m *SessionModel) Get(id, userId int64) (*Session, error) {
+	return m.getOne(sq.Eq{"id": id, "user_id": userId})
+}
+
+func (m *SessionModel) getOne(where sq.Eq) (*Session, error) {
+	ctx, cancel := utils.CreateContext()
+	defer cancel()
+
+	query := sq.Select(sessionColumns...).
+		From("sessions").
+		Where(where).
+		PlaceholderFormat(sq.Dollar)
+
+	sqlStr, args, err := query.ToSql()
+	if err != nil {
+		return nil, err
+	}
+
+	var session Session
+	err = m.DB.QueryRowContext(ctx, sqlStr, args...).Scan(sessionScanFields(&session)...)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+
+		return nil, err
+	}
+
+	return &session, nil
+}
+
+// Extend records a token refresh, which moves both the last seen time and the expiry.
+func (m *SessionModel) Extend(id int64, expiresAt time.Time) error {
+	ctx, cancel := utils.CreateContext()
+	defer cancel()
+
+	query := sq.Update("sessions").
+		Set("last_seen_at", time.Now()).
+		Set("expires_at", expiresAt).
+		Set("updated_at", time.Now()).
+		Where(sq.Eq{"id": id}).
+		PlaceholderFormat(sq.Dollar)
+
+	sqlStr, args, err := query.ToSql()
+	if err != nil {
+		return err
+	}
+
+	_, err = m.DB.ExecContext(ctx, sqlStr, args...)
+	return err
+}
+
+// Revoke ends one session of the user. It returns false when there was no active session to revoke.
+func (m *SessionModel) Revoke(id, userId int64) (bool, error) {
+	return m.revoke(sq.Eq{"id": id, "user_id": userId})
+}
+
+func (m *SessionModel) RevokeFamily(familyId string) error {
+	_, err := m.revoke(sq.Eq{"family_id": familyId})
+	return err
+}
+
+func (m *SessionModel) RevokeAllForUser(userId int64) error {
+	_, err := m.revoke(sq.Eq{"user_id": userId})
+	return err
+}
+
+func (m *SessionModel) revoke(where sq.Eq) (bool, error) {
+	ctx, cancel := utils.CreateContext()
+	defer cancel()
+
+	query := sq.Update("sessions").
+		Set("revoked_at", time.Now()).
+		Set("updated_at", time.Now()).
+		Where(where).
+		Where(sq.Eq{"revoked_at": nil}).
+		PlaceholderFormat(sq.Dollar)
+
+	sqlStr, args, err := query.ToSql()
+	if err != nil {
+		return false, err
+	}
+
+	result, err := m.DB.ExecContext(ctx, sqlStr, args...)
+	if err != nil {
+		return false, err
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+
+	return affected > 0, nil
+}
@@ -0,0 +1,24 @@
+package models
+
+import "database/sql"
+
+type UserIdentityModel struct {
+	DB *sql.DB
+}
+
+// UserIdentity links a user to an account at an external OIDC provider.
+type UserIdentity struct {
+	ID       int64  `db:"id" json:"id"`
+	UserID   int64  `db:"user_id" json:"userId"`
+	Provider string `db:"provider" json:"provider"`
+	Subject  string `db:"subject" json:"subject"`
+	Email    string `db:"email" json:"email"`
+	BaseModel
+}
+
+type OIDCCallbackDto struct {
+	Code             string `form:"code"`
+	State            string `form:"state" binding:"required"`
+	Error            string `form:"error"`
+	ErrorDescription string `form:"error_description"`
+}
@@ -0,0 +1,101 @@
+package models
+
+import (
+	"database/sql"
+	"errors"
+
+	sq "github.com/Masterminds/squirrel"
+	"github.com/vickon16/go-gin-rest-api/internal/utils"
+)
+
+func (m *UserIdentityModel) Insert(identity *UserIdentity) error {
+	ctx, cancel := utils.CreateContext()
+	defer cancel()
+
+	query := sq.Insert("user_identities").
+		Columns("user_id", "provider", "subject", "email").
+		Values(identity.UserID, identity.Provider, identity.Subject, identity.Email).
+		Suffix("RETURNING id, created_at, updated_at").
+		PlaceholderFormat(sq.Dollar)
+
+	sqlStr, args, err := query.ToSql()
+	if err != nil {
+		return err
+	}
+
+	return m.DB.QueryRowContext(ctx, sqlStr, args...).Scan(&identity.ID, &identity.CreatedAt, &identity.UpdatedAt)
+}
+
+func (m *UserIdentityModel) GetByProviderSubject(provider, subject string) (*UserIdentity, error) {
+	ctx, cancel := utils.CreateContext()
+	defer cancel()
+
+	query := sq.Select("id", "user_id", "provider", "subject", "email", "created_at", "updated_at").
+		From("user_identities").
+		Where(sq.Eq{"provider": provider, "subject": subject}).
+		PlaceholderFormat(sq.Dollar)
+
+	sqlStr, args, err := query.ToSql()
+	if err != nil {
+		return nil, err
+	}
+
+	var identity UserIdentity
+	err = m.DB.QueryRowContext(ctx, sqlStr, args...).Scan(
+		&identity.ID, &identity.UserID, &identity.Provider, &identity.Subject,
+		&identity.Email, &identity.CreatedAt, &identity.UpdatedAt,
+	)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+
+		return nil, err
+	}
+
+	return &identity, nil
+}
+
+func (m *UserIdentityModel) GetByUser(userId int64) ([]*UserIdentity, error) {
+	ctx, cancel := utils.CreateContext()
+	defer cancel()
+
+	query := sq.Select("id", "user_id", "provider", "subject", "email", "created_at", "updated_at").
+		From("user_identities").
+		Where(sq.Eq{"user_id": userId}).
+		OrderBy("created_at ASC").
+		PlaceholderFormat(sq.Dollar)
+
+	sqlStr, args, err := query.ToSql()
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := m.DB.QueryContext(ctx, sqlStr, args...)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	var identities []*UserIdentity
+
+	for rows.Next() {
+		var identity UserIdentity
+
+		if err := rows.Scan(
+			&identity.ID, &identity.UserID, &identity.Provider, &identity.Subject,
+			&identity.Email, &identity.CreatedAt, &identity.UpdatedAt,
+		); err != nil {
+			return nil, err
+		}
+
+		identities = append(identities, &identity)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return identities, nil
+}
@@ -2,41 +2,186 @@ package models
 
 import (
 	"database/sql"
+	"time"
+
+	sq "github.com/Masterminds/squirrel"
 )
 
 type UserModel struct {
 	DB *sql.DB
 }
 
+const (
+	RoleUser      = "user"
+	RoleOrganizer = "organizer"
+	RoleAdmin     = "admin"
+)
+
+// DeletedUserName replaces the name of an anonymized account.
+const DeletedUserName = "Deleted user"
+
 type User struct {
-	ID       int64  `db:"id" json:"id"`
-	Email    string `db:"email" json:"email" binding:"required,email"`
-	Name     string `db:"name" json:"name" binding:"required,min=2,max=100"`
-	Password string `db:"password" json:"-" binding:"required,min=6"`
+	ID              int64      `db:"id" json:"id"`
+	Email           string     `db:"email" json:"email" binding:"required,email"`
+	Name            string     `db:"name" json:"name" binding:"required,min=2,max=100"`
+	Password        string     `db:"password" json:"-" binding:"required,min=6"`
+	Role            string     `db:"role" json:"role"`
+	EmailVerifiedAt *time.Time `db:"email_verified_at" json:"emailVerifiedAt,omitempty"`
+	TOTPEnabledAt   *time.Time `db:"totp_enabled_at" json:"totpEnabledAt,omitempty"`
+
+	DeletionRequestedAt *time.Time `db:"deletion_requested_at" json:"deletionRequestedAt,omitempty"`
+	DeletedAt           *time.Time `db:"deleted_at" json:"deletedAt,omitempty"`
+
+	// Profile
+	DisplayName string  `db:"display_name" json:"displayName"`
+	Bio         string  `db:"bio" json:"bio"`
+	Timezone    string  `db:"timezone" json:"timezone"`
+	Locale      string  `db:"locale" json:"locale"`
+	Avatar      *Avatar `db:"avatar" json:"avatar,omitempty"`
 	BaseModel
 }
 
 type UpdateUserDto struct {
 	Email    string `json:"email,omitempty" binding:"omitempty,email"`
 	Name     string `json:"name,omitempty" binding:"omitempty,min=3"`
-	Password string `json:"password,omitempty" binding:"omitempty,min=6,max=64"`
+	Password string `json:"password,omitempty"`
+
+	// Required to set a new password or change the email
+	CurrentPassword string `json:"currentPassword,omitempty" binding:"required_with=Password"`
+
+	DisplayName string `json:"displayName,omitempty" binding:"omitempty,min=2,max=100"`
+	// A pointer so the bio can be cleared with an empty string
+	Bio      *string `json:"bio,omitempty" binding:"omitempty,max=1000"`
+	Timezone string  `json:"timezone,omitempty" binding:"omitempty,timezone"`
+	Locale   string  `json:"locale,omitempty" binding:"omitempty,bcp47_language_tag"`
+}
+
+// HasProfileChanges reports whether the dto changes anything besides the password.
+func (dto *UpdateUserDto) HasProfileChanges() bool {
+	return dto.Name != "" || dto.Email != "" || dto.DisplayName != "" || dto.Bio != nil || dto.Timezone != "" || dto.Locale != ""
+}
+
+type UpdateUserRoleDto struct {
+	Role string `json:"role" binding:"required,oneof=user organizer admin"`
+}
+
+// UserFilters narrow user lists. Zero values do not filter.
+type UserFilters struct {
+	Role string
+	Name string
+}
+
+// UserSortColumns are the fields user lists can be sorted by.
+var UserSortColumns = map[string]string{
+	"name":      "name",
+	"createdAt": "created_at",
+}
+
+func (f *UserFilters) apply(query sq.SelectBuilder) sq.SelectBuilder {
+	if f == nil {
+		return query
+	}
+
+	if f.Role != "" {
+		query = query.Where(sq.Eq{"role": f.Role})
+	}
+	if f.Name != "" {
+		pattern := "%" + escapeLike(f.Name) + "%"
+		query = query.Where(sq.Or{sq.ILike{"name": pattern}, sq.ILike{"display_name": pattern}})
+	}
+
+	return query
 }
 
 type UserSerializer struct {
-	ID    int64  `json:"id,omitempty"`
-	Email string `json:"email,omitempty"`
-	Name  string `json:"name,omitempty"`
+	ID               int64      `json:"id,omitempty"`
+	Email            string     `json:"email,omitempty"`
+	Name             string     `json:"name,omitempty"`
+	Role             string     `json:"role,omitempty"`
+	EmailVerifiedAt  *time.Time `json:"emailVerifiedAt,omitempty"`
+	TwoFactorEnabled bool       `json:"twoFactorEnabled"`
+
+	DeletionRequestedAt *time.Time `json:"deletionRequestedAt,omitempty"`
+	Deleted             bool       `json:"deleted,omitempty"`
+
+	DisplayName string  `json:"displayName,omitempty"`
+	Bio         string  `json:"bio,omitempty"`
+	Timezone    string  `json:"timezone,omitempty"`
+	Locale      string  `json:"locale,omitempty"`
+	Avatar      *Avatar `json:"avatar,omitempty"`
 	BaseModel
+
+	// Joins
+	OrganizedEvents []EventSerializer `json:"organizedEvents,omitempty"`
 }
 
+// CreateResponseUser serializes every field of the user. Only for the user themself
+// and admins, use CreateResponseUserFor when the viewer may be someone else.
 func CreateResponseUser(user *User) UserSerializer {
 	return UserSerializer{
-		ID:    user.ID,
-		Email: user.Email,
-		Name:  user.Name,
+		ID:               user.ID,
+		Email:            user.Email,
+		Name:             user.Name,
+		Role:             user.Role,
+		EmailVerifiedAt:  user.EmailVerifiedAt,
+		TwoFactorEnabled: user.TOTPEnabledAt != nil,
+
+		DeletionRequestedAt: user.DeletionRequestedAt,
+		Deleted:             user.DeletedAt != nil,
+
+		DisplayName: user.DisplayName,
+		Bio:         user.Bio,
+		Timezone:    user.Timezone,
+		Locale:      user.Locale,
+		Avatar:      user.Avatar,
 		BaseModel: BaseModel{
 			CreatedAt: user.CreatedAt,
 			UpdatedAt: user.UpdatedAt,
 		},
 	}
 }
+
+// CreatePublicResponseUser serializes the fields any logged in user may see.
+func CreatePublicResponseUser(user *User) UserSerializer {
+	return UserSerializer{
+		ID:          user.ID,
+		Name:        user.Name,
+		DisplayName: user.DisplayName,
+		Avatar:      user.Avatar,
+	}
+}
+
+// CreateResponseUserFor serializes the user with the fields the viewer is allowed to see.
+func CreateResponseUserFor(user *User, viewer *Viewer) UserSerializer {
+	if viewer.CanSeePrivate(user.ID) {
+		return CreateResponseUser(user)
+	}
+
+	return CreatePublicResponseUser(user)
+}
+
+// DeleteAccountDto confirms a deletion request with the password. Without one, for
+// users who only sign in with a provider or login links, a confirmation link is emailed.
+type DeleteAccountDto struct {
+	Password string `json:"password"`
+}
+
+type ConfirmAccountDeletionDto struct {
+	Token string `json:"token" binding:"required"`
+}
+
+type DeletionRequestSerializer struct {
+	RequestedAt time.Time `json:"requestedAt"`
+	DeleteAfter time.Time `json:"deleteAfter"`
+}
+
+// UserExportSerializer is everything we hold about a user, for data export requests.
+type UserExportSerializer struct {
+	ExportedAt      time.Time           `json:"exportedAt"`
+	Profile         UserSerializer      `json:"profile"`
+	OrganizedEvents []EventSerializer   `json:"organizedEvents"`
+	AttendedEvents  []EventSerializer   `json:"attendedEvents"`
+	Sessions        []SessionSerializer `json:"sessions"`
+	APIKeys         []APIKeySerializer  `json:"apiKeys"`
+	LinkedAccounts  []UserIdentity      `json:"linkedAccounts"`
+}
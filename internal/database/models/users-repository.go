@@ -2,14 +2,36 @@ package models
 
 import (
 	"database/sql"
+	"database/sql/driver"
 	"errors"
 	"fmt"
 	"log"
+	"strings"
+	"time"
 
 	sq "github.com/Masterminds/squirrel"
 	"github.com/vickon16/go-gin-rest-api/internal/utils"
 )
 
+// userColumns are selected by every user query, in the order userScanFields expects them.
+var userColumns = []string{
+	"id", "email", "name", "role", "email_verified_at", "totp_enabled_at", "deletion_requested_at", "deleted_at",
+	"display_name", "bio", "timezone", "locale", "avatar", "created_at", "updated_at",
+}
+
+func userScanFields(user *User) []any {
+	return []any{
+		&user.ID, &user.Email, &user.Name, &user.Role, &user.EmailVerifiedAt, &user.TOTPEnabledAt,
+		&user.DeletionRequestedAt, &user.DeletedAt,
+		&user.DisplayName, &user.Bio, &user.Timezone, &user.Locale, &user.Avatar,
+		&user.CreatedAt, &user.UpdatedAt,
+	}
+}
+
+func userReturning() string {
+	return "RETURNING " + strings.Join(userColumns, ", ")
+}
+
 func (m *UserModel) Insert(user *User) error {
 	ctx, cancel := utils.CreateContext()
 	defer cancel()
@@ -17,7 +39,7 @@ func (m *UserModel) Insert(user *User) error {
 	query := sq.Insert("users").
 		Columns("email", "name", "password").
 		Values(user.Email, user.Name, user.Password).
-		Suffix("RETURNING id, email, name, created_at").
+		Suffix(userReturning()).
 		PlaceholderFormat(sq.Dollar)
 
 	sqlStr, args, err := query.ToSql()
@@ -27,55 +49,64 @@ func (m *UserModel) Insert(user *User) error {
 	}
 
 	// Scan the returned row
-	return m.DB.QueryRowContext(ctx, sqlStr, args...).
-		Scan(&user.ID, &user.Email, &user.Name, &user.CreatedAt)
+	return m.DB.QueryRowContext(ctx, sqlStr, args...).Scan(userScanFields(user)...)
 }
 
-func (m *UserModel) GetAll() ([]*User, error) {
+// GetAll returns one page of the users matching the filters and the number of all matches.
+// Deleted users are never listed.
+func (m *UserModel) GetAll(filters *UserFilters, options *ListOptions) ([]*User, int64, error) {
+	return listUsers(m.DB, filters.apply(sq.Select().From("users").Where(sq.Eq{"deleted_at": nil})), options)
+}
+
+// listUsers pages through the users matched by a filtered query without columns.
+func listUsers(db *sql.DB, filtered sq.SelectBuilder, options *ListOptions) ([]*User, int64, error) {
+	total, err := countRows(db, filtered)
+	if err != nil {
+		return nil, 0, err
+	}
+
 	ctx, cancel := utils.CreateContext()
 	defer cancel()
 
-	query := sq.Select("id, email, name, created_at").
-		From("users").
-		PlaceholderFormat(sq.Dollar)
+	query := paginate(filtered.Columns(userColumns...), options, UserSortColumns, "id")
 
-	sqlStr, args, err := query.ToSql()
+	sqlStr, args, err := query.PlaceholderFormat(sq.Dollar).ToSql()
 	if err != nil {
-		return nil, err
+		return nil, 0, err
 	}
 
 	// Use QueryContext for multiple rows
-	rows, err := m.DB.QueryContext(ctx, sqlStr, args...)
+	rows, err := db.QueryContext(ctx, sqlStr, args...)
 	if err != nil {
-		return nil, err
+		return nil, 0, err
 	}
 
 	defer rows.Close()
 
-	var users []*User
+	users := []*User{}
 
 	for rows.Next() {
 		var user User
 
-		if err := rows.Scan(&user.ID, &user.Email, &user.Name, &user.CreatedAt); err != nil {
-			return nil, err
+		if err := rows.Scan(userScanFields(&user)...); err != nil {
+			return nil, 0, err
 		}
 
 		users = append(users, &user)
 	}
 
 	if err := rows.Err(); err != nil {
-		return nil, err
+		return nil, 0, err
 	}
 
-	return users, nil
+	return users, total, nil
 }
 
 func (m *UserModel) Get(id int64) (*User, error) {
 	ctx, cancel := utils.CreateContext()
 	defer cancel()
 
-	query := sq.Select("id, email, name, created_at").
+	query := sq.Select(userColumns...).
 		From("users").
 		Where(sq.Eq{"id": id}).
 		PlaceholderFormat(sq.Dollar)
@@ -86,7 +117,7 @@ func (m *UserModel) Get(id int64) (*User, error) {
 	}
 
 	var user User
-	err = m.DB.QueryRowContext(ctx, sqlStr, args...).Scan(&user.ID, &user.Email, &user.Name, &user.CreatedAt)
+	err = m.DB.QueryRowContext(ctx, sqlStr, args...).Scan(userScanFields(&user)...)
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			return nil, nil
@@ -108,10 +139,14 @@ func (m *UserModel) GetUserByEmail(email string, withPassword ...bool) (*User, e
 		includePassword = true
 	}
 
+	var user User
+
 	// Define base columns
-	columns := []string{"id", "email", "name", "created_at"}
+	columns := userColumns
+	fields := userScanFields(&user)
 	if includePassword {
-		columns = append(columns, "password")
+		columns = append(columns[:len(columns):len(columns)], "password")
+		fields = append(fields, &user.Password)
 	}
 
 	query := sq.Select(columns...).
@@ -124,18 +159,7 @@ func (m *UserModel) GetUserByEmail(email string, withPassword ...bool) (*User, e
 		return nil, err
 	}
 
-	var user User
-	if includePassword {
-		err = m.DB.QueryRowContext(ctx, sqlStr, args...).Scan(
-			&user.ID, &user.Email, &user.Name,
-			&user.CreatedAt, &user.Password,
-		)
-	} else {
-		err = m.DB.QueryRowContext(ctx, sqlStr, args...).Scan(
-			&user.ID, &user.Email, &user.Name, &user.CreatedAt,
-		)
-	}
-
+	err = m.DB.QueryRowContext(ctx, sqlStr, args...).Scan(fields...)
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			return nil, nil
@@ -157,13 +181,23 @@ func (m *UserModel) Update(id int64, user *UpdateUserDto) (*User, error) {
 		query = query.Set("name", user.Name)
 	}
 	if user.Email != "" {
-		query = query.Set("email", user.Email)
+		// A new address has to be verified again, sending the current one keeps it verified
+		query = query.Set("email_verified_at", sq.Expr("CASE WHEN email = ? THEN email_verified_at END", user.Email)).
+			Set("email", user.Email)
 	}
-	if user.Password != "" {
-		query = query.Set("password", user.Password)
+	if user.DisplayName != "" {
+		query = query.Set("display_name", user.DisplayName)
 	}
-
-	query = query.Where(sq.Eq{"id": id}).Suffix("RETURNING id, email, name, created_at, updated_at")
+	if user.Bio != nil {
+		query = query.Set("bio", *user.Bio)
+	}
+	if user.Timezone != "" {
+		query = query.Set("timezone", user.Timezone)
+	}
+	if user.Locale != "" {
+		query = query.Set("locale", user.Locale)
+	}
+	query = query.Where(sq.Eq{"id": id}).Suffix(userReturning())
 
 	sqlStr, args, err := query.ToSql()
 	if err != nil {
@@ -175,13 +209,32 @@ func (m *UserModel) Update(id int64, user *UpdateUserDto) (*User, error) {
 	}
 
 	var updated User
-	err = m.DB.QueryRowContext(ctx, sqlStr, args...).Scan(
-		&updated.ID,
-		&updated.Email,
-		&updated.Name,
-		&updated.CreatedAt,
-		&updated.UpdatedAt,
-	)
+	err = m.DB.QueryRowContext(ctx, sqlStr, args...).Scan(userScanFields(&updated)...)
+	if err != nil {
+		return nil, err
+	}
+
+	return &updated, nil
+}
+
+func (m *UserModel) UpdateRole(id int64, role string) (*User, error) {
+	ctx, cancel := utils.CreateContext()
+	defer cancel()
+
+	query := sq.Update("users").
+		Set("role", role).
+		Set("updated_at", time.Now()).
+		Where(sq.Eq{"id": id}).
+		Suffix(userReturning()).
+		PlaceholderFormat(sq.Dollar)
+
+	sqlStr, args, err := query.ToSql()
+	if err != nil {
+		return nil, err
+	}
+
+	var updated User
+	err = m.DB.QueryRowContext(ctx, sqlStr, args...).Scan(userScanFields(&updated)...)
 	if err != nil {
 		return nil, err
 	}
@@ -189,11 +242,139 @@ func (m *UserModel) Update(id int64, user *UpdateUserDto) (*User, error) {
 	return &updated, nil
 }
 
-func (m *UserModel) Delete(id int64) error {
+// SetAvatar replaces the avatar, or removes it with nil. Returns the avatar it replaced,
+// read in the same transaction, so concurrent uploads each get back the one they replaced.
+func (m *UserModel) SetAvatar(id int64, avatar *Avatar) (*Avatar, error) {
+	ctx, cancel := utils.CreateContext()
+	defer cancel()
+
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	sqlStr, args, err := sq.Select("avatar").
+		From("users").
+		Where(sq.Eq{"id": id}).
+		Suffix("FOR UPDATE").
+		PlaceholderFormat(sq.Dollar).
+		ToSql()
+	if err != nil {
+		return nil, err
+	}
+
+	var previous *Avatar
+	if err := tx.QueryRowContext(ctx, sqlStr, args...).Scan(&previous); err != nil {
+		return nil, err
+	}
+
+	var value any
+	if avatar != nil {
+		value = *avatar
+	}
+
+	query := sq.Update("users").
+		Set("avatar", value).
+		Set("updated_at", time.Now()).
+		Where(sq.Eq{"id": id}).
+		PlaceholderFormat(sq.Dollar)
+
+	sqlStr, args, err = query.ToSql()
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := tx.ExecContext(ctx, sqlStr, args...); err != nil {
+		return nil, err
+	}
+
+	return previous, tx.Commit()
+}
+
+// UpdatePassword stores a new password hash. Callers must hash with utils.HashPassword first.
+func (m *UserModel) UpdatePassword(id int64, hashedPassword string) error {
+	ctx, cancel := utils.CreateContext()
+	defer cancel()
+
+	query := sq.Update("users").
+		Set("password", hashedPassword).
+		Set("updated_at", time.Now()).
+		Where(sq.Eq{"id": id}).
+		PlaceholderFormat(sq.Dollar)
+
+	sqlStr, args, err := query.ToSql()
+	if err != nil {
+		return err
+	}
+
+	_, err = m.DB.ExecContext(ctx, sqlStr, args...)
+	return err
+}
+
+func (m *UserModel) MarkEmailVerified(id int64) error {
+	ctx, cancel := utils.CreateContext()
+	defer cancel()
+
+	query := sq.Update("users").
+		Set("email_verified_at", time.Now()).
+		Set("updated_at", time.Now()).
+		Where(sq.Eq{"id": id}).
+		PlaceholderFormat(sq.Dollar)
+
+	sqlStr, args, err := query.ToSql()
+	if err != nil {
+		return err
+	}
+
+	_, err = m.DB.ExecContext(ctx, sqlStr, args...)
+	return err
+}
+
+// GetTOTPSecret returns the encrypted TOTP secret, pending or enabled, of a user.
+func (m *UserModel) GetTOTPSecret(id int64) (string, error) {
 	ctx, cancel := utils.CreateContext()
 	defer cancel()
 
-	query := sq.Delete("users").
+	query := sq.Select("COALESCE(totp_secret, '')").
+		From("users").
+		Where(sq.Eq{"id": id}).
+		PlaceholderFormat(sq.Dollar)
+
+	sqlStr, args, err := query.ToSql()
+	if err != nil {
+		return "", err
+	}
+
+	var secret string
+	err = m.DB.QueryRowContext(ctx, sqlStr, args...).Scan(&secret)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return "", err
+	}
+
+	return secret, nil
+}
+
+// SetTOTPSecret stores a pending secret. Two-factor stays off until EnableTOTP is called.
+func (m *UserModel) SetTOTPSecret(id int64, encryptedSecret string) error {
+	return m.updateTOTP(id, sq.Eq{"totp_secret": encryptedSecret, "totp_enabled_at": nil})
+}
+
+func (m *UserModel) EnableTOTP(id int64) error {
+	return m.updateTOTP(id, sq.Eq{"totp_enabled_at": time.Now()})
+}
+
+func (m *UserModel) DisableTOTP(id int64) error {
+	return m.updateTOTP(id, sq.Eq{"totp_secret": nil, "totp_enabled_at": nil})
+}
+
+func (m *UserModel) updateTOTP(id int64, values sq.Eq) error {
+	ctx, cancel := utils.CreateContext()
+	defer cancel()
+
+	query := sq.Update("users").
+		SetMap(values).
+		Set("updated_at", time.Now()).
 		Where(sq.Eq{"id": id}).
 		PlaceholderFormat(sq.Dollar)
 
@@ -203,9 +384,150 @@ func (m *UserModel) Delete(id int64) error {
 	}
 
 	_, err = m.DB.ExecContext(ctx, sqlStr, args...)
+	return err
+}
+
+// SetDeletionRequested schedules the account for anonymization, or cancels it with a nil time.
+func (m *UserModel) SetDeletionRequested(id int64, requestedAt *time.Time) error {
+	ctx, cancel := utils.CreateContext()
+	defer cancel()
+
+	query := sq.Update("users").
+		Set("deletion_requested_at", requestedAt).
+		Set("updated_at", time.Now()).
+		Where(sq.Eq{"id": id, "deleted_at": nil}).
+		PlaceholderFormat(sq.Dollar)
+
+	sqlStr, args, err := query.ToSql()
 	if err != nil {
 		return err
 	}
 
-	return nil
+	_, err = m.DB.ExecContext(ctx, sqlStr, args...)
+	return err
+}
+
+// accountDeletionLockKey names the advisory lock held while due accounts are anonymized
+const accountDeletionLockKey int64 = 0x61636374646c // "acctdl"
+
+// TryLockAccountDeletion takes the Postgres advisory lock that lets one instance at a time
+// anonymize due accounts, without waiting for it. ok is false when another instance holds it.
+// The lock belongs to a dedicated connection, call release to unlock and return it to the pool.
+func (m *UserModel) TryLockAccountDeletion() (release func(), ok bool, err error) {
+	ctx, cancel := utils.CreateContext()
+	defer cancel()
+
+	conn, err := m.DB.Conn(ctx)
+	if err != nil {
+		return nil, false, err
+	}
+
+	if err := conn.QueryRowContext(ctx, "SELECT pg_try_advisory_lock($1)", accountDeletionLockKey).Scan(&ok); err != nil || !ok {
+		conn.Close()
+		return nil, false, err
+	}
+
+	release = func() {
+		ctx, cancel := utils.CreateContext()
+		defer cancel()
+
+		if _, err := conn.ExecContext(ctx, "SELECT pg_advisory_unlock($1)", accountDeletionLockKey); err != nil {
+			log.Printf("Failed to release the account deletion lock: %v", err)
+			// Discard the connection rather than return it to the pool, ending the session releases the lock
+			_ = conn.Raw(func(any) error { return driver.ErrBadConn })
+		}
+		conn.Close()
+	}
+
+	return release, true, nil
+}
+
+// GetDueForDeletion returns the ids of accounts whose deletion was requested before the cutoff.
+func (m *UserModel) GetDueForDeletion(cutoff time.Time) ([]int64, error) {
+	ctx, cancel := utils.CreateContext()
+	defer cancel()
+
+	query := sq.Select("id").
+		From("users").
+		Where(sq.Eq{"deleted_at": nil}).
+		Where(sq.Lt{"deletion_requested_at": cutoff}).
+		PlaceholderFormat(sq.Dollar)
+
+	sqlStr, args, err := query.ToSql()
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := m.DB.QueryContext(ctx, sqlStr, args...)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	var ids []int64
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+
+	return ids, rows.Err()
+}
+
+// Anonymize replaces the user's personal data with a "Deleted user" placeholder
+// and removes their credentials. The row itself stays, so the events they
+// organized and their attendance records keep working.
+func (m *UserModel) Anonymize(id int64) error {
+	ctx, cancel := utils.CreateContext()
+	defer cancel()
+
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	now := time.Now()
+	queries := []sq.Sqlizer{
+		sq.Update("users").
+			Set("name", DeletedUserName).
+			Set("email", fmt.Sprintf("deleted-%d@deleted.invalid", id)).
+			// Not a valid hash, so no password ever matches
+			Set("password", "!").
+			Set("role", RoleUser).
+			Set("display_name", "").
+			Set("bio", "").
+			Set("avatar", nil).
+			Set("email_verified_at", nil).
+			Set("totp_secret", nil).
+			Set("totp_enabled_at", nil).
+			Set("deletion_requested_at", nil).
+			Set("deleted_at", now).
+			Set("updated_at", now).
+			Where(sq.Eq{"id": id}).
+			PlaceholderFormat(sq.Dollar),
+	}
+	for _, table := range []string{"recovery_codes", "api_keys", "user_identities", "sessions", "refresh_tokens", "one_time_tokens", "event_organizers"} {
+		queries = append(queries, sq.Delete(table).Where(sq.Eq{"user_id": id}).PlaceholderFormat(sq.Dollar))
+	}
+
+	queries = append(queries, sq.Delete("follows").
+		Where(sq.Or{sq.Eq{"follower_id": id}, sq.Eq{"followee_id": id}}).
+		PlaceholderFormat(sq.Dollar))
+
+	for _, query := range queries {
+		sqlStr, args, err := query.ToSql()
+		if err != nil {
+			return err
+		}
+
+		if _, err := tx.ExecContext(ctx, sqlStr, args...); err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
 }
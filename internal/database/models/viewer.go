@@ -0,0 +1,26 @@
+package models
+
+// Viewer is the user a response is serialized for. It decides which private
+// fields of other users end up in the response.
+type Viewer struct {
+	UserID int64
+	Role   string
+
+	// Events the viewer owns or co-organizes, whose attendees' emails are visible.
+	// Only loaded by handlers that serialize attendees.
+	OrganizedEventIDs map[int64]bool
+}
+
+func NewViewer(userId int64, role string) *Viewer {
+	return &Viewer{UserID: userId, Role: role}
+}
+
+// CanSeePrivate reports whether the viewer sees every field of the user: their own account, or any account for admins.
+func (v *Viewer) CanSeePrivate(userId int64) bool {
+	return v != nil && (v.Role == RoleAdmin || v.UserID == userId)
+}
+
+// Organizes reports whether the viewer owns or co-organizes the event.
+func (v *Viewer) Organizes(eventId int64) bool {
+	return v != nil && v.OrganizedEventIDs[eventId]
+}
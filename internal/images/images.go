@@ -0,0 +1,112 @@
+package images
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"image"
+	"image/draw"
+	"image/jpeg"
+	"image/png"
+
+	// Registered for image.Decode
+	_ "image/gif"
+)
+
+// Formats accepted for uploads, by the name image.Decode reports.
+var contentTypes = map[string]string{
+	"jpeg": "image/jpeg",
+	"png":  "image/png",
+	"gif":  "image/gif",
+}
+
+var ErrUnsupportedFormat = errors.New("unsupported image format, use JPEG, PNG or GIF")
+
+// Decode checks the format and dimensions before decoding, so a small file
+// claiming huge dimensions is rejected without allocating the pixels.
+func Decode(data []byte, maxDimension int) (image.Image, string, error) {
+	config, format, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, "", ErrUnsupportedFormat
+	}
+	if _, ok := contentTypes[format]; !ok {
+		return nil, "", ErrUnsupportedFormat
+	}
+	if config.Width > maxDimension || config.Height > maxDimension {
+		return nil, "", fmt.Errorf("image must be at most %dx%d pixels", maxDimension, maxDimension)
+	}
+
+	img, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, "", fmt.Errorf("invalid image: %w", err)
+	}
+
+	return img, format, nil
+}
+
+func ContentType(format string) string {
+	return contentTypes[format]
+}
+
+// Thumbnail crops the center square of the image and scales it to size x size,
+// averaging the source pixels covered by each target pixel.
+func Thumbnail(src image.Image, size int) *image.RGBA {
+	bounds := src.Bounds()
+	side := min(bounds.Dx(), bounds.Dy())
+	crop := image.Rect(0, 0, side, side).Add(image.Pt(
+		bounds.Min.X+(bounds.Dx()-side)/2,
+		bounds.Min.Y+(bounds.Dy()-side)/2,
+	))
+
+	rgba := image.NewRGBA(image.Rect(0, 0, side, side))
+	draw.Draw(rgba, rgba.Bounds(), src, crop.Min, draw.Src)
+
+	dst := image.NewRGBA(image.Rect(0, 0, size, size))
+	for y := range size {
+		y0 := y * side / size
+		y1 := max(y0+1, (y+1)*side/size)
+
+		for x := range size {
+			x0 := x * side / size
+			x1 := max(x0+1, (x+1)*side/size)
+
+			var r, g, b, a, count int
+			for sy := y0; sy < y1; sy++ {
+				for sx := x0; sx < x1; sx++ {
+					i := rgba.PixOffset(sx, sy)
+					r += int(rgba.Pix[i])
+					g += int(rgba.Pix[i+1])
+					b += int(rgba.Pix[i+2])
+					a += int(rgba.Pix[i+3])
+					count++
+				}
+			}
+
+			i := dst.PixOffset(x, y)
+			dst.Pix[i] = uint8(r / count)
+			dst.Pix[i+1] = uint8(g / count)
+			dst.Pix[i+2] = uint8(b / count)
+			dst.Pix[i+3] = uint8(a / count)
+		}
+	}
+
+	return dst
+}
+
+// Encode writes JPEG photos as JPEG and everything else as PNG, which keeps transparency.
+// It returns the encoded bytes and their format.
+func Encode(img image.Image, sourceFormat string) ([]byte, string, error) {
+	var buf bytes.Buffer
+
+	if sourceFormat == "jpeg" {
+		if err := jpeg.Encode(&buf, img, &jpeg.Options{Quality: 85}); err != nil {
+			return nil, "", err
+		}
+		return buf.Bytes(), "jpeg", nil
+	}
+
+	if err := png.Encode(&buf, img); err != nil {
+		return nil, "", err
+	}
+	return buf.Bytes(), "png", nil
+}
@@ -0,0 +1,118 @@
+package images
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"hash/crc32"
+	"image"
+	"image/color"
+	"image/gif"
+	"image/jpeg"
+	"image/png"
+	"testing"
+)
+
+func encodePNG(t *testing.T, width, height int) []byte {
+	t.Helper()
+
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, image.NewRGBA(image.Rect(0, 0, width, height))); err != nil {
+		t.Fatal(err)
+	}
+
+	return buf.Bytes()
+}
+
+func TestDecodeAcceptsUploadFormats(t *testing.T) {
+	src := image.NewRGBA(image.Rect(0, 0, 4, 3))
+
+	var jpegBuf, gifBuf bytes.Buffer
+	if err := jpeg.Encode(&jpegBuf, src, nil); err != nil {
+		t.Fatal(err)
+	}
+	if err := gif.Encode(&gifBuf, src, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	for format, data := range map[string][]byte{
+		"png":  encodePNG(t, 4, 3),
+		"jpeg": jpegBuf.Bytes(),
+		"gif":  gifBuf.Bytes(),
+	} {
+		img, got, err := Decode(data, 10)
+		if err != nil {
+			t.Errorf("Decode of %s returned %v", format, err)
+			continue
+		}
+		if got != format || img.Bounds().Dx() != 4 || img.Bounds().Dy() != 3 {
+			t.Errorf("Decode of %s = %s %v", format, got, img.Bounds())
+		}
+	}
+}
+
+func TestDecodeRejectsUnsupportedFormats(t *testing.T) {
+	inputs := map[string][]byte{
+		"empty": nil,
+		"text":  []byte("not an image"),
+		"bmp":   append([]byte("BM"), make([]byte, 64)...),
+		"svg":   []byte(`<svg xmlns="http://www.w3.org/2000/svg" width="10" height="10"/>`),
+		"webp":  append([]byte("RIFF\x00\x00\x00\x00WEBPVP8 "), make([]byte, 32)...),
+	}
+
+	for name, data := range inputs {
+		if _, _, err := Decode(data, 100); !errors.Is(err, ErrUnsupportedFormat) {
+			t.Errorf("Decode of %s returned %v, want ErrUnsupportedFormat", name, err)
+		}
+	}
+}
+
+func TestDecodeRejectsOversizedImages(t *testing.T) {
+	for _, size := range [][2]int{{11, 10}, {10, 11}} {
+		if _, _, err := Decode(encodePNG(t, size[0], size[1]), 10); err == nil {
+			t.Errorf("Decode of a %dx%d image with a 10 pixel limit succeeded", size[0], size[1])
+		}
+	}
+	if _, _, err := Decode(encodePNG(t, 10, 10), 10); err != nil {
+		t.Errorf("Decode of a 10x10 image with a 10 pixel limit returned %v", err)
+	}
+
+	// A tiny file whose header claims a huge image must be refused before the pixels are allocated
+	data := encodePNG(t, 1, 1)
+	binary.BigEndian.PutUint32(data[16:20], 1<<20)
+	binary.BigEndian.PutUint32(data[20:24], 1<<20)
+	binary.BigEndian.PutUint32(data[29:33], crc32.ChecksumIEEE(data[12:29]))
+
+	config, err := png.DecodeConfig(bytes.NewReader(data))
+	if err != nil || config.Width != 1<<20 {
+		t.Fatalf("forged header decodes to %+v, %v", config, err)
+	}
+	if _, _, err := Decode(data, 4096); err == nil || errors.Is(err, ErrUnsupportedFormat) {
+		t.Errorf("Decode of a forged huge header returned %v, want a dimension error", err)
+	}
+}
+
+func TestThumbnail(t *testing.T) {
+	// Left half red, right half blue, so the center crop of a wide image keeps both
+	src := image.NewRGBA(image.Rect(0, 0, 40, 20))
+	for y := range 20 {
+		for x := range 40 {
+			c := color.RGBA{R: 255, A: 255}
+			if x >= 20 {
+				c = color.RGBA{B: 255, A: 255}
+			}
+			src.Set(x, y, c)
+		}
+	}
+
+	thumb := Thumbnail(src, 4)
+	if thumb.Bounds() != image.Rect(0, 0, 4, 4) {
+		t.Fatalf("Thumbnail bounds = %v", thumb.Bounds())
+	}
+	if got := thumb.RGBAAt(0, 0); got != (color.RGBA{R: 255, A: 255}) {
+		t.Errorf("left pixel = %v, want red", got)
+	}
+	if got := thumb.RGBAAt(3, 3); got != (color.RGBA{B: 255, A: 255}) {
+		t.Errorf("right pixel = %v, want blue", got)
+	}
+}
@@ -0,0 +1,41 @@
+package mailer
+
+import (
+	"log"
+
+	"github.com/vickon16/go-gin-rest-api/internal/env"
+)
+
+type Message struct {
+	To      string
+	Subject string
+	Body    string
+}
+
+type Mailer interface {
+	Send(message Message) error
+}
+
+// NewMailer builds the mailer selected by the MAILER_DRIVER env variable.
+// Supported drivers are "smtp", "file" and "memory".
+func NewMailer() Mailer {
+	from := env.GetEnvString("MAIL_FROM", "no-reply@example.com")
+
+	switch driver := env.GetEnvString("MAILER_DRIVER", "file"); driver {
+	case "smtp":
+		return &SMTPMailer{
+			Host:     env.GetEnvString("SMTP_HOST", "localhost"),
+			Port:     env.GetEnvInt("SMTP_PORT", 587),
+			Username: env.GetEnvString("SMTP_USERNAME", ""),
+			Password: env.GetEnvString("SMTP_PASSWORD", ""),
+			From:     from,
+		}
+	case "memory":
+		return &MemoryMailer{}
+	case "file":
+		return &FileMailer{Dir: env.GetEnvString("MAIL_DIR", "./tmp/mails"), From: from}
+	default:
+		log.Fatalf("Unknown mailer driver: %s", driver)
+		return nil
+	}
+}
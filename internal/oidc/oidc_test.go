@@ -0,0 +1,265 @@
+package oidc
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const (
+	testClientID = "test-client"
+	testKeyID    = "test-key"
+	testCode     = "test-code"
+	testVerifier = "test-verifier"
+	testNonce    = "test-nonce"
+)
+
+// testIssuer is an OpenID provider serving discovery, its JWKS and the token
+// endpoint. The token endpoint answers the test code with idToken.
+type testIssuer struct {
+	server  *httptest.Server
+	key     *rsa.PrivateKey
+	idToken string
+}
+
+func newTestIssuer(t *testing.T) *testIssuer {
+	t.Helper()
+
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("GenerateKey returned %v", err)
+	}
+
+	issuer := &testIssuer{key: key}
+	mux := http.NewServeMux()
+
+	mux.HandleFunc("GET /.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(Discovery{
+			Issuer:                issuer.server.URL,
+			AuthorizationEndpoint: issuer.server.URL + "/authorize",
+			TokenEndpoint:         issuer.server.URL + "/token",
+			JWKSURI:               issuer.server.URL + "/jwks",
+		})
+	})
+
+	mux.HandleFunc("GET /jwks", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(jsonWebKeySet{Keys: []jsonWebKey{{
+			Kty: "RSA",
+			Kid: testKeyID,
+			Use: "sig",
+			N:   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+			E:   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+		}}})
+	})
+
+	mux.HandleFunc("POST /token", func(w http.ResponseWriter, r *http.Request) {
+		if r.PostFormValue("grant_type") != "authorization_code" || r.PostFormValue("code") != testCode ||
+			r.PostFormValue("code_verifier") != testVerifier || r.PostFormValue("client_id") != testClientID {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(map[string]string{"error": "invalid_grant"})
+			return
+		}
+
+		json.NewEncoder(w).Encode(TokenResponse{AccessToken: "access", TokenType: "Bearer", IDToken: issuer.idToken, ExpiresIn: 3600})
+	})
+
+	issuer.server = httptest.NewServer(mux)
+	t.Cleanup(issuer.server.Close)
+
+	return issuer
+}
+
+func (i *testIssuer) provider() *Provider {
+	providers := &Providers{providers: map[string]*Provider{}}
+	return providers.Add(Config{
+		Name:        "test",
+		Issuer:      i.server.URL,
+		ClientID:    testClientID,
+		RedirectURL: "http://localhost/callback",
+		Scopes:      []string{"openid", "email"},
+	})
+}
+
+// claims returns valid ID token claims, for the tests to break one at a time.
+func (i *testIssuer) claims() *IDTokenClaims {
+	now := time.Now()
+
+	return &IDTokenClaims{
+		Nonce:         testNonce,
+		Email:         "ada@example.com",
+		EmailVerified: true,
+		RegisteredClaims: jwt.RegisteredClaims{
+			Issuer:    i.server.URL,
+			Subject:   "subject-1",
+			Audience:  jwt.ClaimStrings{testClientID},
+			IssuedAt:  jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(now.Add(time.Hour)),
+		},
+	}
+}
+
+func (i *testIssuer) sign(t *testing.T, claims *IDTokenClaims) string {
+	t.Helper()
+
+	token := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
+	token.Header["kid"] = testKeyID
+
+	signed, err := token.SignedString(i.key)
+	if err != nil {
+		t.Fatalf("SignedString returned %v", err)
+	}
+
+	return signed
+}
+
+func TestAuthCodeURL(t *testing.T) {
+	issuer := newTestIssuer(t)
+
+	authUrl, err := issuer.provider().AuthCodeURL(context.Background(), "state", testNonce, testVerifier)
+	if err != nil {
+		t.Fatalf("AuthCodeURL returned %v", err)
+	}
+
+	for _, want := range []string{
+		issuer.server.URL + "/authorize?",
+		"client_id=" + testClientID,
+		"nonce=" + testNonce,
+		"code_challenge=" + CodeChallenge(testVerifier),
+		"code_challenge_method=S256",
+	} {
+		if !strings.Contains(authUrl, want) {
+			t.Errorf("AuthCodeURL = %q, missing %q", authUrl, want)
+		}
+	}
+}
+
+func TestDiscoverRejectsAnotherIssuer(t *testing.T) {
+	issuer := newTestIssuer(t)
+
+	provider := issuer.provider()
+	provider.Config.Issuer = issuer.server.URL + "/other"
+
+	if _, err := provider.Discover(context.Background()); err == nil {
+		t.Error("Discover accepted metadata for another issuer")
+	}
+}
+
+func TestExchangeAndVerify(t *testing.T) {
+	issuer := newTestIssuer(t)
+	issuer.idToken = issuer.sign(t, issuer.claims())
+	provider := issuer.provider()
+	ctx := context.Background()
+
+	if _, err := provider.Exchange(ctx, testCode, "another-verifier"); err == nil {
+		t.Error("Exchange succeeded with the wrong code verifier")
+	}
+
+	tokens, err := provider.Exchange(ctx, testCode, testVerifier)
+	if err != nil {
+		t.Fatalf("Exchange returned %v", err)
+	}
+
+	claims, err := provider.VerifyIDToken(ctx, tokens.IDToken, testNonce)
+	if err != nil {
+		t.Fatalf("VerifyIDToken returned %v", err)
+	}
+	if claims.Subject != "subject-1" || claims.Email != "ada@example.com" || !claims.IsEmailVerified() {
+		t.Errorf("VerifyIDToken returned %+v", claims)
+	}
+}
+
+func TestVerifyIDToken(t *testing.T) {
+	issuer := newTestIssuer(t)
+	provider := issuer.provider()
+
+	tests := []struct {
+		name   string
+		modify func(claims *IDTokenClaims)
+		nonce  string
+		valid  bool
+	}{
+		{name: "valid", modify: func(claims *IDTokenClaims) {}, valid: true},
+		{name: "other nonce", modify: func(claims *IDTokenClaims) {}, nonce: "replayed-nonce"},
+		{name: "missing nonce", modify: func(claims *IDTokenClaims) { claims.Nonce = "" }},
+		{name: "other audience", modify: func(claims *IDTokenClaims) { claims.Audience = jwt.ClaimStrings{"other-client"} }},
+		{
+			name: "several audiences with our azp",
+			modify: func(claims *IDTokenClaims) {
+				claims.Audience = jwt.ClaimStrings{testClientID, "other-client"}
+				claims.AuthorizedParty = testClientID
+			},
+			valid: true,
+		},
+		{
+			name: "several audiences with another azp",
+			modify: func(claims *IDTokenClaims) {
+				claims.Audience = jwt.ClaimStrings{testClientID, "other-client"}
+				claims.AuthorizedParty = "other-client"
+			},
+		},
+		{
+			name:   "several audiences without azp",
+			modify: func(claims *IDTokenClaims) { claims.Audience = jwt.ClaimStrings{testClientID, "other-client"} },
+		},
+		{name: "other issuer", modify: func(claims *IDTokenClaims) { claims.Issuer = "https://attacker.example.com" }},
+		{name: "expired", modify: func(claims *IDTokenClaims) { claims.ExpiresAt = jwt.NewNumericDate(time.Now().Add(-time.Hour)) }},
+		{name: "missing subject", modify: func(claims *IDTokenClaims) { claims.Subject = "" }},
+	}
+
+	for _, tt := range tests {
+		claims := issuer.claims()
+		tt.modify(claims)
+
+		nonce := tt.nonce
+		if nonce == "" {
+			nonce = testNonce
+		}
+
+		_, err := provider.VerifyIDToken(context.Background(), issuer.sign(t, claims), nonce)
+		if tt.valid && err != nil {
+			t.Errorf("%s: VerifyIDToken returned %v", tt.name, err)
+		}
+		if !tt.valid && err == nil {
+			t.Errorf("%s: VerifyIDToken accepted the token", tt.name)
+		}
+	}
+}
+
+func TestVerifyIDTokenRejectsOtherKeys(t *testing.T) {
+	issuer := newTestIssuer(t)
+	provider := issuer.provider()
+
+	otherKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("GenerateKey returned %v", err)
+	}
+
+	token := jwt.NewWithClaims(jwt.SigningMethodRS256, issuer.claims())
+	token.Header["kid"] = testKeyID
+	forged, err := token.SignedString(otherKey)
+	if err != nil {
+		t.Fatalf("SignedString returned %v", err)
+	}
+
+	if _, err := provider.VerifyIDToken(context.Background(), forged, testNonce); err == nil {
+		t.Error("VerifyIDToken accepted a token signed with another key")
+	}
+
+	unsigned, err := jwt.NewWithClaims(jwt.SigningMethodNone, issuer.claims()).SignedString(jwt.UnsafeAllowNoneSignatureType)
+	if err != nil {
+		t.Fatalf("SignedString returned %v", err)
+	}
+	if _, err := provider.VerifyIDToken(context.Background(), unsigned, testNonce); err == nil {
+		t.Error("VerifyIDToken accepted an unsigned token")
+	}
+}
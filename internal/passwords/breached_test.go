@@ -0,0 +1,55 @@
+package passwords
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestBreachedListContains(t *testing.T) {
+	dir := t.TempDir()
+
+	// SHA-1 of "password" is 5BAA61E4C9B93F3F0682250B6CF8331B7EE68FD8
+	rangeFile := "0018A45C4D1DEF81644B54AB7F969B88D65:1\r\n1E4C9B93F3F0682250B6CF8331B7EE68FD8:9545824\r\n"
+	if err := os.WriteFile(filepath.Join(dir, "5BAA6.txt"), []byte(rangeFile), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	list, err := OpenBreachedList(dir)
+	if err != nil {
+		t.Fatalf("OpenBreachedList returned %v", err)
+	}
+
+	tests := []struct {
+		password string
+		want     bool
+	}{
+		{"password", true},
+		// No range file for its prefix
+		{"correct horse battery staple", false},
+		{"Password", false},
+	}
+
+	for _, tt := range tests {
+		got, err := list.Contains(tt.password)
+		if err != nil {
+			t.Fatalf("Contains(%q) returned %v", tt.password, err)
+		}
+		if got != tt.want {
+			t.Errorf("Contains(%q) = %v, want %v", tt.password, got, tt.want)
+		}
+	}
+}
+
+func TestOpenBreachedListRequiresDirectory(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "hashes.txt")
+	if err := os.WriteFile(file, nil, 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, path := range []string{file, filepath.Join(t.TempDir(), "missing")} {
+		if _, err := OpenBreachedList(path); err == nil {
+			t.Errorf("OpenBreachedList(%q) succeeded", path)
+		}
+	}
+}
@@ -0,0 +1,188 @@
+package passwords
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/vickon16/go-gin-rest-api/internal/env"
+)
+
+const bcryptMaxPasswordLength = 72
+
+// Policy decides whether a new password is acceptable.
+type Policy struct {
+	MinLength int
+	MaxLength int
+
+	// How many of lowercase, uppercase, digits and symbols must appear
+	MinCharacterClasses int
+
+	// Optional, a nil Breached skips the breached password check
+	Breached *BreachedList
+}
+
+// PolicyError lists every rule a password broke, so they can all be shown at once.
+type PolicyError struct {
+	Problems []string
+}
+
+func (e *PolicyError) Error() string {
+	return "password does not meet the policy: " + strings.Join(e.Problems, "; ")
+}
+
+// NewPolicyFromEnv builds the policy from the environment.
+//
+//	PASSWORD_MIN_LENGTH               default 10
+//	PASSWORD_MAX_LENGTH               in bytes, default 256, or 72 with the bcrypt hash algorithm
+//	PASSWORD_MIN_CHARACTER_CLASSES    default 3
+//	PASSWORD_BREACHED_RANGES_DIR      optional, see OpenBreachedList
+func NewPolicyFromEnv() (*Policy, error) {
+	// bcrypt refuses passwords longer than 72 bytes, Argon2id takes any length
+	maxLength, lengthLimit := 256, 0
+	if env.GetEnvString("PASSWORD_HASH_ALGORITHM", "argon2id") == "bcrypt" {
+		maxLength, lengthLimit = bcryptMaxPasswordLength, bcryptMaxPasswordLength
+	}
+
+	policy := &Policy{
+		MinLength:           env.GetEnvInt("PASSWORD_MIN_LENGTH", 10),
+		MaxLength:           env.GetEnvInt("PASSWORD_MAX_LENGTH", maxLength),
+		MinCharacterClasses: env.GetEnvInt("PASSWORD_MIN_CHARACTER_CLASSES", 3),
+	}
+
+	if lengthLimit > 0 && (policy.MaxLength <= 0 || policy.MaxLength > lengthLimit) {
+		return nil, fmt.Errorf("PASSWORD_MAX_LENGTH must be at most %d with the bcrypt hash algorithm", lengthLimit)
+	}
+
+	if dir := env.GetEnvString("PASSWORD_BREACHED_RANGES_DIR", ""); dir != "" {
+		breached, err := OpenBreachedList(dir)
+		if err != nil {
+			return nil, err
+		}
+		policy.Breached = breached
+	}
+
+	return policy, nil
+}
+
+// Validate checks the password against every rule. The identifiers are the
+// user's own details, such as their name and email, which the password must not resemble.
+func (p *Policy) Validate(password string, identifiers ...string) error {
+	var problems []string
+
+	length := utf8.RuneCountInString(password)
+	if length < p.MinLength {
+		problems = append(problems, fmt.Sprintf("must be at least %d characters long", p.MinLength))
+	}
+	if p.MaxLength > 0 && len(password) > p.MaxLength {
+		problems = append(problems, fmt.Sprintf("must be at most %d bytes long", p.MaxLength))
+	}
+
+	if classes := characterClasses(password); classes < p.MinCharacterClasses {
+		problems = append(problems, fmt.Sprintf(
+			"must contain at least %d of: lowercase letters, uppercase letters, digits and symbols", p.MinCharacterClasses,
+		))
+	}
+
+	for _, identifier := range identifiers {
+		if isSimilar(password, identifier) {
+			problems = append(problems, "must not be similar to your name or email")
+			break
+		}
+	}
+
+	if p.Breached != nil {
+		breached, err := p.Breached.Contains(password)
+		if err != nil {
+			return err
+		}
+		if breached {
+			problems = append(problems, "has appeared in a data breach, please choose another one")
+		}
+	}
+
+	if len(problems) > 0 {
+		return &PolicyError{Problems: problems}
+	}
+
+	return nil
+}
+
+func characterClasses(password string) int {
+	var lower, upper, digit, symbol bool
+
+	for _, r := range password {
+		switch {
+		case unicode.IsLower(r):
+			lower = true
+		case unicode.IsUpper(r):
+			upper = true
+		case unicode.IsDigit(r):
+			digit = true
+		default:
+			symbol = true
+		}
+	}
+
+	count := 0
+	for _, present := range []bool{lower, upper, digit, symbol} {
+		if present {
+			count++
+		}
+	}
+
+	return count
+}
+
+// isSimilar reports whether the password contains the identifier, or an email's
+// local part, or is only a few edits away from it.
+func isSimilar(password, identifier string) bool {
+	password = strings.ToLower(password)
+	identifier = strings.ToLower(strings.TrimSpace(identifier))
+
+	candidates := []string{identifier}
+	if local, _, found := strings.Cut(identifier, "@"); found {
+		candidates = append(candidates, local)
+	}
+
+	for _, candidate := range candidates {
+		if utf8.RuneCountInString(candidate) < 3 {
+			continue
+		}
+		if strings.Contains(password, candidate) || strings.Contains(candidate, password) {
+			return true
+		}
+
+		longest := max(utf8.RuneCountInString(password), utf8.RuneCountInString(candidate))
+		if levenshtein(password, candidate)*3 <= longest {
+			return true
+		}
+	}
+
+	return false
+}
+
+func levenshtein(a, b string) int {
+	ra, rb := []rune(a), []rune(b)
+
+	previous := make([]int, len(rb)+1)
+	current := make([]int, len(rb)+1)
+	for j := range previous {
+		previous[j] = j
+	}
+
+	for i := 1; i <= len(ra); i++ {
+		current[0] = i
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			current[j] = min(previous[j]+1, current[j-1]+1, previous[j-1]+cost)
+		}
+		previous, current = current, previous
+	}
+
+	return previous[len(rb)]
+}
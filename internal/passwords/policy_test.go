@@ -0,0 +1,40 @@
+package passwords
+
+import (
+	"os"
+	"testing"
+)
+
+func TestNewPolicyFromEnvMaxLength(t *testing.T) {
+	tests := []struct {
+		name      string
+		algorithm string
+		maxLength string
+		want      int
+		wantErr   bool
+	}{
+		{"argon2id default", "argon2id", "", 256, false},
+		{"bcrypt default", "bcrypt", "", 72, false},
+		{"bcrypt within its limit", "bcrypt", "64", 64, false},
+		{"bcrypt past its limit", "bcrypt", "128", 0, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("PASSWORD_HASH_ALGORITHM", tt.algorithm)
+			t.Setenv("PASSWORD_MAX_LENGTH", tt.maxLength)
+			if tt.maxLength == "" {
+				os.Unsetenv("PASSWORD_MAX_LENGTH")
+			}
+			t.Setenv("PASSWORD_BREACHED_RANGES_DIR", "")
+
+			policy, err := NewPolicyFromEnv()
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("NewPolicyFromEnv() returned %v, want error %v", err, tt.wantErr)
+			}
+			if err == nil && policy.MaxLength != tt.want {
+				t.Errorf("MaxLength = %d, want %d", policy.MaxLength, tt.want)
+			}
+		})
+	}
+}
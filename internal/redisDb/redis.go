@@ -49,9 +49,52 @@ func (r *RedisClient) Get(key string) (string, error) {
 	return r.Client.Get(ctx, key).Result()
 }
 
+// TTL returns the remaining time to live of a key, or zero when it does not expire or exist.
+func (r *RedisClient) TTL(key string) (time.Duration, error) {
+	ctx, cancel := utils.CreateContext()
+	defer cancel()
+
+	ttl, err := r.Client.TTL(ctx, key).Result()
+	if err != nil {
+		return 0, err
+	}
+	if ttl < 0 {
+		return 0, nil
+	}
+
+	return ttl, nil
+}
+
 func (r *RedisClient) Delete(key string) error {
 	ctx, cancel := utils.CreateContext()
 	defer cancel()
 
 	return r.Client.Del(ctx, key).Err()
 }
+
+// GetDelete reads a key and removes it in one step, for values that must only be used once.
+func (r *RedisClient) GetDelete(key string) (string, error) {
+	ctx, cancel := utils.CreateContext()
+	defer cancel()
+
+	return r.Client.GetDel(ctx, key).Result()
+}
+
+// Increment adds one to a counter, starting its expiry when the key is first created.
+func (r *RedisClient) Increment(key string, duration time.Duration) (int64, error) {
+	ctx, cancel := utils.CreateContext()
+	defer cancel()
+
+	count, err := r.Client.Incr(ctx, key).Result()
+	if err != nil {
+		return 0, err
+	}
+
+	if count == 1 {
+		if err := r.Client.Expire(ctx, key, duration).Err(); err != nil {
+			return 0, err
+		}
+	}
+
+	return count, nil
+}
@@ -0,0 +1,64 @@
+package redisDb
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/vickon16/go-gin-rest-api/internal/utils"
+)
+
+// RevokeSession rejects access tokens of the session for as long as they can still be valid.
+func (r *RedisClient) RevokeSession(sessionId int64, ttl time.Duration) error {
+	return r.Set(utils.ConstructRedisRevokedSessionKey(sessionId), 1, ttl)
+}
+
+func (r *RedisClient) IsSessionRevoked(sessionId int64) (bool, error) {
+	ctx, cancel := utils.CreateContext()
+	defer cancel()
+
+	count, err := r.Client.Exists(ctx, utils.ConstructRedisRevokedSessionKey(sessionId)).Result()
+	if err != nil {
+		return false, err
+	}
+
+	return count > 0, nil
+}
+
+// TouchSession records activity on a session. It runs on every request, so it
+// only writes to Redis and the database keeps the time of the last refresh.
+func (r *RedisClient) TouchSession(sessionId int64, ttl time.Duration) error {
+	return r.Set(utils.ConstructRedisSessionLastSeenKey(sessionId), time.Now().Unix(), ttl)
+}
+
+// GetSessionsLastSeen returns the last activity recorded in Redis for each session that has one.
+func (r *RedisClient) GetSessionsLastSeen(sessionIds []int64) (map[int64]time.Time, error) {
+	lastSeen := map[int64]time.Time{}
+	if len(sessionIds) == 0 {
+		return lastSeen, nil
+	}
+
+	keys := make([]string, len(sessionIds))
+	for i, sessionId := range sessionIds {
+		keys[i] = utils.ConstructRedisSessionLastSeenKey(sessionId)
+	}
+
+	ctx, cancel := utils.CreateContext()
+	defer cancel()
+
+	values, err := r.Client.MGet(ctx, keys...).Result()
+	if err != nil {
+		return nil, err
+	}
+
+	for i, value := range values {
+		str, ok := value.(string)
+		if !ok {
+			continue
+		}
+		if unix, err := strconv.ParseInt(str, 10, 64); err == nil {
+			lastSeen[sessionIds[i]] = time.Unix(unix, 0)
+		}
+	}
+
+	return lastSeen, nil
+}
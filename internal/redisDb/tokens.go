@@ -0,0 +1,54 @@
+package redisDb
+
+import (
+	"errors"
+	"strconv"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"github.com/vickon16/go-gin-rest-api/internal/utils"
+)
+
+// GetTokenVersion returns the current access token version for a user.
+// Tokens carrying an older version are no longer accepted.
+func (r *RedisClient) GetTokenVersion(userId int64) (int64, error) {
+	value, err := r.Get(utils.ConstructRedisTokenVersionKey(userId))
+	if err != nil {
+		if errors.Is(err, redis.Nil) {
+			return 0, nil
+		}
+		return 0, err
+	}
+
+	return strconv.ParseInt(value, 10, 64)
+}
+
+// IncrementTokenVersion invalidates every access token issued to a user so far.
+func (r *RedisClient) IncrementTokenVersion(userId int64) error {
+	ctx, cancel := utils.CreateContext()
+	defer cancel()
+
+	return r.Client.Incr(ctx, utils.ConstructRedisTokenVersionKey(userId)).Err()
+}
+
+// RevokeToken adds a token id to the revocation list until the token would have expired anyway.
+func (r *RedisClient) RevokeToken(jti string, expiresAt time.Time) error {
+	ttl := time.Until(expiresAt)
+	if ttl <= 0 {
+		return nil
+	}
+
+	return r.Set(utils.ConstructRedisRevokedTokenKey(jti), 1, ttl)
+}
+
+func (r *RedisClient) IsTokenRevoked(jti string) (bool, error) {
+	ctx, cancel := utils.CreateContext()
+	defer cancel()
+
+	count, err := r.Client.Exists(ctx, utils.ConstructRedisRevokedTokenKey(jti)).Result()
+	if err != nil {
+		return false, err
+	}
+
+	return count > 0, nil
+}
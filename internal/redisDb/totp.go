@@ -0,0 +1,33 @@
+package redisDb
+
+import (
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"github.com/vickon16/go-gin-rest-api/internal/utils"
+)
+
+// Stores the step only when it is newer than the one already stored, so two
+// requests with the same code cannot both pass
+var useTOTPStepScript = redis.NewScript(`
+local last = tonumber(redis.call("GET", KEYS[1]))
+if last and last >= tonumber(ARGV[1]) then
+	return 0
+end
+redis.call("SET", KEYS[1], ARGV[1], "PX", ARGV[2])
+return 1
+`)
+
+// UseTOTPStep records the time step of an accepted TOTP code. It returns false when the
+// user already used this step or a later one.
+func (r *RedisClient) UseTOTPStep(userId, step int64, ttl time.Duration) (bool, error) {
+	ctx, cancel := utils.CreateContext()
+	defer cancel()
+
+	used, err := useTOTPStepScript.Run(ctx, r.Client, []string{utils.ConstructRedisTwoFactorStepKey(userId)}, step, ttl.Milliseconds()).Int()
+	if err != nil {
+		return false, err
+	}
+
+	return used == 1, nil
+}
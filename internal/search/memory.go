@@ -0,0 +1,166 @@
+package search
+
+import (
+	"context"
+	"slices"
+	"strings"
+	"sync"
+	"unicode"
+
+	"github.com/vickon16/go-gin-rest-api/internal/database/models"
+)
+
+// How much a matched word counts in each field, mirroring the weights of the search_vector column
+const (
+	nameWeight        = 1.0
+	locationWeight    = 0.4
+	descriptionWeight = 0.2
+)
+
+// Descriptions are cut down to this many words around the first match
+const descriptionSnippetWords = 30
+
+// MemoryBackend keeps the events in memory and matches words by prefix.
+// Meant for tests and local development, it has to be filled with Index on startup.
+type MemoryBackend struct {
+	mu     sync.RWMutex
+	events map[int64]*models.Event
+}
+
+func NewMemoryBackend() *MemoryBackend {
+	return &MemoryBackend{events: map[int64]*models.Event{}}
+}
+
+func (b *MemoryBackend) Search(ctx context.Context, query string, options *models.ListOptions) ([]*Hit, int64, error) {
+	terms := tokenize(query)
+	if len(terms) == 0 {
+		return []*Hit{}, 0, nil
+	}
+
+	b.mu.RLock()
+	hits := []*Hit{}
+	for _, event := range b.events {
+		rank, ok := rankEvent(event, terms)
+		if !ok {
+			continue
+		}
+
+		copied := *event
+		hits = append(hits, &Hit{
+			Event: &copied,
+			Rank:  rank,
+			Highlights: Highlights{
+				Name:        renderHighlight(markMatches(event.Name, terms, 0)),
+				Description: renderHighlight(markMatches(event.Description, terms, descriptionSnippetWords)),
+				Location:    renderHighlight(markMatches(event.Location, terms, 0)),
+			},
+		})
+	}
+	b.mu.RUnlock()
+
+	slices.SortFunc(hits, func(a, b *Hit) int {
+		if a.Rank != b.Rank {
+			if a.Rank > b.Rank {
+				return -1
+			}
+			return 1
+		}
+		return int(a.Event.ID - b.Event.ID)
+	})
+
+	total := int64(len(hits))
+	start := min(int(options.Offset()), len(hits))
+	end := min(start+options.Limit, len(hits))
+
+	return hits[start:end], total, nil
+}
+
+func (b *MemoryBackend) Index(ctx context.Context, event *models.Event) error {
+	copied := *event
+	copied.User = nil
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.events[event.ID] = &copied
+	return nil
+}
+
+func (b *MemoryBackend) Remove(ctx context.Context, eventId int64) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	delete(b.events, eventId)
+	return nil
+}
+
+// rankEvent sums the weights of the fields each term matches. Every term has to match somewhere.
+func rankEvent(event *models.Event, terms []string) (float64, bool) {
+	fields := []struct {
+		words  []string
+		weight float64
+	}{
+		{tokenize(event.Name), nameWeight},
+		{tokenize(event.Location), locationWeight},
+		{tokenize(event.Description), descriptionWeight},
+	}
+
+	var rank float64
+	for _, term := range terms {
+		matched := false
+		for _, field := range fields {
+			if slices.ContainsFunc(field.words, func(word string) bool { return strings.HasPrefix(word, term) }) {
+				rank += field.weight
+				matched = true
+			}
+		}
+		if !matched {
+			return 0, false
+		}
+	}
+
+	return rank, true
+}
+
+// markMatches wraps the words of the text that match a term in the match markers.
+// With a window, only that many words starting a little before the first match are kept.
+func markMatches(text string, terms []string, window int) string {
+	words := strings.Fields(stripMarks(text))
+	first := -1
+
+	for i, word := range words {
+		if slices.ContainsFunc(tokenize(word), func(token string) bool {
+			return slices.ContainsFunc(terms, func(term string) bool { return strings.HasPrefix(token, term) })
+		}) {
+			words[i] = startMark + word + stopMark
+			if first < 0 {
+				first = i
+			}
+		}
+	}
+
+	if window <= 0 || len(words) <= window {
+		return strings.Join(words, " ")
+	}
+
+	start := max(first-5, 0)
+	end := min(start+window, len(words))
+	start = max(end-window, 0)
+
+	snippet := strings.Join(words[start:end], " ")
+	if start > 0 {
+		snippet = "… " + snippet
+	}
+	if end < len(words) {
+		snippet += " …"
+	}
+
+	return snippet
+}
+
+// tokenize splits the text into lowercase words.
+func tokenize(text string) []string {
+	return strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsNumber(r)
+	})
+}
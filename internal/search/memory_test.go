@@ -0,0 +1,181 @@
+package search
+
+import (
+	"context"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/vickon16/go-gin-rest-api/internal/database/models"
+)
+
+func newTestBackend(t *testing.T, events ...*models.Event) *MemoryBackend {
+	t.Helper()
+
+	backend := NewMemoryBackend()
+	for _, event := range events {
+		if err := backend.Index(context.Background(), event); err != nil {
+			t.Fatalf("Index(%d) returned %v", event.ID, err)
+		}
+	}
+
+	return backend
+}
+
+func hitIDs(hits []*Hit) []int64 {
+	ids := make([]int64, len(hits))
+	for i, hit := range hits {
+		ids[i] = hit.Event.ID
+	}
+	return ids
+}
+
+func TestMemoryBackendRanking(t *testing.T) {
+	backend := newTestBackend(t,
+		&models.Event{ID: 1, Name: "Board games night", Location: "Jazz cafe", Description: "Bring your own snacks"},
+		&models.Event{ID: 2, Name: "Jazz evening", Location: "Town hall", Description: "Live music"},
+		&models.Event{ID: 3, Name: "Book club", Location: "Library", Description: "This month we read about jazz musicians"},
+		&models.Event{ID: 4, Name: "Jazz in the park", Location: "Central park", Description: "Open air jazz concert"},
+		&models.Event{ID: 5, Name: "Cooking class", Location: "Community kitchen", Description: "Italian dishes"},
+	)
+
+	tests := []struct {
+		name  string
+		query string
+		want  []int64
+	}{
+		{"name outranks location and description", "jazz", []int64{4, 2, 1, 3}},
+		{"every term has to match", "jazz park", []int64{4}},
+		{"terms match word prefixes", "cook", []int64{5}},
+		{"matching ignores case", "LIBRARY", []int64{3}},
+		{"no match", "karaoke", []int64{}},
+		{"punctuation only", "!!", []int64{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			hits, total, err := backend.Search(context.Background(), tt.query, models.NewListOptions())
+			if err != nil {
+				t.Fatalf("Search(%q) returned %v", tt.query, err)
+			}
+
+			got := hitIDs(hits)
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("Search(%q) = %v, want %v", tt.query, got, tt.want)
+			}
+			if total != int64(len(tt.want)) {
+				t.Errorf("Search(%q) total = %d, want %d", tt.query, total, len(tt.want))
+			}
+		})
+	}
+}
+
+// Equal ranks are ordered by id, so pages do not overlap
+func TestMemoryBackendPagination(t *testing.T) {
+	backend := newTestBackend(t,
+		&models.Event{ID: 1, Name: "Run one"},
+		&models.Event{ID: 2, Name: "Run two"},
+		&models.Event{ID: 3, Name: "Run three"},
+	)
+
+	tests := []struct {
+		page int
+		want []int64
+	}{
+		{1, []int64{1, 2}},
+		{2, []int64{3}},
+		{3, []int64{}},
+	}
+
+	for _, tt := range tests {
+		options := &models.ListOptions{Page: tt.page, Limit: 2}
+
+		hits, total, err := backend.Search(context.Background(), "run", options)
+		if err != nil {
+			t.Fatalf("Search page %d returned %v", tt.page, err)
+		}
+		if got := hitIDs(hits); !slices.Equal(got, tt.want) {
+			t.Errorf("page %d = %v, want %v", tt.page, got, tt.want)
+		}
+		if total != 3 {
+			t.Errorf("page %d total = %d, want 3", tt.page, total)
+		}
+	}
+}
+
+func TestMemoryBackendRemove(t *testing.T) {
+	backend := newTestBackend(t, &models.Event{ID: 1, Name: "Jazz evening"})
+
+	if err := backend.Remove(context.Background(), 1); err != nil {
+		t.Fatalf("Remove returned %v", err)
+	}
+
+	hits, _, err := backend.Search(context.Background(), "jazz", models.NewListOptions())
+	if err != nil {
+		t.Fatalf("Search returned %v", err)
+	}
+	if len(hits) != 0 {
+		t.Errorf("Search after Remove = %v, want no hits", hitIDs(hits))
+	}
+}
+
+func TestMemoryBackendHighlights(t *testing.T) {
+	longDescription := strings.Repeat("filler ", 40) + "jazz " + strings.Repeat("filler ", 40)
+
+	tests := []struct {
+		name  string
+		event *models.Event
+		query string
+		want  Highlights
+	}{
+		{
+			name:  "matched words are marked in every field",
+			event: &models.Event{ID: 1, Name: "Jazz evening", Location: "Jazz cafe", Description: "Live jazz"},
+			query: "jazz",
+			want: Highlights{
+				Name:        "<mark>Jazz</mark> evening",
+				Location:    "<mark>Jazz</mark> cafe",
+				Description: "Live <mark>jazz</mark>",
+			},
+		},
+		{
+			name:  "text is HTML escaped",
+			event: &models.Event{ID: 1, Name: "<b>Jazz</b> & blues"},
+			query: "jazz",
+			want:  Highlights{Name: "<mark>&lt;b&gt;Jazz&lt;/b&gt;</mark> &amp; blues"},
+		},
+		{
+			name:  "markers already in the text are not turned into tags",
+			event: &models.Event{ID: 1, Name: "Jazz \uE000night\uE001", Location: "\uE000<script>\uE001"},
+			query: "jazz",
+			want:  Highlights{Name: "<mark>Jazz</mark> night", Location: "&lt;script&gt;"},
+		},
+		{
+			name:  "long descriptions are cut down around the first match",
+			event: &models.Event{ID: 1, Name: "Jazz", Description: longDescription},
+			query: "jazz",
+			want: Highlights{
+				Name:        "<mark>Jazz</mark>",
+				Description: "… " + strings.Repeat("filler ", 5) + "<mark>jazz</mark>" + strings.Repeat(" filler", 24) + " …",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			backend := newTestBackend(t, tt.event)
+
+			hits, _, err := backend.Search(context.Background(), tt.query, models.NewListOptions())
+			if err != nil {
+				t.Fatalf("Search returned %v", err)
+			}
+			if len(hits) != 1 {
+				t.Fatalf("Search returned %d hits, want 1", len(hits))
+			}
+
+			if got := hits[0].Highlights; got != tt.want {
+				t.Errorf("Highlights = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
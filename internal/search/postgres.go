@@ -0,0 +1,97 @@
+package search
+
+import (
+	"context"
+	"database/sql"
+
+	sq "github.com/Masterminds/squirrel"
+	"github.com/vickon16/go-gin-rest-api/internal/database/models"
+)
+
+// Options for ts_headline. Names and locations are short and highlighted whole,
+// descriptions are cut down to the fragments around the matches.
+const (
+	headlineOptions            = "StartSel=" + startMark + ", StopSel=" + stopMark + ", HighlightAll=true"
+	descriptionHeadlineOptions = "StartSel=" + startMark + ", StopSel=" + stopMark + `, MaxFragments=2, MinWords=10, MaxWords=30, FragmentDelimiter=" … "`
+)
+
+// PostgresBackend searches the events.search_vector column. The column is generated
+// by the database, so indexing and removing are no-ops.
+type PostgresBackend struct {
+	DB *sql.DB
+}
+
+func (b *PostgresBackend) Search(ctx context.Context, query string, options *models.ListOptions) ([]*Hit, int64, error) {
+	matches := sq.Select().
+		From("events e").
+		JoinClause("CROSS JOIN websearch_to_tsquery('english', ?) q", query).
+		Where("e.search_vector @@ q").
+		PlaceholderFormat(sq.Dollar)
+
+	sqlStr, args, err := matches.Columns("COUNT(*)").ToSql()
+	if err != nil {
+		return nil, 0, err
+	}
+
+	var total int64
+	err = b.DB.QueryRowContext(ctx, sqlStr, args...).Scan(&total)
+	if err != nil || total == 0 {
+		return []*Hit{}, total, err
+	}
+
+	sqlStr, args, err = matches.
+		Columns("e.id", "e.user_id", "e.name", "e.description", "e.date", "e.location", "e.capacity", "e.created_at").
+		Column("ts_rank(e.search_vector, q) AS rank").
+		// Markers already in the text are removed first, see stripMarks
+		Column("ts_headline('english', translate(e.name, ?, ''), q, ?)", startMark+stopMark, headlineOptions).
+		Column("ts_headline('english', translate(e.description, ?, ''), q, ?)", startMark+stopMark, descriptionHeadlineOptions).
+		Column("ts_headline('english', translate(e.location, ?, ''), q, ?)", startMark+stopMark, headlineOptions).
+		OrderBy("rank DESC", "e.id ASC").
+		Limit(uint64(options.Limit)).
+		Offset(options.Offset()).
+		ToSql()
+	if err != nil {
+		return nil, 0, err
+	}
+
+	rows, err := b.DB.QueryContext(ctx, sqlStr, args...)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	defer rows.Close()
+
+	hits := []*Hit{}
+
+	for rows.Next() {
+		var event models.Event
+		hit := Hit{Event: &event}
+
+		if err := rows.Scan(&event.ID, &event.UserID, &event.Name, &event.Description, &event.Date, &event.Location, &event.Capacity, &event.CreatedAt,
+			&hit.Rank, &hit.Highlights.Name, &hit.Highlights.Description, &hit.Highlights.Location,
+		); err != nil {
+			return nil, 0, err
+		}
+
+		hit.Highlights = Highlights{
+			Name:        renderHighlight(hit.Highlights.Name),
+			Description: renderHighlight(hit.Highlights.Description),
+			Location:    renderHighlight(hit.Highlights.Location),
+		}
+		hits = append(hits, &hit)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, 0, err
+	}
+
+	return hits, total, nil
+}
+
+func (b *PostgresBackend) Index(ctx context.Context, event *models.Event) error {
+	return nil
+}
+
+func (b *PostgresBackend) Remove(ctx context.Context, eventId int64) error {
+	return nil
+}
@@ -0,0 +1,73 @@
+package search
+
+import (
+	"context"
+	"database/sql"
+	"html"
+	"log"
+	"strings"
+
+	"github.com/vickon16/go-gin-rest-api/internal/database/models"
+	"github.com/vickon16/go-gin-rest-api/internal/env"
+)
+
+// Backend finds events by keyword.
+type Backend interface {
+	// Search returns one page of the events matching the query, best match first, and the number of all matches.
+	Search(ctx context.Context, query string, options *models.ListOptions) ([]*Hit, int64, error)
+	// Index adds or replaces the event in the index.
+	Index(ctx context.Context, event *models.Event) error
+	// Remove drops the event from the index.
+	Remove(ctx context.Context, eventId int64) error
+}
+
+// Hit is an event matching a search.
+type Hit struct {
+	Event      *models.Event
+	Rank       float64
+	Highlights Highlights
+}
+
+// Highlights are HTML escaped excerpts of the event with the matched words wrapped in <mark>.
+type Highlights struct {
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	Location    string `json:"location"`
+}
+
+// NewBackend builds the backend selected by the SEARCH_DRIVER env variable.
+// Supported drivers are "postgres" and "memory".
+func NewBackend(db *sql.DB) Backend {
+	switch driver := env.GetEnvString("SEARCH_DRIVER", "postgres"); driver {
+	case "postgres":
+		return &PostgresBackend{DB: db}
+	case "memory":
+		return NewMemoryBackend()
+	default:
+		log.Fatalf("Unknown search driver: %s", driver)
+		return nil
+	}
+}
+
+// Backends mark matches with these private use characters, which survive HTML
+// escaping. They are stripped from the text first, so they cannot be confused with it.
+const (
+	startMark = "\uE000"
+	stopMark  = "\uE001"
+)
+
+var (
+	markReplacer = strings.NewReplacer(startMark, "<mark>", stopMark, "</mark>")
+	markStripper = strings.NewReplacer(startMark, "", stopMark, "")
+)
+
+// stripMarks removes the match markers from text before it is marked, so markers
+// already present in an event cannot turn into <mark> tags.
+func stripMarks(text string) string {
+	return markStripper.Replace(text)
+}
+
+// renderHighlight escapes the text and turns the match markers into <mark> tags.
+func renderHighlight(marked string) string {
+	return markReplacer.Replace(html.EscapeString(marked))
+}
@@ -0,0 +1,83 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestLocalPutAndDelete(t *testing.T) {
+	dir := t.TempDir()
+	local := &LocalStorage{Dir: dir, BaseURL: "http://localhost:8080/uploads/"}
+	ctx := context.Background()
+
+	if err := local.Put(ctx, "avatars/1/a.png", []byte("image"), "image/png"); err != nil {
+		t.Fatalf("Put returned %v", err)
+	}
+
+	data, err := os.ReadFile(filepath.Join(dir, "avatars", "1", "a.png"))
+	if err != nil || string(data) != "image" {
+		t.Fatalf("stored file = %q, %v", data, err)
+	}
+
+	// No temporary file is left next to it
+	entries, err := os.ReadDir(filepath.Join(dir, "avatars", "1"))
+	if err != nil || len(entries) != 1 {
+		t.Errorf("directory holds %v, %v", entries, err)
+	}
+
+	if got, want := local.URL("avatars/1/a.png"), "http://localhost:8080/uploads/avatars/1/a.png"; got != want {
+		t.Errorf("URL = %q, want %q", got, want)
+	}
+
+	if err := local.Delete(ctx, "avatars/1/a.png"); err != nil {
+		t.Fatalf("Delete returned %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "avatars", "1", "a.png")); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("file still exists after Delete: %v", err)
+	}
+
+	// Deleting twice is not an error
+	if err := local.Delete(ctx, "avatars/1/a.png"); err != nil {
+		t.Errorf("Delete of a missing file returned %v", err)
+	}
+}
+
+func TestLocalRejectsKeysOutsideDir(t *testing.T) {
+	root := t.TempDir()
+	dir := filepath.Join(root, "uploads")
+	outside := filepath.Join(root, "outside.txt")
+	if err := os.WriteFile(outside, []byte("keep"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	local := &LocalStorage{Dir: dir}
+	ctx := context.Background()
+
+	keys := []string{
+		"",
+		".",
+		"/",
+		"../outside.txt",
+		"avatars/../../outside.txt",
+		"/outside.txt",
+		"avatars//a.png",
+		"avatars/./a.png",
+		"avatars/",
+	}
+
+	for _, key := range keys {
+		if err := local.Put(ctx, key, []byte("overwritten"), "text/plain"); err == nil {
+			t.Errorf("Put(%q) succeeded", key)
+		}
+		if err := local.Delete(ctx, key); err == nil {
+			t.Errorf("Delete(%q) succeeded", key)
+		}
+	}
+
+	if data, err := os.ReadFile(outside); err != nil || string(data) != "keep" {
+		t.Errorf("file outside the storage dir = %q, %v", data, err)
+	}
+}
@@ -0,0 +1,45 @@
+package storage
+
+import (
+	"context"
+	"log"
+	"strings"
+
+	"github.com/vickon16/go-gin-rest-api/internal/env"
+)
+
+// Storage keeps uploaded files and tells where they can be downloaded from.
+type Storage interface {
+	Put(ctx context.Context, key string, data []byte, contentType string) error
+	Delete(ctx context.Context, key string) error
+	URL(key string) string
+}
+
+// NewStorage builds the storage selected by the STORAGE_DRIVER env variable.
+// Supported drivers are "local" and "s3".
+func NewStorage() Storage {
+	switch driver := env.GetEnvString("STORAGE_DRIVER", "local"); driver {
+	case "local":
+		apiUrl := env.GetEnvString("API_URL", "http://localhost:8080")
+		return &LocalStorage{
+			Dir:     env.GetEnvString("STORAGE_LOCAL_DIR", "./tmp/uploads"),
+			BaseURL: env.GetEnvString("STORAGE_PUBLIC_URL", apiUrl+LocalURLPath),
+		}
+	case "s3":
+		s3 := &S3Storage{
+			Endpoint:        strings.TrimSuffix(env.GetEnvString("S3_ENDPOINT", "https://s3.amazonaws.com"), "/"),
+			Region:          env.GetEnvString("S3_REGION", "us-east-1"),
+			Bucket:          env.GetEnvString("S3_BUCKET", ""),
+			AccessKeyID:     env.GetEnvString("S3_ACCESS_KEY_ID", ""),
+			SecretAccessKey: env.GetEnvString("S3_SECRET_ACCESS_KEY", ""),
+			PublicURL:       strings.TrimSuffix(env.GetEnvString("STORAGE_PUBLIC_URL", ""), "/"),
+		}
+		if s3.Bucket == "" {
+			log.Fatal("S3_BUCKET is required for the s3 storage driver")
+		}
+		return s3
+	default:
+		log.Fatalf("Unknown storage driver: %s", driver)
+		return nil
+	}
+}
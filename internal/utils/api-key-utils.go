@@ -0,0 +1,45 @@
+package utils
+
+import (
+	"crypto/rand"
+	"encoding/base32"
+	"strings"
+)
+
+// Every API key starts with this marker, so it can be told apart from a JWT.
+const APIKeyMarker = "gga_"
+
+const apiKeyPrefixLength = 8
+
+var apiKeyEncoding = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+// GenerateAPIKey returns a new key in the form gga_<prefix>_<secret>.
+// The prefix is stored in clear to find the key, the whole key is only stored hashed.
+func GenerateAPIKey() (key, prefix string, err error) {
+	prefixBytes := make([]byte, 5)
+	if _, err := rand.Read(prefixBytes); err != nil {
+		return "", "", err
+	}
+
+	secret, err := GenerateOpaqueToken()
+	if err != nil {
+		return "", "", err
+	}
+
+	prefix = strings.ToLower(apiKeyEncoding.EncodeToString(prefixBytes))[:apiKeyPrefixLength]
+	return APIKeyMarker + prefix + "_" + secret, prefix, nil
+}
+
+func IsAPIKey(value string) bool {
+	return strings.HasPrefix(value, APIKeyMarker)
+}
+
+// ParseAPIKeyPrefix extracts the lookup prefix of a key.
+func ParseAPIKeyPrefix(key string) (string, bool) {
+	rest, ok := strings.CutPrefix(key, APIKeyMarker)
+	if !ok || len(rest) <= apiKeyPrefixLength+1 || rest[apiKeyPrefixLength] != '_' {
+		return "", false
+	}
+
+	return rest[:apiKeyPrefixLength], true
+}
@@ -0,0 +1,45 @@
+package utils
+
+import "testing"
+
+func TestEncryptRoundTrip(t *testing.T) {
+	sealed, err := Encrypt(rfc6238Secret)
+	if err != nil {
+		t.Fatalf("Encrypt returned %v", err)
+	}
+	if sealed == rfc6238Secret {
+		t.Fatal("Encrypt returned the plaintext")
+	}
+
+	opened, err := Decrypt(sealed)
+	if err != nil {
+		t.Fatalf("Decrypt returned %v", err)
+	}
+	if opened != rfc6238Secret {
+		t.Errorf("Decrypt = %q, want %q", opened, rfc6238Secret)
+	}
+
+	again, err := Encrypt(rfc6238Secret)
+	if err != nil {
+		t.Fatalf("Encrypt returned %v", err)
+	}
+	if again == sealed {
+		t.Error("Encrypt is deterministic, every value needs its own nonce")
+	}
+}
+
+func TestDecryptRejectsTampering(t *testing.T) {
+	sealed, err := Encrypt("secret")
+	if err != nil {
+		t.Fatalf("Encrypt returned %v", err)
+	}
+
+	tampered := []byte(sealed)
+	tampered[len(tampered)/2] ^= 'A' ^ 'B'
+
+	for _, ciphertext := range []string{string(tampered), "c2hvcnQ", "not base64!"} {
+		if _, err := Decrypt(ciphertext); err == nil {
+			t.Errorf("Decrypt(%q) succeeded", ciphertext)
+		}
+	}
+}
@@ -0,0 +1,83 @@
+package utils
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"strings"
+)
+
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+var cursorSecret = &envSecret{name: "CURSOR_SECRET"}
+
+// LoadCursorSecret reads CURSOR_SECRET once. Call it at startup so a missing
+// or short secret stops the server instead of failing the first paginated list.
+func LoadCursorSecret() error {
+	_, err := cursorSecret.load()
+	return err
+}
+
+// SignCursor encodes a pagination position as an opaque token. It is signed with
+// the CURSOR_SECRET env variable, so clients cannot forge positions. The scope names
+// the list the position belongs to and is signed along with it, so a cursor is only
+// accepted by the list, order and filters it was issued for.
+func SignCursor(scope string, position any) (string, error) {
+	payload, err := json.Marshal(position)
+	if err != nil {
+		return "", err
+	}
+
+	encoded := base64.RawURLEncoding.EncodeToString(payload)
+	signature, err := cursorSignature(scope, encoded)
+	if err != nil {
+		return "", err
+	}
+
+	return encoded + "." + base64.RawURLEncoding.EncodeToString(signature), nil
+}
+
+// VerifyCursor checks the signature of a token made by SignCursor for the same scope and decodes its position.
+func VerifyCursor(cursor string, scope string, position any) error {
+	encoded, signature, ok := strings.Cut(cursor, ".")
+	if !ok {
+		return ErrInvalidCursor
+	}
+
+	expected, err := cursorSignature(scope, encoded)
+	if err != nil {
+		return err
+	}
+
+	decodedSignature, err := base64.RawURLEncoding.DecodeString(signature)
+	if err != nil || !hmac.Equal(decodedSignature, expected) {
+		return ErrInvalidCursor
+	}
+
+	payload, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil {
+		return ErrInvalidCursor
+	}
+	if err := json.Unmarshal(payload, position); err != nil {
+		return ErrInvalidCursor
+	}
+
+	return nil
+}
+
+func cursorSignature(scope string, encoded string) ([]byte, error) {
+	secret, err := cursorSecret.load()
+	if err != nil {
+		return nil, err
+	}
+
+	// The scope is hashed to a fixed length, so it cannot run into the payload
+	scopeHash := sha256.Sum256([]byte(scope))
+
+	mac := hmac.New(sha256.New, secret)
+	mac.Write(scopeHash[:])
+	mac.Write([]byte(encoded))
+	return mac.Sum(nil), nil
+}
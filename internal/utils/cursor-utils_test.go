@@ -0,0 +1,68 @@
+package utils
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+type testPosition struct {
+	Value string `json:"value"`
+	ID    int64  `json:"id"`
+}
+
+func TestCursorRoundTrip(t *testing.T) {
+	position := testPosition{Value: "2026-01-02T15:04:05Z", ID: 42}
+
+	cursor, err := SignCursor("/events date {}", position)
+	if err != nil {
+		t.Fatalf("SignCursor returned %v", err)
+	}
+
+	var decoded testPosition
+	if err := VerifyCursor(cursor, "/events date {}", &decoded); err != nil {
+		t.Fatalf("VerifyCursor returned %v", err)
+	}
+	if decoded != position {
+		t.Errorf("VerifyCursor decoded %+v, want %+v", decoded, position)
+	}
+}
+
+func TestVerifyCursorRejects(t *testing.T) {
+	const scope = "/events date {}"
+
+	cursor, err := SignCursor(scope, testPosition{ID: 42})
+	if err != nil {
+		t.Fatalf("SignCursor returned %v", err)
+	}
+	encoded, signature, _ := strings.Cut(cursor, ".")
+
+	forged, err := SignCursor(scope, testPosition{ID: 43})
+	if err != nil {
+		t.Fatalf("SignCursor returned %v", err)
+	}
+	forgedEncoded, _, _ := strings.Cut(forged, ".")
+
+	tests := []struct {
+		name   string
+		cursor string
+		scope  string
+	}{
+		{"another list", cursor, "/events/1/attendees createdAt {}"},
+		{"another sort", cursor, "/events name {}"},
+		{"other filters", cursor, `/events date {"location":"Berlin"}`},
+		{"payload swapped", forgedEncoded + "." + signature, scope},
+		{"signature missing", encoded, scope},
+		{"signature not base64", encoded + ".!!!", scope},
+		{"empty", "", scope},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var decoded testPosition
+			if err := VerifyCursor(tt.cursor, tt.scope, &decoded); !errors.Is(err, ErrInvalidCursor) {
+				t.Errorf("VerifyCursor returned %v, want ErrInvalidCursor", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,249 @@
+package utils
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/vickon16/go-gin-rest-api/internal/env"
+)
+
+const defaultJWTSecret = "some-secret-123456"
+
+type jwtKey struct {
+	kid    string
+	method jwt.SigningMethod
+	public crypto.PublicKey
+}
+
+type jwtKeySet struct {
+	method     jwt.SigningMethod
+	signingKid string
+	signingKey any
+
+	// Keys accepted when verifying, by kid. The signing key is always one of them.
+	verificationKeys map[string]jwtKey
+}
+
+type JSONWebKey struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	Alg string `json:"alg"`
+	N   string `json:"n,omitempty"`
+	E   string `json:"e,omitempty"`
+	Crv string `json:"crv,omitempty"`
+	X   string `json:"x,omitempty"`
+}
+
+type JSONWebKeySet struct {
+	Keys []JSONWebKey `json:"keys"`
+}
+
+var (
+	jwtKeys     *jwtKeySet
+	jwtKeysErr  error
+	jwtKeysOnce sync.Once
+)
+
+// LoadJWTKeys reads the signing configuration once. Call it at startup so
+// a bad configuration stops the server instead of failing the first login.
+//
+//	JWT_ALGORITHM                HS256 (default), RS256 or EdDSA
+//	JWT_SECRET                   HMAC secret, for HS256
+//	JWT_SIGNING_KEY_FILE         PEM private key, for RS256 and EdDSA
+//	JWT_VERIFICATION_KEY_FILES   comma separated PEM keys still accepted during a rotation
+func LoadJWTKeys() error {
+	jwtKeysOnce.Do(func() {
+		jwtKeys, jwtKeysErr = loadJWTKeySet()
+	})
+
+	return jwtKeysErr
+}
+
+// GetJWKS returns the public verification keys. It is empty for HS256.
+func GetJWKS() (JSONWebKeySet, error) {
+	if err := LoadJWTKeys(); err != nil {
+		return JSONWebKeySet{}, err
+	}
+
+	set := JSONWebKeySet{Keys: []JSONWebKey{}}
+	for _, key := range jwtKeys.verificationKeys {
+		if jwk, ok := toJSONWebKey(key); ok {
+			set.Keys = append(set.Keys, jwk)
+		}
+	}
+
+	return set, nil
+}
+
+func loadJWTKeySet() (*jwtKeySet, error) {
+	algorithm := env.GetEnvString("JWT_ALGORITHM", "HS256")
+
+	if algorithm == "HS256" {
+		secret := env.GetEnvString("JWT_SECRET", defaultJWTSecret)
+		// An unset APP_ENV counts as production, so a forgotten variable cannot ship the default secret
+		if secret == defaultJWTSecret && env.GetEnvString("APP_ENV", "") != "development" {
+			return nil, errors.New("JWT_SECRET must be set unless APP_ENV is development")
+		}
+
+		return &jwtKeySet{
+			method:           jwt.SigningMethodHS256,
+			signingKey:       []byte(secret),
+			verificationKeys: map[string]jwtKey{},
+		}, nil
+	}
+
+	signingKeyFile := env.GetEnvString("JWT_SIGNING_KEY_FILE", "")
+	if signingKeyFile == "" {
+		return nil, fmt.Errorf("JWT_SIGNING_KEY_FILE is required for %s", algorithm)
+	}
+
+	parsed, err := readPEMKey(signingKeyFile)
+	if err != nil {
+		return nil, err
+	}
+
+	signer, ok := parsed.(crypto.Signer)
+	if !ok {
+		return nil, fmt.Errorf("%s does not contain a private key", signingKeyFile)
+	}
+
+	signingKey, err := newJWTKey(signer.Public())
+	if err != nil {
+		return nil, err
+	}
+	if signingKey.method.Alg() != algorithm {
+		return nil, fmt.Errorf("%s holds a %s key but JWT_ALGORITHM is %s", signingKeyFile, signingKey.method.Alg(), algorithm)
+	}
+
+	set := &jwtKeySet{
+		method:           signingKey.method,
+		signingKid:       signingKey.kid,
+		signingKey:       signer,
+		verificationKeys: map[string]jwtKey{signingKey.kid: signingKey},
+	}
+
+	for _, file := range strings.Split(env.GetEnvString("JWT_VERIFICATION_KEY_FILES", ""), ",") {
+		file = strings.TrimSpace(file)
+		if file == "" {
+			continue
+		}
+
+		parsed, err := readPEMKey(file)
+		if err != nil {
+			return nil, err
+		}
+
+		public := parsed
+		if signer, ok := parsed.(crypto.Signer); ok {
+			public = signer.Public()
+		}
+
+		key, err := newJWTKey(public)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", file, err)
+		}
+		set.verificationKeys[key.kid] = key
+	}
+
+	return set, nil
+}
+
+func newJWTKey(public crypto.PublicKey) (jwtKey, error) {
+	key := jwtKey{public: public}
+
+	switch public.(type) {
+	case *rsa.PublicKey:
+		key.method = jwt.SigningMethodRS256
+	case ed25519.PublicKey:
+		key.method = jwt.SigningMethodEdDSA
+	default:
+		return jwtKey{}, fmt.Errorf("unsupported key type %T", public)
+	}
+
+	jwk, _ := toJSONWebKey(key)
+	key.kid = jwkThumbprint(jwk)
+
+	return key, nil
+}
+
+func readPEMKey(path string) (any, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("%s is not a PEM file", path)
+	}
+
+	switch block.Type {
+	case "PRIVATE KEY":
+		return x509.ParsePKCS8PrivateKey(block.Bytes)
+	case "RSA PRIVATE KEY":
+		return x509.ParsePKCS1PrivateKey(block.Bytes)
+	case "PUBLIC KEY":
+		return x509.ParsePKIXPublicKey(block.Bytes)
+	case "RSA PUBLIC KEY":
+		return x509.ParsePKCS1PublicKey(block.Bytes)
+	default:
+		return nil, fmt.Errorf("%s: unsupported PEM block %q", path, block.Type)
+	}
+}
+
+func toJSONWebKey(key jwtKey) (JSONWebKey, bool) {
+	encode := base64.RawURLEncoding.EncodeToString
+
+	switch public := key.public.(type) {
+	case *rsa.PublicKey:
+		return JSONWebKey{
+			Kty: "RSA", Kid: key.kid, Use: "sig", Alg: "RS256",
+			N: encode(public.N.Bytes()),
+			E: encode(big.NewInt(int64(public.E)).Bytes()),
+		}, true
+	case ed25519.PublicKey:
+		return JSONWebKey{
+			Kty: "OKP", Kid: key.kid, Use: "sig", Alg: "EdDSA",
+			Crv: "Ed25519",
+			X:   encode(public),
+		}, true
+	}
+
+	return JSONWebKey{}, false
+}
+
+// jwkThumbprint derives a stable kid from the public key (RFC 7638).
+func jwkThumbprint(jwk JSONWebKey) string {
+	var members any
+	if jwk.Kty == "RSA" {
+		members = struct {
+			E   string `json:"e"`
+			Kty string `json:"kty"`
+			N   string `json:"n"`
+		}{jwk.E, jwk.Kty, jwk.N}
+	} else {
+		members = struct {
+			Crv string `json:"crv"`
+			Kty string `json:"kty"`
+			X   string `json:"x"`
+		}{jwk.Crv, jwk.Kty, jwk.X}
+	}
+
+	data, _ := json.Marshal(members)
+	sum := sha256.Sum256(data)
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
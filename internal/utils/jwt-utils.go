@@ -9,31 +9,92 @@ import (
 )
 
 type CustomClaims struct {
-	UserID int64  `json:"userId"`
-	Email  string `json:"email"`
+	UserID       int64  `json:"userId"`
+	Email        string `json:"email"`
+	Role         string `json:"role"`
+	TokenVersion int64  `json:"ver"`
+	SessionID    int64  `json:"sid,omitempty"`
+
+	// Only set on impersonation tokens
+	Actor *ActorClaim `json:"act,omitempty"`
 	jwt.RegisteredClaims
 }
 
-func GenerateLoginToken(userId int64, email string) (string, error) {
-	jwtSecret := env.GetEnvString("JWT_SECRET", "some-secret-123456")
+// ActorClaim identifies the admin behind an impersonation token (RFC 8693 "act").
+type ActorClaim struct {
+	UserID       int64  `json:"userId"`
+	Email        string `json:"email"`
+	TokenVersion int64  `json:"ver"`
+}
+
+func GenerateLoginToken(userId int64, email, role string, tokenVersion, sessionId int64) (string, error) {
 	jwtExpirationMinutes := env.GetEnvInt("JWT_EXPIRATION_MINUTES", 10)
 	duration := time.Duration(jwtExpirationMinutes) * time.Minute // 10 * 60 // 10 minutes
 
+	claims, err := newCustomClaims(userId, email, role, tokenVersion, duration)
+	if err != nil {
+		return "", err
+	}
+	claims.SessionID = sessionId
+
+	return SignToken(claims)
+}
+
+// GenerateImpersonationToken issues a short-lived token for the user that also
+// names the admin acting on their behalf. It has no session and cannot be refreshed.
+func GenerateImpersonationToken(userId int64, email, role string, tokenVersion int64, actor ActorClaim) (string, time.Time, error) {
+	duration := time.Duration(env.GetEnvInt("IMPERSONATION_EXPIRATION_MINUTES", 15)) * time.Minute
+
+	claims, err := newCustomClaims(userId, email, role, tokenVersion, duration)
+	if err != nil {
+		return "", time.Time{}, err
+	}
+	claims.Actor = &actor
+
+	token, err := SignToken(claims)
+	if err != nil {
+		return "", time.Time{}, err
+	}
+
+	return token, claims.ExpiresAt.Time, nil
+}
+
+func newCustomClaims(userId int64, email, role string, tokenVersion int64, duration time.Duration) (*CustomClaims, error) {
 	// Token expires in minutes
 	expirationTime := time.Now().Add(duration)
 
-	claims := CustomClaims{
-		UserID: userId,
-		Email:  email,
+	// Unique token id, used to revoke this token on logout
+	jti, err := GenerateOpaqueToken(16)
+	if err != nil {
+		return nil, err
+	}
+
+	return &CustomClaims{
+		UserID:       userId,
+		Email:        email,
+		Role:         role,
+		TokenVersion: tokenVersion,
 		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        jti,
 			ExpiresAt: jwt.NewNumericDate(expirationTime),
 			IssuedAt:  jwt.NewNumericDate(time.Now()),
 			Issuer:    "go-gin-tutorial",
 		},
+	}, nil
+}
+
+// SignToken signs the claims with the current signing key and sets its kid header.
+func SignToken(claims jwt.Claims) (string, error) {
+	if err := LoadJWTKeys(); err != nil {
+		return "", err
+	}
+
+	token := jwt.NewWithClaims(jwtKeys.method, claims)
+	if jwtKeys.signingKid != "" {
+		token.Header["kid"] = jwtKeys.signingKid
 	}
 
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, &claims)
-	tokenString, err := token.SignedString([]byte(jwtSecret))
+	tokenString, err := token.SignedString(jwtKeys.signingKey)
 	if err != nil {
 		return "", err
 	}
@@ -42,14 +103,26 @@ func GenerateLoginToken(userId int64, email string) (string, error) {
 }
 
 func VerifyToken(tokenString string) (*CustomClaims, error) {
-	jwtSecret := env.GetEnvString("JWT_SECRET", "some-secret-123456")
+	if err := LoadJWTKeys(); err != nil {
+		return nil, err
+	}
 
 	tokenFunc := func(token *jwt.Token) (interface{}, error) {
-		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
+		if jwtKeys.method == jwt.SigningMethodHS256 {
+			if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
+				return nil, jwt.ErrSignatureInvalid
+			}
+			return jwtKeys.signingKey, nil
+		}
+
+		// Asymmetric keys are picked by kid, and the algorithm must match the key type
+		kid, _ := token.Header["kid"].(string)
+		key, ok := jwtKeys.verificationKeys[kid]
+		if !ok || token.Method.Alg() != key.method.Alg() {
 			return nil, jwt.ErrSignatureInvalid
 		}
 
-		return []byte(jwtSecret), nil
+		return key.public, nil
 	}
 
 	token, err := jwt.ParseWithClaims(tokenString, &CustomClaims{}, tokenFunc)
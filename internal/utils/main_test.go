@@ -0,0 +1,14 @@
+package utils
+
+import (
+	"os"
+	"testing"
+)
+
+// The secrets are read once, so they are set before any test runs
+func TestMain(m *testing.M) {
+	os.Setenv("CURSOR_SECRET", "test-cursor-secret-of-at-least-32-characters")
+	os.Setenv("ENCRYPTION_KEY", "test-encryption-key-of-at-least-32-characters")
+
+	os.Exit(m.Run())
+}
@@ -1,17 +1,295 @@
 package utils
 
-import "golang.org/x/crypto/bcrypt"
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"runtime"
+	"strconv"
+	"strings"
+	"sync"
 
-// HashPassword hashes a plaintext password using bcrypt.
+	"github.com/vickon16/go-gin-rest-api/internal/env"
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// PasswordHasher produces and checks one kind of password hash.
+type PasswordHasher interface {
+	Hash(password string) (string, error)
+	Verify(hashedPassword, password string) bool
+	// NeedsRehash reports whether a hash this hasher recognises was made with other parameters.
+	NeedsRehash(hashedPassword string) bool
+	// Recognises reports whether the hash was produced by this algorithm.
+	Recognises(hashedPassword string) bool
+}
+
+var (
+	currentHasher     PasswordHasher
+	currentHasherErr  error
+	currentHasherOnce sync.Once
+
+	// dummyHash is checked against when there is no user, see SimulatePasswordCheck
+	dummyHash string
+
+	// argon2Slots bounds how many Argon2id hashes run at once, each holds ARGON2_MEMORY_KB of memory
+	argon2Slots = make(chan struct{}, runtime.NumCPU())
+)
+
+// LoadPasswordHasher builds the hasher new passwords are stored with. Call it at
+// startup so invalid settings stop the server instead of failing the first login.
+//
+//	PASSWORD_HASH_ALGORITHM   argon2id (default) or bcrypt
+//	PASSWORD_HASH_CONCURRENCY most Argon2id hashes computed at once, default the number of CPUs
+//	ARGON2_MEMORY_KB          default 65536
+//	ARGON2_ITERATIONS         default 3
+//	ARGON2_PARALLELISM        default 2
+//	BCRYPT_COST               default bcrypt.DefaultCost
+func LoadPasswordHasher() error {
+	currentHasherOnce.Do(func() {
+		currentHasher, currentHasherErr = newPasswordHasherFromEnv()
+		if currentHasherErr != nil {
+			return
+		}
+
+		concurrency, err := envIntInRange("PASSWORD_HASH_CONCURRENCY", runtime.NumCPU(), 1, 1024)
+		if err != nil {
+			currentHasherErr = err
+			return
+		}
+		argon2Slots = make(chan struct{}, concurrency)
+
+		dummyHash, currentHasherErr = currentHasher.Hash("dummy-password")
+	})
+
+	return currentHasherErr
+}
+
+func newPasswordHasherFromEnv() (PasswordHasher, error) {
+	switch algorithm := env.GetEnvString("PASSWORD_HASH_ALGORITHM", "argon2id"); algorithm {
+	case "bcrypt":
+		cost, err := envIntInRange("BCRYPT_COST", bcrypt.DefaultCost, bcrypt.MinCost, bcrypt.MaxCost)
+		if err != nil {
+			return nil, err
+		}
+		return &BcryptHasher{Cost: cost}, nil
+	case "argon2id":
+		parallelism, err := envIntInRange("ARGON2_PARALLELISM", 2, 1, math.MaxUint8)
+		if err != nil {
+			return nil, err
+		}
+		// Argon2 needs at least 8 KiB per lane, and more than 4 GiB is a typo
+		memory, err := envIntInRange("ARGON2_MEMORY_KB", 64*1024, 8*parallelism, 4*1024*1024)
+		if err != nil {
+			return nil, err
+		}
+		iterations, err := envIntInRange("ARGON2_ITERATIONS", 3, 1, 100)
+		if err != nil {
+			return nil, err
+		}
+
+		return &Argon2idHasher{
+			Memory:      uint32(memory),
+			Iterations:  uint32(iterations),
+			Parallelism: uint8(parallelism),
+			SaltLength:  16,
+			KeyLength:   32,
+		}, nil
+	default:
+		return nil, fmt.Errorf("unknown PASSWORD_HASH_ALGORITHM %q, use argon2id or bcrypt", algorithm)
+	}
+}
+
+// envIntInRange reads an optional integer env variable, unlike env.GetEnvInt
+// it refuses values that are not numbers or out of range instead of ignoring them.
+func envIntInRange(key string, defaultValue, minValue, maxValue int) (int, error) {
+	value, exists := os.LookupEnv(key)
+	if !exists || value == "" {
+		return defaultValue, nil
+	}
+
+	parsed, err := strconv.Atoi(value)
+	if err != nil || parsed < minValue || parsed > maxValue {
+		return 0, fmt.Errorf("%s must be a number between %d and %d", key, minValue, maxValue)
+	}
+
+	return parsed, nil
+}
+
+// passwordHashers are every algorithm a stored hash may use, so old hashes keep working.
+// Verifying reads the parameters from the hash itself, so the zero values do.
+func passwordHashers() []PasswordHasher {
+	return []PasswordHasher{&Argon2idHasher{}, &BcryptHasher{}}
+}
+
+// HashPassword hashes a plaintext password with the configured algorithm.
 // Returns the hashed string or an error.
 func HashPassword(password string) (string, error) {
-	bytes, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
-	return string(bytes), err
+	if err := LoadPasswordHasher(); err != nil {
+		return "", err
+	}
+
+	return currentHasher.Hash(password)
 }
 
-// CheckPasswordHash compares a plaintext password with a hashed password.
+// CheckPasswordHash compares a plaintext password with a hashed password of any supported algorithm.
 // Returns true if they match, false otherwise.
 func CheckPasswordHash(hashedPassword, newPassword string) bool {
-	err := bcrypt.CompareHashAndPassword([]byte(hashedPassword), []byte(newPassword))
-	return err == nil
+	for _, hasher := range passwordHashers() {
+		if hasher.Recognises(hashedPassword) {
+			return hasher.Verify(hashedPassword, newPassword)
+		}
+	}
+
+	return false
+}
+
+// PasswordNeedsRehash reports whether the hash was made with another algorithm
+// or other parameters than the ones currently configured.
+func PasswordNeedsRehash(hashedPassword string) bool {
+	if err := LoadPasswordHasher(); err != nil {
+		return false
+	}
+	if !currentHasher.Recognises(hashedPassword) {
+		return true
+	}
+
+	return currentHasher.NeedsRehash(hashedPassword)
+}
+
+// SimulatePasswordCheck spends the same time as CheckPasswordHash.
+// Used when there is no user to check, so response times do not reveal which emails exist.
+// It goes through the same bounded Argon2id slots, so unknown emails cannot exhaust memory either.
+func SimulatePasswordCheck(password string) {
+	if err := LoadPasswordHasher(); err != nil {
+		return
+	}
+
+	_ = CheckPasswordHash(dummyHash, password)
+}
+
+type BcryptHasher struct {
+	Cost int
+}
+
+func (h *BcryptHasher) Hash(password string) (string, error) {
+	bytes, err := bcrypt.GenerateFromPassword([]byte(password), h.Cost)
+	return string(bytes), err
+}
+
+func (h *BcryptHasher) Verify(hashedPassword, password string) bool {
+	return bcrypt.CompareHashAndPassword([]byte(hashedPassword), []byte(password)) == nil
+}
+
+func (h *BcryptHasher) NeedsRehash(hashedPassword string) bool {
+	cost, err := bcrypt.Cost([]byte(hashedPassword))
+	return err != nil || cost != h.Cost
+}
+
+func (h *BcryptHasher) Recognises(hashedPassword string) bool {
+	for _, prefix := range []string{"$2a$", "$2b$", "$2y$"} {
+		if strings.HasPrefix(hashedPassword, prefix) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Argon2idHasher stores hashes in the PHC string format:
+// $argon2id$v=19$m=65536,t=3,p=2$<salt>$<hash>
+type Argon2idHasher struct {
+	Memory      uint32
+	Iterations  uint32
+	Parallelism uint8
+	SaltLength  int
+	KeyLength   uint32
+}
+
+const argon2idPrefix = "$argon2id$"
+
+func (h *Argon2idHasher) Hash(password string) (string, error) {
+	salt := make([]byte, h.SaltLength)
+	if _, err := rand.Read(salt); err != nil {
+		return "", err
+	}
+
+	release := acquireArgon2Slot()
+	key := argon2.IDKey([]byte(password), salt, h.Iterations, h.Memory, h.Parallelism, h.KeyLength)
+	release()
+
+	encode := base64.RawStdEncoding.EncodeToString
+	return fmt.Sprintf("%sv=%d$m=%d,t=%d,p=%d$%s$%s",
+		argon2idPrefix, argon2.Version, h.Memory, h.Iterations, h.Parallelism, encode(salt), encode(key),
+	), nil
+}
+
+func (h *Argon2idHasher) Verify(hashedPassword, password string) bool {
+	params, salt, key, err := decodeArgon2id(hashedPassword)
+	if err != nil {
+		return false
+	}
+
+	release := acquireArgon2Slot()
+	candidate := argon2.IDKey([]byte(password), salt, params.Iterations, params.Memory, params.Parallelism, uint32(len(key)))
+	release()
+
+	return subtle.ConstantTimeCompare(key, candidate) == 1
+}
+
+func (h *Argon2idHasher) NeedsRehash(hashedPassword string) bool {
+	params, salt, key, err := decodeArgon2id(hashedPassword)
+	if err != nil {
+		return true
+	}
+
+	return params.Memory != h.Memory || params.Iterations != h.Iterations || params.Parallelism != h.Parallelism ||
+		len(salt) != h.SaltLength || uint32(len(key)) != h.KeyLength
+}
+
+func (h *Argon2idHasher) Recognises(hashedPassword string) bool {
+	return strings.HasPrefix(hashedPassword, argon2idPrefix)
+}
+
+// acquireArgon2Slot waits until fewer than PASSWORD_HASH_CONCURRENCY hashes run. Call the returned func when done.
+func acquireArgon2Slot() func() {
+	slots := argon2Slots
+	slots <- struct{}{}
+	return func() { <-slots }
+}
+
+func decodeArgon2id(hashedPassword string) (*Argon2idHasher, []byte, []byte, error) {
+	// "", "argon2id", "v=19", "m=...,t=...,p=...", salt, hash
+	parts := strings.Split(hashedPassword, "$")
+	if len(parts) != 6 || parts[1] != "argon2id" {
+		return nil, nil, nil, errors.New("invalid argon2id hash")
+	}
+
+	var version int
+	if _, err := fmt.Sscanf(parts[2], "v=%d", &version); err != nil || version != argon2.Version {
+		return nil, nil, nil, errors.New("unsupported argon2id version")
+	}
+
+	params := &Argon2idHasher{}
+	if _, err := fmt.Sscanf(parts[3], "m=%d,t=%d,p=%d", &params.Memory, &params.Iterations, &params.Parallelism); err != nil {
+		return nil, nil, nil, errors.New("invalid argon2id parameters")
+	}
+	if params.Memory == 0 || params.Iterations == 0 || params.Parallelism == 0 {
+		return nil, nil, nil, errors.New("invalid argon2id parameters")
+	}
+
+	salt, err := base64.RawStdEncoding.DecodeString(parts[4])
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	key, err := base64.RawStdEncoding.DecodeString(parts[5])
+	if err != nil || len(key) == 0 {
+		return nil, nil, nil, errors.New("invalid argon2id hash")
+	}
+
+	return params, salt, key, nil
 }
@@ -0,0 +1,83 @@
+package utils
+
+import (
+	"os"
+	"testing"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+func TestNewPasswordHasherFromEnv(t *testing.T) {
+	tests := []struct {
+		name    string
+		env     map[string]string
+		wantErr bool
+	}{
+		{"defaults", map[string]string{}, false},
+		{"bcrypt", map[string]string{"PASSWORD_HASH_ALGORITHM": "bcrypt", "BCRYPT_COST": "12"}, false},
+		{"unknown algorithm", map[string]string{"PASSWORD_HASH_ALGORITHM": "md5"}, true},
+		{"zero parallelism", map[string]string{"ARGON2_PARALLELISM": "0"}, true},
+		{"parallelism past uint8", map[string]string{"ARGON2_PARALLELISM": "300"}, true},
+		{"memory below 8 KiB per lane", map[string]string{"ARGON2_PARALLELISM": "4", "ARGON2_MEMORY_KB": "16"}, true},
+		{"zero iterations", map[string]string{"ARGON2_ITERATIONS": "0"}, true},
+		{"not a number", map[string]string{"ARGON2_MEMORY_KB": "64MB"}, true},
+		{"bcrypt cost too high", map[string]string{"PASSWORD_HASH_ALGORITHM": "bcrypt", "BCRYPT_COST": "40"}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for _, key := range []string{"PASSWORD_HASH_ALGORITHM", "ARGON2_MEMORY_KB", "ARGON2_ITERATIONS", "ARGON2_PARALLELISM", "BCRYPT_COST"} {
+				// Setenv restores the variable after the test, unset ones fall back to the defaults
+				t.Setenv(key, tt.env[key])
+				if _, ok := tt.env[key]; !ok {
+					os.Unsetenv(key)
+				}
+			}
+
+			_, err := newPasswordHasherFromEnv()
+			if (err != nil) != tt.wantErr {
+				t.Errorf("newPasswordHasherFromEnv() returned %v, want error %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestPasswordHashers(t *testing.T) {
+	// Small parameters keep the test fast, verifying reads them from the hash
+	hashers := map[string]PasswordHasher{
+		"argon2id": &Argon2idHasher{Memory: 64, Iterations: 1, Parallelism: 1, SaltLength: 16, KeyLength: 32},
+		"bcrypt":   &BcryptHasher{Cost: bcrypt.MinCost},
+	}
+
+	for name, hasher := range hashers {
+		t.Run(name, func(t *testing.T) {
+			hash, err := hasher.Hash("correct horse battery staple")
+			if err != nil {
+				t.Fatalf("Hash returned %v", err)
+			}
+
+			if !CheckPasswordHash(hash, "correct horse battery staple") {
+				t.Error("CheckPasswordHash rejected the right password")
+			}
+			if CheckPasswordHash(hash, "correct horse battery stable") {
+				t.Error("CheckPasswordHash accepted a wrong password")
+			}
+			if hasher.NeedsRehash(hash) {
+				t.Error("NeedsRehash is true for a hash with the same parameters")
+			}
+		})
+	}
+
+	weaker := &Argon2idHasher{Memory: 32, Iterations: 1, Parallelism: 1, SaltLength: 16, KeyLength: 32}
+	hash, err := weaker.Hash("password")
+	if err != nil {
+		t.Fatalf("Hash returned %v", err)
+	}
+	if !hashers["argon2id"].NeedsRehash(hash) {
+		t.Error("NeedsRehash is false for a hash with less memory")
+	}
+
+	if CheckPasswordHash("plaintext", "plaintext") {
+		t.Error("CheckPasswordHash accepted a value that is not a hash")
+	}
+}
@@ -6,3 +6,48 @@ func ConstructRedisUserKey(userId int64) string {
 	cacheKey := "tutorial:user:" + strconv.FormatInt(userId, 10)
 	return cacheKey
 }
+
+func ConstructRedisTokenVersionKey(userId int64) string {
+	return "tutorial:token-version:" + strconv.FormatInt(userId, 10)
+}
+
+func ConstructRedisRevokedTokenKey(jti string) string {
+	return "tutorial:revoked-token:" + jti
+}
+
+func ConstructRedisTwoFactorChallengeKey(challengeHash string) string {
+	return "tutorial:2fa-challenge:" + challengeHash
+}
+
+func ConstructRedisTwoFactorAttemptsKey(challengeHash string) string {
+	return "tutorial:2fa-attempts:" + challengeHash
+}
+
+func ConstructRedisTwoFactorStepKey(userId int64) string {
+	return "tutorial:2fa-last-step:" + strconv.FormatInt(userId, 10)
+}
+
+// scope is either "email" or "ip"
+func ConstructRedisLoginFailuresKey(scope, value string) string {
+	return "tutorial:login-failures:" + scope + ":" + value
+}
+
+func ConstructRedisLoginLockKey(scope, value string) string {
+	return "tutorial:login-lock:" + scope + ":" + value
+}
+
+func ConstructRedisOIDCStateKey(state string) string {
+	return "tutorial:oidc-state:" + state
+}
+
+func ConstructRedisMagicLinkRequestsKey(email string) string {
+	return "tutorial:magic-link-requests:" + email
+}
+
+func ConstructRedisRevokedSessionKey(sessionId int64) string {
+	return "tutorial:revoked-session:" + strconv.FormatInt(sessionId, 10)
+}
+
+func ConstructRedisSessionLastSeenKey(sessionId int64) string {
+	return "tutorial:session-last-seen:" + strconv.FormatInt(sessionId, 10)
+}
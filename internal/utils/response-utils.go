@@ -7,9 +7,26 @@ import (
 )
 
 type ApiResponse struct {
-	Success bool   `json:"success"`
-	Message string `json:"message,omitempty"`
-	Data    any    `json:"data,omitempty"`
+	Success    bool        `json:"success"`
+	Message    string      `json:"message,omitempty"`
+	Data       any         `json:"data,omitempty"`
+	Pagination *Pagination `json:"pagination,omitempty"`
+}
+
+// Pagination tells where a page of a list response is. Lists paginated by
+// page carry the totals, lists paginated by cursor the cursors of the pages around it.
+type Pagination struct {
+	Page       int    `json:"page,omitempty"`
+	Limit      int    `json:"limit"`
+	Total      *int64 `json:"total,omitempty"`
+	TotalPages *int   `json:"totalPages,omitempty"`
+	NextCursor string `json:"nextCursor,omitempty"`
+	PrevCursor string `json:"prevCursor,omitempty"`
+}
+
+func NewPagination(page, limit int, total int64) *Pagination {
+	totalPages := int((total + int64(limit) - 1) / int64(limit))
+	return &Pagination{Page: page, Limit: limit, Total: &total, TotalPages: &totalPages}
 }
 
 func SuccessResponse(c *gin.Context, message string, data any, status ...int) {
@@ -25,6 +42,16 @@ func SuccessResponse(c *gin.Context, message string, data any, status ...int) {
 	})
 }
 
+// PaginatedResponse is a SuccessResponse for one page of a list.
+func PaginatedResponse(c *gin.Context, message string, data any, pagination *Pagination) {
+	c.JSON(http.StatusOK, ApiResponse{
+		Success:    true,
+		Message:    message,
+		Data:       data,
+		Pagination: pagination,
+	})
+}
+
 func ErrorResponse(c *gin.Context, message string, status int, data ...any) {
 	var responseData any = nil
 	if len(data) > 0 {
@@ -0,0 +1,34 @@
+package utils
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/vickon16/go-gin-rest-api/internal/env"
+)
+
+// Secrets should come from a random generator, shorter ones are refused
+const minSecretLength = 32
+
+// envSecret is a secret read once from an env variable.
+type envSecret struct {
+	name string
+
+	once  sync.Once
+	value []byte
+	err   error
+}
+
+// load returns the secret, or an error when the variable is missing or too short.
+func (s *envSecret) load() ([]byte, error) {
+	s.once.Do(func() {
+		secret := env.GetEnvString(s.name, "")
+		if len(secret) < minSecretLength {
+			s.err = fmt.Errorf("%s must be set to at least %d characters", s.name, minSecretLength)
+			return
+		}
+		s.value = []byte(secret)
+	})
+
+	return s.value, s.err
+}
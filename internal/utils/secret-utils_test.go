@@ -0,0 +1,29 @@
+package utils
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestEnvSecret(t *testing.T) {
+	t.Setenv("TEST_SECRET", "too short")
+
+	short := &envSecret{name: "TEST_SECRET"}
+	if _, err := short.load(); err == nil || !strings.Contains(err.Error(), "TEST_SECRET") {
+		t.Errorf("load of a short secret returned %v", err)
+	}
+
+	secret := strings.Repeat("s", minSecretLength)
+	t.Setenv("TEST_SECRET", secret)
+
+	loaded := &envSecret{name: "TEST_SECRET"}
+	if value, err := loaded.load(); err != nil || string(value) != secret {
+		t.Fatalf("load = %q, %v", value, err)
+	}
+
+	// The variable is only read once
+	t.Setenv("TEST_SECRET", "")
+	if value, err := loaded.load(); err != nil || string(value) != secret {
+		t.Errorf("second load = %q, %v", value, err)
+	}
+}